@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
-	"os/signal"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/saviobatista/sbs-logger/internal/config"
 	"github.com/saviobatista/sbs-logger/internal/nats"
+	"github.com/saviobatista/sbs-logger/internal/receiver"
+	"github.com/saviobatista/sbs-logger/internal/shutdown"
+	"github.com/saviobatista/sbs-logger/internal/spool"
 	"github.com/saviobatista/sbs-logger/internal/types"
 )
 
@@ -22,76 +25,212 @@ type NATSClient interface {
 }
 
 func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML or TOML (.toml) configuration file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration and exit")
+	flag.Parse()
+
 	// Load configuration
-	sources := os.Getenv("SOURCES")
-	if sources == "" {
-		log.Fatal("SOURCES environment variable is required")
+	cfg, err := config.Load(config.Ingestor, *configPath)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
 	}
-
-	natsURL := os.Getenv("NATS_URL")
-	if natsURL == "" {
-		natsURL = "nats://nats:4222" // Default to Docker service name
+	opts, err := buildOptions(cfg)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if *printConfig {
+		fmt.Print(cfg.Effective(config.Ingestor))
+		return
 	}
+	log.Printf("Effective configuration:\n%s", cfg.Effective(config.Ingestor))
 
 	// Create NATS client
-	client, err := nats.New(natsURL)
+	client, err := nats.NewWithOptions(opts.nats)
 	if err != nil {
 		log.Printf("Failed to create NATS client: %v", err)
 		os.Exit(1)
 	}
-	defer client.Close()
+
+	// Retried and replayed messages keep their ID, so the stream discards
+	// them when the first attempt was stored
+	if err := client.SetDuplicateWindow(cfg.NATS.DuplicateWindow); err != nil {
+		log.Printf("Failed to set the stream duplicate window: %v", err)
+		client.Close()
+		os.Exit(1)
+	}
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	replayDone := make(chan struct{})
+
+	// Spool messages to disk while NATS is unavailable, replaying them in
+	// order once it recovers
+	var publisher NATSClient = client
+	var spooler *spoolingClient
+	var s *spool.Spool
+	if cfg.Ingestor.SpoolDir != "" {
+		s, err = spool.Open(cfg.Ingestor.SpoolDir, cfg.Ingestor.SpoolMaxSize, cfg.Ingestor.SpoolSegmentSize)
+		if err != nil {
+			log.Printf("Failed to open spool: %v", err)
+			client.Close()
+			os.Exit(1)
+		}
+		if depth := s.Depth(); depth.Records > 0 {
+			log.Printf("Spool holds %d messages from a previous run", depth.Records)
+		}
+		spooler = newSpoolingClient(client, s)
+		publisher = spooler
+		go func() {
+			defer close(replayDone)
+			spooler.Run(ctx, spoolReplayInterval)
+		}()
+	} else {
+		close(replayDone)
+	}
 
-	// Start ingesting from each source
-	sourceList := strings.Split(sources, ",")
-	for _, source := range sourceList {
-		source = strings.TrimSpace(source)
-		go ingestSource(ctx, source, client)
+	// Publish without waiting for each ack, spooling the messages that fail
+	if cfg.Ingestor.AsyncPublish {
+		onError := func(_ []byte, err error) {
+			log.Printf("Failed to publish message: %v", err)
+		}
+		if spooler != nil {
+			onError = spooler.PublishFailed
+		}
+		if err := client.EnableAsyncPublish(opts.publish, onError); err != nil {
+			log.Printf("Failed to enable async publishing: %v", err)
+			client.Close()
+			os.Exit(1)
+		}
+	}
+
+	// Track the health of the sources, served over HTTP and published on NATS
+	health := receiver.NewMonitor()
+	go health.Run(ctx, receiver.DefaultSampleInterval)
+	server, err := setupStatus(health, spooler, client, cfg.Ingestor.StatusAddr)
+	if err != nil {
+		log.Printf("Failed to start status endpoint: %v", err)
+		client.Close()
+		os.Exit(1)
+	}
+	go publishStatus(ctx, client, health, cfg.Ingestor.StatusSubject, cfg.Ingestor.StatusInterval)
+
+	// Report the NATS connection and the last message from any source
+	checker, healthServer, err := setupHealth(health, client.Ping, cfg.Health.Addr, cfg.Health.MaxMessageAge)
+	if err != nil {
+		log.Printf("Failed to start health endpoint: %v", err)
+		client.Close()
+		os.Exit(1)
 	}
 
+	// Start ingesting from each source, and start or stop sources when the
+	// configuration is reloaded
+	sources := newSourceManager(ctx, publisher, health)
+	sources.Update(opts.sources)
+	reloader := &reloader{sources: sources, initial: cfg}
+	go config.Watch(ctx, config.Ingestor, *configPath, config.ReloadInterval, reloader.apply)
+
 	// Wait for shutdown signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	sig := shutdown.Wait()
+	log.Printf("Received %s, shutting down...", sig)
+	checker.Stopping()
 
-	log.Println("Shutting down...")
-	cancel()
-	time.Sleep(time.Second) // Give time for goroutines to clean up
+	// Stop reporting health and ingesting, then publish or spool every
+	// message already read before closing the connection and the spool. The
+	// publisher is closed first so failed acks still reach the spool.
+	var steps []shutdown.Step
+	if healthServer != nil {
+		steps = append(steps, shutdown.Step{Name: "stop health endpoint", Run: healthServer.Shutdown})
+	}
+	steps = append(steps,
+		shutdown.Step{Name: "stop sources", Run: sources.Stop},
+		shutdown.Step{Name: "stop spool replay", Run: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-replayDone:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		}},
+		shutdown.Step{Name: "publish pending messages", Run: client.ClosePublisher},
+		shutdown.Step{Name: "drain NATS", Run: client.Drain},
+	)
+	if s != nil {
+		steps = append(steps, shutdown.Func("close spool", s.Close))
+	}
+	if server != nil {
+		steps = append(steps, shutdown.Step{Name: "stop status endpoint", Run: server.Shutdown})
+	}
+	shutdown.Exit(shutdown.Run(cfg.ShutdownTimeout, steps...))
 }
 
-func ingestSource(ctx context.Context, source string, client NATSClient) {
+// stableSession is how long a session must stay connected for the next
+// reconnection to start again from the initial backoff delay, when it
+// delivered no message
+const stableSession = time.Minute
+
+func ingestSource(ctx context.Context, source string, opts receiver.Options, health *receiver.Health, client NATSClient) {
+	attempt := 0 // Failed sessions in a row
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			if err := connectAndIngest(ctx, source, client); err != nil {
+			started, received := time.Now(), health.Status().Messages
+			if err := connectAndIngest(ctx, source, opts, health, client); err != nil {
 				log.Printf("Error from source %s: %v", source, err)
-				time.Sleep(5 * time.Second) // Wait before retrying
+				if sessionHealthy(health.Status(), started, received) {
+					attempt = 0
+				}
+				// Wait before reconnecting, longer after each failed session
+				delay := opts.Backoff.Delay(attempt)
+				attempt++
+				health.Backoff(err, delay)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+				}
 			}
 		}
 	}
 }
 
-func connectAndIngest(ctx context.Context, source string, client NATSClient) error {
+// sessionHealthy reports whether the session started at started delivered
+// messages, received being the count before it, or stayed connected for
+// stableSession
+func sessionHealthy(status receiver.Status, started time.Time, received uint64) bool {
+	if status.Messages > received {
+		return true
+	}
+	return status.LastConnected != nil && !status.LastConnected.Before(started) && time.Since(*status.LastConnected) >= stableSession
+}
+
+func connectAndIngest(ctx context.Context, source string, opts receiver.Options, health *receiver.Health, client NATSClient) error {
 	// Create TCP connection
-	conn, err := connectWithRetry(source)
+	conn, err := connectWithRetry(ctx, source, opts, health)
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
-	defer func() {
+	closeConn := func() {
 		if err := conn.Close(); err != nil {
 			fmt.Fprintf(os.Stderr, "error closing conn: %v\n", err)
 		}
+	}
+
+	// Close the connection as soon as the source is stopped, unblocking the read
+	stop := context.AfterFunc(ctx, closeConn)
+	defer func() {
+		if stop() {
+			closeConn()
+		}
 	}()
 
 	log.Printf("Connected to source: %s", source)
 
 	// Create buffer for reading messages
-	buf := make([]byte, 1024)
+	buf := make([]byte, opts.BufferSize)
 	var messageBuffer strings.Builder
 
 	for {
@@ -99,14 +238,17 @@ func connectAndIngest(ctx context.Context, source string, client NATSClient) err
 		case <-ctx.Done():
 			return nil
 		default:
-			// Set read deadline
-			if err := conn.SetReadDeadline(time.Now().Add(30 * time.Second)); err != nil {
+			// Reconnect when the source goes quiet for too long
+			if err := conn.SetReadDeadline(time.Now().Add(opts.ReadIdleTimeout)); err != nil {
 				return fmt.Errorf("failed to set read deadline: %w", err)
 			}
 
 			// Read data
 			n, err := conn.Read(buf)
 			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
 				return fmt.Errorf("read error: %w", err)
 			}
 
@@ -119,11 +261,13 @@ func connectAndIngest(ctx context.Context, source string, client NATSClient) err
 
 			// Keep the last message in buffer (it might be incomplete)
 			messageBuffer.Reset()
+			received := 0
 			if len(messages) > 1 {
 				// Process all complete messages except the last one
 				for i := 0; i < len(messages)-1; i++ {
 					message := strings.TrimSpace(messages[i])
 					if message != "" {
+						received++
 						// Create and publish message
 						msg := &types.SBSMessage{
 							Raw:       message,
@@ -143,23 +287,33 @@ func connectAndIngest(ctx context.Context, source string, client NATSClient) err
 			if len(messages) > 0 {
 				messageBuffer.WriteString(messages[len(messages)-1])
 			}
+			health.Received(n, received)
 		}
 	}
 }
 
-func connectWithRetry(source string) (*net.TCPConn, error) {
-	addr, err := net.ResolveTCPAddr("tcp", source)
-	if err != nil {
+// connectWithRetry connects to source, retrying with exponential backoff
+// until it succeeds or the context is cancelled
+func connectWithRetry(ctx context.Context, source string, opts receiver.Options, health *receiver.Health) (*net.TCPConn, error) {
+	if _, err := net.ResolveTCPAddr("tcp", source); err != nil {
 		return nil, fmt.Errorf("failed to resolve address: %w", err)
 	}
 
-	for {
-		conn, err := net.DialTCP("tcp", nil, addr)
+	for attempt := 0; ; attempt++ {
+		health.Connecting()
+		conn, err := opts.Dial(ctx, source)
 		if err == nil {
+			health.Connected()
 			return conn, nil
 		}
 
-		log.Printf("Failed to connect to %s: %v. Retrying in 5 seconds...", source, err)
-		time.Sleep(5 * time.Second)
+		delay := opts.Backoff.Delay(attempt)
+		health.Backoff(err, delay)
+		log.Printf("Failed to connect to %s: %v. Retrying in %s...", source, err, delay.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/saviobatista/sbs-logger/internal/nats"
+	"github.com/saviobatista/sbs-logger/internal/receiver"
 	"github.com/saviobatista/sbs-logger/internal/types"
 	"github.com/testcontainers/testcontainers-go"
 	natscontainer "github.com/testcontainers/testcontainers-go/modules/nats"
@@ -121,7 +122,7 @@ func TestIngestSourceIntegration(t *testing.T) {
 	defer cancel()
 
 	// Start ingestion in a goroutine
-	go ingestSource(ctx, source, client)
+	go ingestSource(ctx, source, receiver.DefaultOptions(), receiver.NewHealth(source), client)
 
 	// Wait for context to complete
 	<-ctx.Done()
@@ -196,7 +197,7 @@ func TestConnectAndIngestIntegration(t *testing.T) {
 	defer cancel()
 
 	// Test the full connect and ingest flow
-	err = connectAndIngest(ctx, source, client)
+	err = connectAndIngest(ctx, source, receiver.DefaultOptions(), receiver.NewHealth(source), client)
 	if err != nil {
 		// EOF is expected when the mock server closes the connection
 		if !strings.Contains(fmt.Sprintf("%v", err), "EOF") {
@@ -223,7 +224,7 @@ func TestConnectWithRetryIntegration(t *testing.T) {
 	// Test with a timeout to prevent infinite retry
 	done := make(chan error, 1)
 	go func() {
-		conn, err := connectWithRetry(source)
+		conn, err := connectWithRetry(context.Background(), source, receiver.DefaultOptions(), receiver.NewHealth(source))
 		if conn != nil {
 			_ = conn.Close()
 		}
@@ -300,7 +301,7 @@ func TestNATSMessageFlow_Integration(t *testing.T) {
 	defer cancel()
 
 	// Start ingestion in a goroutine
-	go ingestSource(ctx, source, client)
+	go ingestSource(ctx, source, receiver.DefaultOptions(), receiver.NewHealth(source), client)
 
 	// Collect received messages
 	received := make([]*types.SBSMessage, 0, 4)
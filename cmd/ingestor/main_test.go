@@ -10,6 +10,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/saviobatista/sbs-logger/internal/config"
+	"github.com/saviobatista/sbs-logger/internal/receiver"
 	"github.com/saviobatista/sbs-logger/internal/types"
 )
 
@@ -207,7 +209,7 @@ func TestConnectWithRetry(t *testing.T) {
 			// Test with a timeout to prevent infinite retry
 			done := make(chan error, 1)
 			go func() {
-				conn, err := connectWithRetry(actualSource)
+				conn, err := connectWithRetry(context.Background(), actualSource, receiver.DefaultOptions(), receiver.NewHealth(actualSource))
 				if conn != nil {
 					_ = conn.Close()
 				}
@@ -293,7 +295,8 @@ func TestConnectAndIngest(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), tt.maxDuration)
 			defer cancel()
 
-			err := connectAndIngest(ctx, actualSource, mockClient)
+			health := receiver.NewHealth(actualSource)
+			err := connectAndIngest(ctx, actualSource, receiver.DefaultOptions(), health, mockClient)
 
 			if tt.expectError && err == nil {
 				t.Error("Expected error, got nil")
@@ -308,6 +311,9 @@ func TestConnectAndIngest(t *testing.T) {
 			if mockClient.GetPublishedMessagesCount() != tt.expectMessages {
 				t.Errorf("Expected %d messages, got %d", tt.expectMessages, mockClient.GetPublishedMessagesCount())
 			}
+			if status := health.Status(); status.Messages != uint64(tt.expectMessages) {
+				t.Errorf("Expected source health to count %d messages, got %+v", tt.expectMessages, status)
+			}
 		})
 	}
 }
@@ -343,25 +349,13 @@ func TestNATSClientInterface(t *testing.T) {
 
 // Helper functions
 
-// parseEnvironment extracts the core environment parsing logic for testing
+// parseEnvironment loads the ingestor configuration from the environment
 func parseEnvironment() ([]string, string, error) {
-	sources := os.Getenv("SOURCES")
-	if sources == "" {
-		return nil, "", fmt.Errorf("SOURCES environment variable is required")
-	}
-
-	natsURL := os.Getenv("NATS_URL")
-	if natsURL == "" {
-		natsURL = "nats://nats:4222" // Default to Docker service name
-	}
-
-	// Parse sources
-	sourceList := strings.Split(sources, ",")
-	for i, source := range sourceList {
-		sourceList[i] = strings.TrimSpace(source)
+	cfg, err := config.Load(config.Ingestor, "")
+	if err != nil {
+		return nil, "", err
 	}
-
-	return sourceList, natsURL, nil
+	return cfg.Ingestor.Sources, cfg.NATS.URL, nil
 }
 
 // createMockTCPServer creates a mock TCP server that sends predefined messages
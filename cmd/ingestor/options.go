@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/saviobatista/sbs-logger/internal/config"
+	"github.com/saviobatista/sbs-logger/internal/nats"
+	"github.com/saviobatista/sbs-logger/internal/receiver"
+)
+
+// options holds the options the ingestor passes to the NATS and receiver
+// packages, built from its configuration
+type options struct {
+	nats    *nats.Options
+	sources map[string]receiver.Options // By source address
+	publish nats.PublisherOptions
+}
+
+// buildOptions builds the options of the ingestor from cfg, so a
+// configuration those packages reject is reported before it is used
+func buildOptions(cfg *config.Config) (*options, error) {
+	natsOpts, err := natsOptions(&cfg.NATS)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.NATS.DuplicateWindow > nats.StreamMaxAge {
+		return nil, fmt.Errorf("invalid nats.duplicate_window %s, expected up to %s", cfg.NATS.DuplicateWindow, nats.StreamMaxAge)
+	}
+	if err := toReceiverOptions(cfg.Ingestor.Connection).Validate(); err != nil {
+		return nil, fmt.Errorf("invalid ingestor.connection: %w", err)
+	}
+	sources, err := sourceOptions(&cfg.Ingestor)
+	if err != nil {
+		return nil, err
+	}
+	publish, err := publisherOptions(&cfg.Ingestor.Publish)
+	if err != nil {
+		return nil, err
+	}
+	return &options{nats: natsOpts, sources: sources, publish: publish}, nil
+}
+
+// natsOptions returns the options of the ingestor's NATS connection
+func natsOptions(cfg *config.NATSConfig) (*nats.Options, error) {
+	conn := cfg.Connection(config.Ingestor)
+	opts := &nats.Options{
+		URL:             cfg.URL,
+		Name:            "sbs-" + string(config.Ingestor),
+		CredentialsFile: conn.CredsFile,
+		NKeyFile:        conn.NKeyFile,
+		User:            conn.User,
+		Password:        conn.Password,
+		TLSCAFile:       conn.TLSCAFile,
+		TLSCertFile:     conn.TLSCertFile,
+		TLSKeyFile:      conn.TLSKeyFile,
+		ReconnectWait:   conn.ReconnectWait,
+		MaxReconnects:   conn.MaxReconnects,
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid nats settings of the ingestor: %w", err)
+	}
+	return opts, nil
+}
+
+// sourceOptions returns the connection options of every source. Options a
+// source doesn't set are those of the connection section, or the receiver
+// defaults.
+func sourceOptions(cfg *config.IngestorConfig) (map[string]receiver.Options, error) {
+	options := make(map[string]receiver.Options, len(cfg.Sources))
+	for _, source := range cfg.Sources {
+		opts := toReceiverOptions(cfg.Options(source)).Merge(receiver.DefaultOptions())
+		if err := opts.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid connection options of ingestor source %q: %w", source, err)
+		}
+		options[source] = opts
+	}
+	return options, nil
+}
+
+// toReceiverOptions converts connection options read from the configuration
+func toReceiverOptions(opts config.SourceOptions) receiver.Options {
+	return receiver.Options{
+		DialTimeout:     opts.DialTimeout,
+		ReadIdleTimeout: opts.ReadIdleTimeout,
+		KeepAlive:       opts.KeepAlive,
+		BufferSize:      opts.BufferSize,
+		Backoff: receiver.Backoff{
+			Initial: opts.Backoff.Initial,
+			Max:     opts.Backoff.Max,
+			Jitter:  opts.Backoff.Jitter,
+		},
+	}
+}
+
+// publisherOptions returns the async publishing options. Zero fields are
+// left for the publisher to default.
+func publisherOptions(cfg *config.PublishConfig) (nats.PublisherOptions, error) {
+	opts := nats.PublisherOptions{
+		MaxPending:    cfg.MaxPending,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: cfg.FlushInterval,
+		MaxRetries:    cfg.MaxRetries,
+		AckTimeout:    cfg.AckTimeout,
+	}
+	if err := opts.Validate(); err != nil {
+		return nats.PublisherOptions{}, fmt.Errorf("invalid ingestor.publish: %w", err)
+	}
+	return opts, nil
+}
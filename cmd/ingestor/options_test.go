@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/config"
+	"github.com/saviobatista/sbs-logger/internal/health"
+	"github.com/saviobatista/sbs-logger/internal/nats"
+	"github.com/saviobatista/sbs-logger/internal/receiver"
+	"github.com/saviobatista/sbs-logger/internal/shutdown"
+	"github.com/saviobatista/sbs-logger/internal/spool"
+)
+
+func TestConfigDefaults(t *testing.T) {
+	if config.DefaultShutdownTimeout != shutdown.DefaultTimeout || config.DefaultMaxMessageAge != health.DefaultMaxMessageAge {
+		t.Error("Expected the shutdown and health defaults of the configuration to match the packages")
+	}
+	if config.DefaultDuplicateWindow != nats.DefaultDuplicateWindow || config.DefaultStatusSubject != nats.SubjectSources {
+		t.Error("Expected the NATS defaults of the configuration to match the nats package")
+	}
+	if config.DefaultSpoolMaxSize != spool.DefaultMaxSize || config.DefaultSpoolSegmentSize != spool.DefaultSegmentSize {
+		t.Error("Expected the spool defaults of the configuration to match the spool package")
+	}
+}
+
+func TestBuildOptions(t *testing.T) {
+	cfg := config.Default()
+	cfg.NATS.URL = "tls://nats:4222"
+	cfg.NATS.User = "sbs"
+	cfg.NATS.Password = "shared-secret"
+	cfg.NATS.Services = map[config.Service]config.NATSConnection{
+		config.Ingestor: {CredsFile: "/etc/nats/ingestor.creds", User: "ingestor", Password: "ingestor-secret"},
+		config.Tracker:  {ReconnectWait: 5 * time.Second},
+	}
+	cfg.Ingestor.Sources = []string{"local:30003", "remote:30003"}
+	cfg.Ingestor.Connection = config.SourceOptions{ReadIdleTimeout: time.Minute, Backoff: config.BackoffConfig{Max: 30 * time.Second}}
+	cfg.Ingestor.SourceOptions = map[string]config.SourceOptions{
+		"remote:30003": {DialTimeout: 30 * time.Second, BufferSize: 8192, Backoff: config.BackoffConfig{Initial: 5 * time.Second}},
+	}
+	cfg.Ingestor.Publish = config.PublishConfig{BatchSize: 64, AckTimeout: 2 * time.Second}
+
+	opts, err := buildOptions(cfg)
+	if err != nil {
+		t.Fatalf("buildOptions() failed: %v", err)
+	}
+
+	expectedNATS := nats.Options{URL: "tls://nats:4222", Name: "sbs-ingestor", CredentialsFile: "/etc/nats/ingestor.creds",
+		User: "ingestor", Password: "ingestor-secret", ReconnectWait: config.DefaultNATSReconnectWait, MaxReconnects: -1}
+	if *opts.nats != expectedNATS {
+		t.Errorf("NATS options = %+v, expected %+v", *opts.nats, expectedNATS)
+	}
+
+	local := receiver.DefaultOptions()
+	local.ReadIdleTimeout = time.Minute
+	local.Backoff.Max = 30 * time.Second
+	if got := opts.sources["local:30003"]; got != local {
+		t.Errorf("Options of local = %+v, expected %+v", got, local)
+	}
+	remote := local
+	remote.DialTimeout = 30 * time.Second
+	remote.BufferSize = 8192
+	remote.Backoff.Initial = 5 * time.Second
+	if got := opts.sources["remote:30003"]; got != remote {
+		t.Errorf("Options of remote = %+v, expected %+v", got, remote)
+	}
+
+	if expected := (nats.PublisherOptions{BatchSize: 64, AckTimeout: 2 * time.Second}); opts.publish != expected {
+		t.Errorf("Publisher options = %+v, expected %+v", opts.publish, expected)
+	}
+}
+
+func TestBuildOptions_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config.Config)
+	}{
+		{name: "duplicate window longer than the stream", modify: func(c *config.Config) { c.NATS.DuplicateWindow = 48 * time.Hour }},
+		{name: "nats password without user", modify: func(c *config.Config) { c.NATS.Password = "secret" }},
+		{name: "nats reconnect wait", modify: func(c *config.Config) { c.NATS.ReconnectWait = -time.Second }},
+		{name: "nats creds and nkey", modify: func(c *config.Config) {
+			c.NATS.CredsFile = "a.creds"
+			c.NATS.Services = map[config.Service]config.NATSConnection{config.Ingestor: {NKeyFile: "a.nk"}}
+		}},
+		{name: "nats cert without key", modify: func(c *config.Config) {
+			c.NATS.Services = map[config.Service]config.NATSConnection{config.Ingestor: {TLSCertFile: "cert.pem"}}
+		}},
+		{name: "buffer size", modify: func(c *config.Config) { c.Ingestor.Connection.BufferSize = -1 }},
+		{name: "jitter", modify: func(c *config.Config) { c.Ingestor.Connection.Backoff.Jitter = 2 }},
+		{name: "source backoff", modify: func(c *config.Config) {
+			c.Ingestor.SourceOptions = map[string]config.SourceOptions{
+				"receiver:30003": {Backoff: config.BackoffConfig{Initial: time.Minute, Max: time.Second}},
+			}
+		}},
+		{name: "batch size", modify: func(c *config.Config) { c.Ingestor.Publish = config.PublishConfig{MaxPending: 10, BatchSize: 100} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Ingestor.Sources = []string{"receiver:30003"}
+			tt.modify(cfg)
+			if opts, err := buildOptions(cfg); err == nil {
+				t.Errorf("Expected error, got %+v", opts)
+			}
+		})
+	}
+
+	// A negative jitter disables it
+	cfg := config.Default()
+	cfg.Ingestor.Sources = []string{"receiver:30003"}
+	cfg.Ingestor.Connection.Backoff.Jitter = -1
+	if _, err := buildOptions(cfg); err != nil {
+		t.Errorf("Expected a negative jitter to be valid, got %v", err)
+	}
+}
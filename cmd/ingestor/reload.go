@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/saviobatista/sbs-logger/internal/config"
+)
+
+// reloader applies configuration changes to a running ingestor. Sources are
+// updated in place; other changes are logged as needing a restart, once
+// each time the set of such changes differs.
+type reloader struct {
+	sources *sourceManager
+	initial *config.Config // Configuration the ingestor was started with
+	pending []string       // Settings last logged as needing a restart
+}
+
+// apply updates the ingestor from cfg, unless the options built from it
+// are invalid
+func (r *reloader) apply(cfg *config.Config) error {
+	opts, err := buildOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	if changed := r.restartRequired(cfg); !slices.Equal(changed, r.pending) {
+		if len(changed) > 0 {
+			log.Printf("Warning: Changes to %s take effect after a restart", strings.Join(changed, ", "))
+		} else {
+			log.Printf("Settings needing a restart are back to their running values")
+		}
+		r.pending = changed
+	}
+
+	r.sources.Update(opts.sources)
+	return nil
+}
+
+// restartRequired returns the settings of cfg that differ from the
+// configuration the ingestor was started with and can't be applied to it
+func (r *reloader) restartRequired(cfg *config.Config) []string {
+	initial, next := &r.initial.Ingestor, &cfg.Ingestor
+	settings := []struct {
+		name    string
+		changed bool
+	}{
+		{"nats", !reflect.DeepEqual(r.initial.NATS, cfg.NATS)},
+		{"shutdown_timeout", r.initial.ShutdownTimeout != cfg.ShutdownTimeout},
+		{"health", r.initial.Health != cfg.Health},
+		{"ingestor.status_addr", initial.StatusAddr != next.StatusAddr},
+		{"ingestor.status_subject", initial.StatusSubject != next.StatusSubject},
+		{"ingestor.status_interval", initial.StatusInterval != next.StatusInterval},
+		{"ingestor.spool_dir", initial.SpoolDir != next.SpoolDir},
+		{"ingestor.spool_max_size", initial.SpoolMaxSize != next.SpoolMaxSize},
+		{"ingestor.spool_segment_size", initial.SpoolSegmentSize != next.SpoolSegmentSize},
+		{"ingestor.async_publish", initial.AsyncPublish != next.AsyncPublish},
+		{"ingestor.publish", initial.Publish != next.Publish},
+	}
+
+	var changed []string
+	for _, setting := range settings {
+		if setting.changed {
+			changed = append(changed, setting.name)
+		}
+	}
+	return changed
+}
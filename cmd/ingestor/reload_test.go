@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/config"
+	"github.com/saviobatista/sbs-logger/internal/receiver"
+)
+
+func TestReloader_Apply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sources := newSourceManager(ctx, &mockNATSClient{}, receiver.NewMonitor())
+	sources.ingest = func(ctx context.Context, _ string, _ receiver.Options, _ *receiver.Health, _ NATSClient) {
+		<-ctx.Done()
+	}
+	r := &reloader{sources: sources, initial: config.Default()}
+
+	next := config.Default()
+	next.Ingestor.Sources = []string{"a:30003"}
+	next.Ingestor.StatusAddr = ":9090"
+	if err := r.apply(next); err != nil {
+		t.Fatalf("apply() failed: %v", err)
+	}
+	if len(sources.running) != 1 {
+		t.Errorf("Expected the new source to be running, got %v", sources.running)
+	}
+	if !reflect.DeepEqual(r.pending, []string{"ingestor.status_addr"}) {
+		t.Errorf("Expected the status address to need a restart, got %v", r.pending)
+	}
+
+	// Reverting the setting no longer needs a restart
+	next.Ingestor.StatusAddr = ""
+	if err := r.apply(next); err != nil {
+		t.Fatalf("apply() failed: %v", err)
+	}
+	if len(r.pending) != 0 {
+		t.Errorf("Expected nothing to need a restart, got %v", r.pending)
+	}
+
+	// Invalid source options are rejected, keeping the running sources
+	invalid := config.Default()
+	invalid.Ingestor.Sources = []string{"b:30003"}
+	invalid.Ingestor.Connection.BufferSize = -1
+	if err := r.apply(invalid); err == nil {
+		t.Error("Expected invalid source options to be rejected")
+	}
+	if _, ok := sources.running["a:30003"]; !ok || len(sources.running) != 1 {
+		t.Errorf("Expected source a to keep running, got %v", sources.running)
+	}
+}
+
+func TestReloader_RestartRequired(t *testing.T) {
+	r := &reloader{initial: config.Default()}
+
+	tests := []struct {
+		name     string
+		modify   func(*config.Config)
+		expected []string
+	}{
+		{name: "unchanged", modify: func(*config.Config) {}},
+		{name: "sources", modify: func(c *config.Config) {
+			c.Ingestor.Sources = []string{"a:30003"}
+			c.Ingestor.Connection.DialTimeout = time.Second
+		}},
+		{name: "nats", modify: func(c *config.Config) { c.NATS.URL = "nats://other:4222" }, expected: []string{"nats"}},
+		{name: "status and spool", modify: func(c *config.Config) {
+			c.Ingestor.StatusInterval = time.Minute
+			c.Ingestor.SpoolDir = "/spool"
+		}, expected: []string{"ingestor.status_interval", "ingestor.spool_dir"}},
+		{name: "publish", modify: func(c *config.Config) {
+			c.Ingestor.AsyncPublish = !c.Ingestor.AsyncPublish
+			c.Ingestor.Publish.BatchSize++
+		}, expected: []string{"ingestor.async_publish", "ingestor.publish"}},
+		{name: "shutdown and health", modify: func(c *config.Config) {
+			c.ShutdownTimeout = time.Minute
+			c.Health.Addr = ":8082"
+		}, expected: []string{"shutdown_timeout", "health"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			tt.modify(cfg)
+			if changed := r.restartRequired(cfg); !reflect.DeepEqual(changed, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, changed)
+			}
+		})
+	}
+}
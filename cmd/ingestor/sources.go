@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/saviobatista/sbs-logger/internal/receiver"
+)
+
+// runningSource is a source being ingested and the options it connected with
+type runningSource struct {
+	cancel context.CancelFunc
+	opts   receiver.Options
+}
+
+// sourceManager runs one ingestSource goroutine per source and starts or
+// stops them as the configured sources change, sharing the NATS client
+type sourceManager struct {
+	ctx     context.Context
+	client  NATSClient
+	health  *receiver.Monitor // Health of the running sources
+	ingest  func(ctx context.Context, source string, opts receiver.Options, health *receiver.Health, client NATSClient)
+	mu      sync.Mutex
+	running map[string]runningSource
+	stopped bool
+	wg      sync.WaitGroup // Source goroutines, including those being stopped
+}
+
+// newSourceManager creates a source manager whose sources stop when ctx is cancelled
+func newSourceManager(ctx context.Context, client NATSClient, health *receiver.Monitor) *sourceManager {
+	return &sourceManager{
+		ctx:     ctx,
+		client:  client,
+		health:  health,
+		ingest:  ingestSource,
+		running: make(map[string]runningSource),
+	}
+}
+
+// Update starts ingesting from the sources of wanted not running yet, with
+// their connection options, and stops the ones no longer listed. Sources
+// whose connection options changed are restarted, the others keep their
+// connection.
+func (m *sourceManager) Update(wanted map[string]receiver.Options) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return
+	}
+
+	for source, running := range m.running {
+		if opts, ok := wanted[source]; !ok || opts != running.opts {
+			running.cancel()
+			delete(m.running, source)
+			m.health.Remove(source)
+			log.Printf("Stopped source: %s", source)
+		}
+	}
+
+	for _, source := range slices.Sorted(maps.Keys(wanted)) {
+		if _, ok := m.running[source]; ok {
+			continue
+		}
+		opts := wanted[source]
+		ctx, cancel := context.WithCancel(m.ctx)
+		m.running[source] = runningSource{cancel: cancel, opts: opts}
+		health := m.health.Add(source)
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.ingest(ctx, source, opts, health, m.client)
+		}()
+		log.Printf("Started source: %s", source)
+	}
+}
+
+// Stop disconnects every source and waits until they stopped publishing, or
+// the context is done. Sources aren't started again by later updates.
+func (m *sourceManager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	m.stopped = true
+	for source, running := range m.running {
+		running.cancel()
+		delete(m.running, source)
+	}
+	m.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("sources still running: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/receiver"
+)
+
+func TestSourceManager_Update(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var started []string
+	stopped := make(chan string, 10)
+
+	client := &mockNATSClient{}
+	health := receiver.NewMonitor()
+	m := newSourceManager(ctx, client, health)
+	m.ingest = func(ctx context.Context, source string, _ receiver.Options, _ *receiver.Health, c NATSClient) {
+		if c != client {
+			t.Errorf("Expected the shared NATS client for %s", source)
+		}
+		mu.Lock()
+		started = append(started, source)
+		mu.Unlock()
+		<-ctx.Done()
+		stopped <- source
+	}
+
+	running := func() []string {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		var sources []string
+		for source := range m.running {
+			sources = append(sources, source)
+		}
+		slices.Sort(sources)
+		return sources
+	}
+
+	defaults := receiver.DefaultOptions()
+	m.Update(map[string]receiver.Options{"a:30003": defaults, "b:30003": defaults})
+	m.Update(map[string]receiver.Options{"b:30003": defaults, "c:30003": defaults})
+
+	if got := running(); !reflect.DeepEqual(got, []string{"b:30003", "c:30003"}) {
+		t.Errorf("Expected sources b and c to be running, got %v", got)
+	}
+	if statuses := health.Statuses(); len(statuses) != 2 || statuses[0].Source != "b:30003" {
+		t.Errorf("Expected health of sources b and c, got %+v", statuses)
+	}
+	select {
+	case source := <-stopped:
+		if source != "a:30003" {
+			t.Errorf("Expected source a to be stopped, got %s", source)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected removed source to be stopped")
+	}
+
+	// Changing the connection options of a source restarts it
+	buffered := defaults
+	buffered.BufferSize = 4096
+	m.Update(map[string]receiver.Options{"b:30003": defaults, "c:30003": buffered})
+	select {
+	case source := <-stopped:
+		if source != "c:30003" {
+			t.Errorf("Expected source c to be restarted, got %s", source)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected source with new options to be restarted")
+	}
+	if opts := m.running["c:30003"].opts; opts.BufferSize != 4096 {
+		t.Errorf("Expected source c to run with the new options, got %+v", opts)
+	}
+
+	// Cancelling the parent context stops the remaining sources
+	cancel()
+	for range 2 {
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("Expected sources to stop with the context")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(started)
+	if !reflect.DeepEqual(started, []string{"a:30003", "b:30003", "c:30003", "c:30003"}) {
+		t.Errorf("Expected every source to be started once and c restarted, got %v", started)
+	}
+}
+
+func TestSourceManager_Stop(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	started := 0
+
+	m := newSourceManager(context.Background(), &mockNATSClient{}, receiver.NewMonitor())
+	m.ingest = func(ctx context.Context, _ string, _ receiver.Options, _ *receiver.Health, _ NATSClient) {
+		mu.Lock()
+		started++
+		mu.Unlock()
+		<-ctx.Done()
+		<-release // Still publishing the last messages read
+	}
+	m.Update(map[string]receiver.Options{"a:30003": receiver.DefaultOptions(), "b:30003": receiver.DefaultOptions()})
+
+	// Stop waits for the sources to return
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Stop(ctx); err == nil {
+		t.Error("Expected Stop() to time out while sources are running")
+	}
+	close(release)
+	if err := m.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() failed: %v", err)
+	}
+
+	// Sources aren't started again once stopped
+	m.Update(map[string]receiver.Options{"c:30003": receiver.DefaultOptions()})
+	mu.Lock()
+	defer mu.Unlock()
+	if started != 2 || len(m.running) != 0 {
+		t.Errorf("Expected no source started after Stop(), got %d started and %d running", started, len(m.running))
+	}
+}
+
+func TestConnectAndIngest_StopsOnCancel(t *testing.T) {
+	// A source that accepts the connection but never sends anything
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	defer func() {
+		if err := listener.Close(); err != nil {
+			t.Errorf("Failed to close listener: %v", err)
+		}
+	}()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- connectAndIngest(ctx, listener.Addr().String(), receiver.DefaultOptions(), receiver.NewHealth("test"), &mockNATSClient{})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected no error when stopped, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("connectAndIngest did not return when the context was cancelled")
+	}
+}
+
+func TestIngestSource_BacksOff(t *testing.T) {
+	// A source that accepts each connection and drops it at once
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	var mu sync.Mutex
+	var accepted []time.Time
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			accepted = append(accepted, time.Now())
+			mu.Unlock()
+			_ = conn.Close()
+		}
+	}()
+
+	opts := receiver.DefaultOptions()
+	opts.Backoff = receiver.Backoff{Initial: 10 * time.Millisecond, Max: time.Second, Jitter: -1}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ingestSource(ctx, listener.Addr().String(), opts, receiver.NewHealth("test"), &mockNATSClient{})
+		close(done)
+	}()
+	time.Sleep(400 * time.Millisecond)
+	cancel()
+	<-done
+	if err := listener.Close(); err != nil {
+		t.Errorf("Failed to close listener: %v", err)
+	}
+
+	// Delays of 10, 20, 40, 80 and 160ms fit in 400ms, not 40 fixed ones
+	mu.Lock()
+	defer mu.Unlock()
+	if len(accepted) < 3 || len(accepted) > 7 {
+		t.Fatalf("Expected the delays to grow, got %d connections", len(accepted))
+	}
+	if first, last := accepted[1].Sub(accepted[0]), accepted[len(accepted)-1].Sub(accepted[len(accepted)-2]); last <= first {
+		t.Errorf("Expected growing delays, got %s then %s", first, last)
+	}
+}
+
+func TestSessionHealthy(t *testing.T) {
+	started := time.Now().Add(-2 * stableSession)
+	connectedAt := func(at time.Time) *time.Time { return &at }
+
+	tests := []struct {
+		name     string
+		status   receiver.Status
+		expected bool
+	}{
+		{name: "never connected", status: receiver.Status{}, expected: false},
+		{name: "dropped at once", status: receiver.Status{LastConnected: connectedAt(time.Now())}, expected: false},
+		{name: "delivered messages", status: receiver.Status{Messages: 1, LastConnected: connectedAt(time.Now())}, expected: true},
+		{name: "stayed connected", status: receiver.Status{LastConnected: connectedAt(started)}, expected: true},
+		{name: "connected before the session", status: receiver.Status{LastConnected: connectedAt(started.Add(-time.Second))}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionHealthy(tt.status, started, 0); got != tt.expected {
+				t.Errorf("sessionHealthy() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
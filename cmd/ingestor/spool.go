@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/spool"
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+// spoolReplayInterval is how often spooled messages are replayed to NATS
+const spoolReplayInterval = 5 * time.Second
+
+// SpoolStatus is the state of the spool reported by the status endpoint
+type SpoolStatus struct {
+	spool.Depth
+	Dropped uint64 `json:"dropped"` // Messages lost because the spool was full
+}
+
+// syncPublisher is implemented by clients that publish asynchronously but
+// can also wait for each ack, which keeps replays in order
+type syncPublisher interface {
+	PublishSBSMessageSync(msg *types.SBSMessage) error
+}
+
+// spoolingClient publishes messages to NATS, writing them to an on-disk
+// spool when publishing fails. While the spool holds messages, new ones are
+// spooled behind them so they are published in order once NATS recovers.
+type spoolingClient struct {
+	client  NATSClient
+	replay  func(msg *types.SBSMessage) error // Publishes spooled messages
+	spool   *spool.Spool
+	dropped atomic.Uint64
+}
+
+// newSpoolingClient wraps client with the spool s
+func newSpoolingClient(client NATSClient, s *spool.Spool) *spoolingClient {
+	c := &spoolingClient{client: client, replay: client.PublishSBSMessage, spool: s}
+	if sync, ok := client.(syncPublisher); ok {
+		c.replay = sync.PublishSBSMessageSync
+	}
+	return c
+}
+
+// PublishSBSMessage publishes msg, or spools it if NATS is unavailable or
+// older messages are waiting in the spool
+func (c *spoolingClient) PublishSBSMessage(msg *types.SBSMessage) error {
+	if c.spool.Depth().Records == 0 {
+		err := c.client.PublishSBSMessage(msg)
+		if err == nil {
+			return nil
+		}
+		log.Printf("Warning: Failed to publish message, spooling: %v", err)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return c.append(data)
+}
+
+// PublishFailed spools a message whose async publish failed
+func (c *spoolingClient) PublishFailed(data []byte, err error) {
+	if c.spool.Depth().Records == 0 {
+		log.Printf("Warning: %v, spooling", err)
+	}
+	if err := c.append(data); err != nil {
+		log.Printf("Failed to publish message: %v", err)
+	}
+}
+
+// append writes a JSON encoded message to the spool, counting the messages
+// dropped because it is full
+func (c *spoolingClient) append(data []byte) error {
+	if err := c.spool.Append(data); err != nil {
+		if errors.Is(err, spool.ErrFull) {
+			c.dropped.Add(1)
+		}
+		return fmt.Errorf("failed to spool message: %w", err)
+	}
+	return nil
+}
+
+// Close closes the NATS client. The spool is closed separately.
+func (c *spoolingClient) Close() {
+	c.client.Close()
+}
+
+// Status returns the depth of the spool and the messages dropped
+func (c *spoolingClient) Status() SpoolStatus {
+	return SpoolStatus{Depth: c.spool.Depth(), Dropped: c.dropped.Load()}
+}
+
+// replaySpool publishes the spooled messages in order, stopping at the
+// first message NATS doesn't accept, and returns the number published
+func (c *spoolingClient) replaySpool() (int, error) {
+	published := 0
+	err := c.spool.Replay(func(data []byte) error {
+		var msg types.SBSMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("Warning: Dropping invalid spooled message: %v", err)
+			return nil
+		}
+		if err := c.replay(&msg); err != nil {
+			return err
+		}
+		published++
+		return nil
+	})
+	return published, err
+}
+
+// Run replays the spool every interval until the context is cancelled,
+// logging its depth while it holds messages
+func (c *spoolingClient) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = spoolReplayInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.spool.Depth().Records == 0 {
+				continue
+			}
+			published, err := c.replaySpool()
+			if err != nil {
+				status := c.Status()
+				log.Printf("Spool holds %d messages (%d bytes, %d dropped), replay paused: %v",
+					status.Records, status.Bytes, status.Dropped, err)
+				continue
+			}
+			log.Printf("Replayed %d spooled messages", published)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/spool"
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+func TestSpoolingClient(t *testing.T) {
+	s, err := spool.Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("spool.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	mockClient := &mockNATSClient{}
+	client := newSpoolingClient(mockClient, s)
+	publish := func(i int) {
+		msg := &types.SBSMessage{Raw: fmt.Sprintf("MSG,%d", i), Timestamp: time.Now().UTC(), Source: "receiver:30003"}
+		if err := client.PublishSBSMessage(msg); err != nil {
+			t.Fatalf("PublishSBSMessage() failed: %v", err)
+		}
+	}
+
+	// NATS is down, messages are spooled
+	publish(0)
+	mockClient.mu.Lock()
+	mockClient.publishError = errors.New("nats: timeout")
+	mockClient.mu.Unlock()
+	publish(1)
+	publish(2)
+	if status := client.Status(); status.Records != 2 {
+		t.Fatalf("Expected 2 spooled messages, got %+v", status)
+	}
+	if published, err := client.replaySpool(); err == nil || published != 0 {
+		t.Errorf("Expected replay to fail while NATS is down, got %d, %v", published, err)
+	}
+
+	// Once NATS recovers, new messages queue behind the spooled ones
+	mockClient.mu.Lock()
+	mockClient.publishError = nil
+	mockClient.mu.Unlock()
+	publish(3)
+	if count := mockClient.GetPublishedMessagesCount(); count != 1 {
+		t.Errorf("Expected new messages to be spooled while the spool isn't empty, got %d published", count)
+	}
+
+	if published, err := client.replaySpool(); err != nil || published != 3 {
+		t.Fatalf("replay() = %d, %v, expected 3 messages", published, err)
+	}
+	for i, msg := range mockClient.GetPublishedMessages() {
+		if expected := fmt.Sprintf("MSG,%d", i); msg.Raw != expected || msg.Source != "receiver:30003" {
+			t.Errorf("Message %d = %+v, expected %s", i, msg, expected)
+		}
+	}
+
+	// With an empty spool, messages are published directly
+	publish(4)
+	if count := mockClient.GetPublishedMessagesCount(); count != 5 {
+		t.Errorf("Expected 5 published messages, got %d", count)
+	}
+}
+
+func TestSpoolingClient_Full(t *testing.T) {
+	s, err := spool.Open(t.TempDir(), 64, 64)
+	if err != nil {
+		t.Fatalf("spool.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	client := newSpoolingClient(&mockNATSClient{publishError: errors.New("nats: no servers available")}, s)
+	msg := &types.SBSMessage{Raw: "MSG,3,1,1,ABC123,1,2024/01/01,00:00:00.000,2024/01/01,00:00:00.000", Source: "receiver:30003"}
+	if err := client.PublishSBSMessage(msg); !errors.Is(err, spool.ErrFull) {
+		t.Errorf("Expected ErrFull, got %v", err)
+	}
+	if status := client.Status(); status.Dropped != 1 || status.Records != 0 {
+		t.Errorf("Expected 1 dropped message, got %+v", status)
+	}
+}
+
+func TestSpoolingClient_Run(t *testing.T) {
+	s, err := spool.Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("spool.Open() failed: %v", err)
+	}
+	defer s.Close()
+	if err := s.Append([]byte(`{"raw":"MSG,1","source":"receiver:30003"}`)); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+
+	mockClient := &mockNATSClient{}
+	client := newSpoolingClient(mockClient, s)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Run(ctx, 10*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for mockClient.GetPublishedMessagesCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if messages := mockClient.GetPublishedMessages(); len(messages) != 1 || messages[0].Raw != "MSG,1" {
+		t.Errorf("Expected the spooled message to be replayed, got %+v", messages)
+	}
+
+	client.Close()
+	if !mockClient.IsClosed() {
+		t.Error("Expected Close() to close the NATS client")
+	}
+}
+
+// mockAsyncNATSClient publishes asynchronously, replaying with sync publishes
+type mockAsyncNATSClient struct {
+	mockNATSClient
+	synced []*types.SBSMessage
+}
+
+func (m *mockAsyncNATSClient) PublishSBSMessageSync(msg *types.SBSMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.synced = append(m.synced, msg)
+	return nil
+}
+
+func TestSpoolingClient_PublishFailed(t *testing.T) {
+	s, err := spool.Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("spool.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	mockClient := &mockAsyncNATSClient{}
+	client := newSpoolingClient(mockClient, s)
+
+	// A message whose ack failed is spooled, and the next ones queue behind it
+	client.PublishFailed([]byte(`{"raw":"MSG,1","source":"receiver:30003"}`), errors.New("nats: timeout"))
+	if err := client.PublishSBSMessage(&types.SBSMessage{Raw: "MSG,2", Source: "receiver:30003"}); err != nil {
+		t.Fatalf("PublishSBSMessage() failed: %v", err)
+	}
+	if count := mockClient.GetPublishedMessagesCount(); count != 0 {
+		t.Errorf("Expected messages to be spooled, got %d published", count)
+	}
+
+	if published, err := client.replaySpool(); err != nil || published != 2 {
+		t.Fatalf("replaySpool() = %d, %v, expected 2 messages", published, err)
+	}
+	if len(mockClient.synced) != 2 || mockClient.synced[0].Raw != "MSG,1" || mockClient.synced[1].Raw != "MSG,2" {
+		t.Errorf("Expected the spool to be replayed in order with sync publishes, got %+v", mockClient.synced)
+	}
+}
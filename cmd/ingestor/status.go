@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/health"
+	"github.com/saviobatista/sbs-logger/internal/nats"
+	"github.com/saviobatista/sbs-logger/internal/receiver"
+)
+
+// StatusPublisher publishes the health of the sources, e.g. on NATS
+type StatusPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// ConnectionReporter reports the state of the NATS connection
+type ConnectionReporter interface {
+	ConnectionStats() nats.ConnStats
+}
+
+// newStatusHandler returns the HTTP handler of the source health endpoint.
+// The spool is nil when spooling is disabled.
+func newStatusHandler(health *receiver.Monitor, spooler *spoolingClient, conn ConnectionReporter) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sources", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, health.Statuses())
+	})
+	mux.HandleFunc("GET /sources/{source}", func(w http.ResponseWriter, r *http.Request) {
+		status, ok := health.Status(r.PathValue("source"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "source not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	})
+	mux.HandleFunc("GET /spool", func(w http.ResponseWriter, _ *http.Request) {
+		if spooler == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "spool disabled"})
+			return
+		}
+		writeJSON(w, http.StatusOK, spooler.Status())
+	})
+	mux.HandleFunc("GET /nats", func(w http.ResponseWriter, _ *http.Request) {
+		if conn == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "no NATS connection"})
+			return
+		}
+		writeJSON(w, http.StatusOK, conn.ConnectionStats())
+	})
+	return mux
+}
+
+// writeJSON writes body as a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Warning: Failed to write status response: %v", err)
+	}
+}
+
+// setupStatus starts the HTTP status endpoint on addr, if set
+func setupStatus(health *receiver.Monitor, spooler *spoolingClient, conn ConnectionReporter, addr string) (*http.Server, error) {
+	if addr == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           newStatusHandler(health, spooler, conn),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Status endpoint stopped: %v", err)
+		}
+	}()
+	log.Printf("Status endpoint listening on %s", listener.Addr())
+	return server, nil
+}
+
+// setupHealth starts the liveness and readiness endpoints on addr, if set,
+// reporting the NATS connection and the last message from any source
+func setupHealth(sources *receiver.Monitor, ping health.Check, addr string, maxMessageAge time.Duration) (*health.Checker, *http.Server, error) {
+	checker := health.New(sources.LastMessage, maxMessageAge)
+	checker.Add("nats", ping)
+	server, err := checker.Serve(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return checker, server, nil
+}
+
+// publishStatus publishes the health of every source on subject every
+// interval until the context is cancelled
+func publishStatus(ctx context.Context, publisher StatusPublisher, health *receiver.Monitor, subject string, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			data, err := json.Marshal(health.Statuses())
+			if err != nil {
+				log.Printf("Warning: Failed to marshal source status: %v", err)
+				continue
+			}
+			if err := publisher.Publish(subject, data); err != nil {
+				log.Printf("Warning: Failed to publish source status: %v", err)
+			}
+		}
+	}
+}
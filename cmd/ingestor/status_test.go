@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/nats"
+	"github.com/saviobatista/sbs-logger/internal/receiver"
+	"github.com/saviobatista/sbs-logger/internal/spool"
+)
+
+// mockStatusPublisher records the published source status
+type mockStatusPublisher struct {
+	mu         sync.Mutex
+	subjects   []string
+	payloads   [][]byte
+	publishErr error
+}
+
+func (m *mockStatusPublisher) Publish(subject string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.publishErr != nil {
+		return m.publishErr
+	}
+	m.subjects = append(m.subjects, subject)
+	m.payloads = append(m.payloads, data)
+	return nil
+}
+
+func TestStatusHandler(t *testing.T) {
+	health := receiver.NewMonitor()
+	health.Add("receiver:30003").Connected()
+	handler := newStatusHandler(health, nil, nil)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "list", path: "/sources", expectedStatus: http.StatusOK, expectedBody: `"state":"connected"`},
+		{name: "get", path: "/sources/receiver:30003", expectedStatus: http.StatusOK, expectedBody: `"source":"receiver:30003"`},
+		{name: "get missing", path: "/sources/other:30003", expectedStatus: http.StatusNotFound},
+		{name: "spool disabled", path: "/spool", expectedStatus: http.StatusNotFound},
+		{name: "no NATS connection", path: "/nats", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedBody != "" && !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %s, got %s", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestStatusHandler_Spool(t *testing.T) {
+	s, err := spool.Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("spool.Open() failed: %v", err)
+	}
+	defer s.Close()
+	if err := s.Append([]byte(`{"raw":"MSG,1"}`)); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	handler := newStatusHandler(receiver.NewMonitor(), newSpoolingClient(&mockNATSClient{}, s), nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/spool", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var status SpoolStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode spool status: %v", err)
+	}
+	if status.Records != 1 || status.Segments != 1 || status.Dropped != 0 {
+		t.Errorf("Unexpected spool status: %+v", status)
+	}
+}
+
+// mockConnectionReporter reports fixed NATS connection stats
+type mockConnectionReporter struct {
+	stats nats.ConnStats
+}
+
+func (m *mockConnectionReporter) ConnectionStats() nats.ConnStats {
+	return m.stats
+}
+
+func TestStatusHandler_NATS(t *testing.T) {
+	conn := &mockConnectionReporter{stats: nats.ConnStats{Connected: true, Server: "nats://nats:4222", Disconnects: 2, Reconnects: 2}}
+	handler := newStatusHandler(receiver.NewMonitor(), nil, conn)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stats nats.ConnStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode NATS stats: %v", err)
+	}
+	if !stats.Connected || stats.Server != "nats://nats:4222" || stats.Reconnects != 2 {
+		t.Errorf("Unexpected NATS stats: %+v", stats)
+	}
+}
+
+func TestSetupStatus(t *testing.T) {
+	health := receiver.NewMonitor()
+
+	server, err := setupStatus(health, nil, nil, "")
+	if err != nil || server != nil {
+		t.Errorf("Expected no status endpoint without an address, got %v, %v", server, err)
+	}
+
+	if _, err := setupStatus(health, nil, nil, "invalid-address"); err == nil {
+		t.Error("Expected error for invalid address")
+	}
+
+	server, err = setupStatus(health, nil, nil, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("setupStatus() failed: %v", err)
+	}
+	if err := server.Close(); err != nil {
+		t.Errorf("Failed to close status endpoint: %v", err)
+	}
+}
+
+func TestSetupHealth(t *testing.T) {
+	sources := receiver.NewMonitor()
+	pingErr := errors.New("not connected to NATS")
+	ping := func(context.Context) error { return pingErr }
+
+	checker, server, err := setupHealth(sources, ping, "", time.Minute)
+	if err != nil || server != nil {
+		t.Fatalf("Expected no health endpoint without an address, got %v, %v", server, err)
+	}
+	if report := checker.Ready(context.Background()); report.OK() || report.Checks["nats"].Error != pingErr.Error() {
+		t.Errorf("Expected the NATS check to fail, got %+v", report)
+	}
+
+	// Messages from any source count
+	sources.Add("receiver:30003").Received(120, 2)
+	if report := checker.Live(); !report.OK() || time.Since(report.LastMessageTime) > time.Minute {
+		t.Errorf("Expected a recent message, got %+v", report)
+	}
+
+	if _, _, err := setupHealth(sources, ping, "invalid-address", time.Minute); err == nil {
+		t.Error("Expected error for invalid address")
+	}
+	_, server, err = setupHealth(sources, ping, "127.0.0.1:0", time.Minute)
+	if err != nil {
+		t.Fatalf("setupHealth() failed: %v", err)
+	}
+	if err := server.Close(); err != nil {
+		t.Errorf("Failed to close health endpoint: %v", err)
+	}
+}
+
+func TestPublishStatus(t *testing.T) {
+	health := receiver.NewMonitor()
+	health.Add("receiver:30003").Backoff(fmt.Errorf("connection refused"), time.Second)
+	publisher := &mockStatusPublisher{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	publishStatus(ctx, publisher, health, "sbs.sources", 10*time.Millisecond)
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	if len(publisher.payloads) == 0 || publisher.subjects[0] != "sbs.sources" {
+		t.Fatalf("Expected status to be published on sbs.sources, got %v", publisher.subjects)
+	}
+	var statuses []receiver.Status
+	if err := json.Unmarshal(publisher.payloads[0], &statuses); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].State != receiver.StateBackoff || statuses[0].LastError != "connection refused" {
+		t.Errorf("Unexpected published status: %+v", statuses)
+	}
+
+	// Publishing is disabled without an interval
+	disabled := &mockStatusPublisher{}
+	publishStatus(context.Background(), disabled, health, "sbs.sources", 0)
+	if len(disabled.payloads) != 0 {
+		t.Error("Expected no status published without an interval")
+	}
+}
@@ -2,30 +2,67 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 
+	"github.com/saviobatista/sbs-logger/internal/config"
+	"github.com/saviobatista/sbs-logger/internal/health"
 	"github.com/saviobatista/sbs-logger/internal/nats"
+	"github.com/saviobatista/sbs-logger/internal/shutdown"
 	"github.com/saviobatista/sbs-logger/internal/types"
 )
 
 func main() {
-	if err := runLogger(); err != nil {
-		log.Printf("Logger failed: %v", err)
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of the YAML or TOML (.toml) configuration file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration and exit")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load(config.Logger, *configPath)
+	if err != nil {
+		log.Printf("Invalid configuration: %v", err)
 		os.Exit(1)
 	}
+	if *printConfig {
+		fmt.Print(cfg.Effective(config.Logger))
+		return
+	}
+	log.Printf("Effective configuration:\n%s", cfg.Effective(config.Logger))
+
+	shutdown.Exit(runLogger(cfg))
+}
+
+// natsOptions returns the options of the logger's NATS connection
+func natsOptions(cfg *config.NATSConfig) (*nats.Options, error) {
+	conn := cfg.Connection(config.Logger)
+	opts := &nats.Options{
+		URL:             cfg.URL,
+		Name:            "sbs-" + string(config.Logger),
+		CredentialsFile: conn.CredsFile,
+		NKeyFile:        conn.NKeyFile,
+		User:            conn.User,
+		Password:        conn.Password,
+		TLSCAFile:       conn.TLSCAFile,
+		TLSCertFile:     conn.TLSCertFile,
+		TLSKeyFile:      conn.TLSKeyFile,
+		ReconnectWait:   conn.ReconnectWait,
+		MaxReconnects:   conn.MaxReconnects,
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid nats settings of the logger: %w", err)
+	}
+	return opts, nil
 }
 
 // runLogger contains the main application logic and can be tested
-func runLogger() error {
-	// Load configuration
-	outputDir, natsURL := parseEnvironment()
+func runLogger(cfg *config.Config) error {
+	outputDir := cfg.Logger.OutputDir
 
 	// Create output directory if it doesn't exist
 	if err := os.MkdirAll(outputDir, 0o750); err != nil {
@@ -33,7 +70,11 @@ func runLogger() error {
 	}
 
 	// Create NATS client
-	client, err := nats.New(natsURL)
+	natsOpts, err := natsOptions(&cfg.NATS)
+	if err != nil {
+		return err
+	}
+	client, err := nats.NewWithOptions(natsOpts)
 	if err != nil {
 		return fmt.Errorf("failed to create NATS client: %w", err)
 	}
@@ -58,32 +99,36 @@ func runLogger() error {
 		return fmt.Errorf("failed to subscribe to SBS messages: %w", err)
 	}
 
-	// Wait for shutdown signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
-
-	log.Println("Shutting down...")
-	client.Close()          // Close client before canceling context
-	cancel()                // Cancel context after closing client
-	time.Sleep(time.Second) // Give time for goroutines to clean up
-
-	return nil
-}
-
-// parseEnvironment extracts environment variables with defaults
-func parseEnvironment() (string, string) {
-	outputDir := os.Getenv("OUTPUT_DIR")
-	if outputDir == "" {
-		outputDir = "./logs" // Default output directory
+	// Report the NATS connection and the last message written
+	checker := health.New(logger.LastMessage, cfg.Health.MaxMessageAge)
+	checker.Add("nats", client.Ping)
+	server, err := checker.Serve(cfg.Health.Addr)
+	if err != nil {
+		client.Close()
+		cancel()
+		return fmt.Errorf("failed to start health endpoint: %w", err)
 	}
 
-	natsURL := os.Getenv("NATS_URL")
-	if natsURL == "" {
-		natsURL = "nats://nats:4222" // Default to Docker service name
+	// Wait for shutdown signal
+	sig := shutdown.Wait()
+	log.Printf("Received %s, shutting down...", sig)
+	checker.Stopping()
+
+	// Stop reporting health, then write the messages already received before
+	// syncing the log file. The rotation timer runs until then, as writes may
+	// trigger a rotation.
+	var steps []shutdown.Step
+	if server != nil {
+		steps = append(steps, shutdown.Step{Name: "stop health endpoint", Run: server.Shutdown})
 	}
-
-	return outputDir, natsURL
+	steps = append(steps,
+		shutdown.Step{Name: "drain NATS", Run: client.Drain},
+		shutdown.Func("close log file", func() error {
+			cancel()
+			return logger.Close()
+		}),
+	)
+	return shutdown.Run(cfg.ShutdownTimeout, steps...)
 }
 
 // Logger handles writing messages to log files
@@ -92,15 +137,18 @@ type Logger struct {
 	currentFile  *os.File
 	currentDate  string
 	rotationChan chan struct{}
+	lastMessage  atomic.Int64 // Unix nanoseconds of the last message written
 	mu           sync.RWMutex
 }
 
 // NewLogger creates a new logger instance
 func NewLogger(outputDir string) *Logger {
-	return &Logger{
+	l := &Logger{
 		outputDir:    outputDir,
 		rotationChan: make(chan struct{}, 1),
 	}
+	l.lastMessage.Store(time.Now().UnixNano())
+	return l
 }
 
 // Start initializes the logger and starts the rotation timer
@@ -134,7 +182,34 @@ func (l *Logger) WriteMessage(msg *types.SBSMessage) error {
 	if _, err := currentFile.WriteString(msg.Raw); err != nil {
 		return fmt.Errorf("failed to write message: %w", err)
 	}
+	l.lastMessage.Store(time.Now().UnixNano())
+
+	return nil
+}
 
+// LastMessage returns when the last message was written, or when the logger
+// was created if none was
+func (l *Logger) LastMessage() time.Time {
+	return time.Unix(0, l.lastMessage.Load())
+}
+
+// Close syncs the current log file to disk and closes it
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.currentFile == nil {
+		return nil
+	}
+
+	file := l.currentFile
+	l.currentFile = nil
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync log file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
 	return nil
 }
 
@@ -90,7 +90,7 @@ func TestLoggerMain_Integration(t *testing.T) {
 	defer client.Close()
 
 	// Test environment parsing
-	outputDir, parsedNATSURL := parseEnvironment()
+	outputDir, parsedNATSURL := loadEnvironment(t)
 	if outputDir == "" {
 		t.Error("Expected output directory to be set")
 	}
@@ -212,7 +212,7 @@ func TestLoggerMain_EnvironmentVariables(t *testing.T) {
 			}
 
 			// Test environment parsing
-			outputDir, natsURL := parseEnvironment()
+			outputDir, natsURL := loadEnvironment(t)
 
 			if tt.outputDir == "" {
 				// Should use default
@@ -486,7 +486,7 @@ func TestLoggerMain_ErrorHandling(t *testing.T) {
 			tt.setupEnv()
 
 			// Test environment parsing
-			outputDir, natsURL := parseEnvironment()
+			outputDir, natsURL := loadEnvironment(t)
 
 			if tt.name == "invalid NATS URL" {
 				// Should still parse the URL even if invalid
@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/saviobatista/sbs-logger/internal/config"
+	"github.com/saviobatista/sbs-logger/internal/nats"
 	"github.com/saviobatista/sbs-logger/internal/types"
 )
 
@@ -51,7 +53,7 @@ func TestParseEnvironment(t *testing.T) {
 			os.Setenv("OUTPUT_DIR", tt.outputDir)
 			os.Setenv("NATS_URL", tt.natsURL)
 
-			outputDir, natsURL := parseEnvironment()
+			outputDir, natsURL := loadEnvironment(t)
 
 			if outputDir != tt.expectedOutputDir {
 				t.Errorf("Expected output dir %q, got %q", tt.expectedOutputDir, outputDir)
@@ -313,6 +315,59 @@ func TestLogger_WriteMessage(t *testing.T) {
 	}
 }
 
+// TestLogger_LastMessage tests that writes update the last message time
+func TestLogger_LastMessage(t *testing.T) {
+	logger := NewLogger(t.TempDir())
+	if err := logger.rotateFile(); err != nil {
+		t.Fatalf("rotateFile() failed: %v", err)
+	}
+	defer func() { _ = logger.Close() }()
+
+	created := logger.LastMessage()
+	if time.Since(created) > time.Minute {
+		t.Errorf("Expected the creation time before any message, got %s", created)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := logger.WriteMessage(&types.SBSMessage{Raw: "test message\n"}); err != nil {
+		t.Fatalf("WriteMessage() failed: %v", err)
+	}
+	if !logger.LastMessage().After(created) {
+		t.Error("Expected the write to update the last message time")
+	}
+}
+
+// TestLogger_Close tests that closing the logger keeps the written messages
+func TestLogger_Close(t *testing.T) {
+	logger := NewLogger(t.TempDir())
+	if err := logger.rotateFile(); err != nil {
+		t.Fatalf("rotateFile() failed: %v", err)
+	}
+	if err := logger.WriteMessage(&types.SBSMessage{Raw: "MSG,3,1,1,ABC123,1\n"}); err != nil {
+		t.Fatalf("WriteMessage() failed: %v", err)
+	}
+	path := logger.GetCurrentFile().Name()
+
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if logger.GetCurrentFile() != nil {
+		t.Error("Expected no current file after Close()")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "MSG,3,1,1,ABC123,1\n" {
+		t.Errorf("Expected the message in %s, got %q: %v", path, data, err)
+	}
+
+	// Closing again is a no-op, and later writes fail
+	if err := logger.Close(); err != nil {
+		t.Errorf("Expected a second Close() to succeed, got %v", err)
+	}
+	if err := logger.WriteMessage(&types.SBSMessage{Raw: "late\n"}); err == nil {
+		t.Error("Expected error writing after Close()")
+	}
+}
+
 // TestCompressFile tests comprehensive compression scenarios
 func TestCompressFile(t *testing.T) {
 	tests := []struct {
@@ -443,16 +498,34 @@ func TestLogger_ErrorScenarios(t *testing.T) {
 	})
 }
 
+// TestNATSOptions tests the options of the NATS connection built from the configuration
+func TestNATSOptions(t *testing.T) {
+	cfg := config.Default()
+	cfg.NATS.URL = "tls://nats:4222"
+	cfg.NATS.TLSCAFile = "/etc/nats/ca.pem"
+	cfg.NATS.User = "sbs"
+	cfg.NATS.Password = "shared-secret"
+	cfg.NATS.MaxReconnects = 100
+	cfg.NATS.Services = map[config.Service]config.NATSConnection{config.Ingestor: {User: "ingestor"}}
+
+	opts, err := natsOptions(&cfg.NATS)
+	if err != nil {
+		t.Fatalf("natsOptions() failed: %v", err)
+	}
+	expected := nats.Options{URL: "tls://nats:4222", Name: "sbs-logger", User: "sbs", Password: "shared-secret",
+		TLSCAFile: "/etc/nats/ca.pem", ReconnectWait: config.DefaultNATSReconnectWait, MaxReconnects: 100}
+	if *opts != expected {
+		t.Errorf("natsOptions() = %+v, expected %+v", *opts, expected)
+	}
+
+	cfg.NATS.Services = map[config.Service]config.NATSConnection{config.Logger: {TLSKeyFile: "key.pem"}}
+	if _, err := natsOptions(&cfg.NATS); err == nil {
+		t.Error("Expected error for a TLS key without certificate")
+	}
+}
+
 // TestRunLogger tests the main application logic
 func TestRunLogger(t *testing.T) {
-	// Save original environment
-	originalOutputDir := os.Getenv("OUTPUT_DIR")
-	originalNATSURL := os.Getenv("NATS_URL")
-	defer func() {
-		os.Setenv("OUTPUT_DIR", originalOutputDir)
-		os.Setenv("NATS_URL", originalNATSURL)
-	}()
-
 	tests := []struct {
 		name          string
 		outputDir     string
@@ -463,13 +536,13 @@ func TestRunLogger(t *testing.T) {
 		{
 			name:          "invalid output directory",
 			outputDir:     "/invalid/path/that/doesnt/exist",
-			natsURL:       "",
+			natsURL:       "nats://nats:4222",
 			expectError:   true,
 			errorContains: "failed to create output directory",
 		},
 		{
 			name:          "invalid NATS URL",
-			outputDir:     "/tmp/test-logs",
+			outputDir:     t.TempDir(),
 			natsURL:       "invalid://url",
 			expectError:   true,
 			errorContains: "failed to create NATS client",
@@ -478,22 +551,12 @@ func TestRunLogger(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Set environment variables
-			os.Setenv("OUTPUT_DIR", tt.outputDir)
-			os.Setenv("NATS_URL", tt.natsURL)
-
-			// Create a temporary directory for valid tests
-			if tt.outputDir == "/tmp/test-logs" {
-				tempDir, err := os.MkdirTemp("", "logger-test")
-				if err != nil {
-					t.Fatalf("Failed to create temp dir: %v", err)
-				}
-				defer os.RemoveAll(tempDir)
-				os.Setenv("OUTPUT_DIR", tempDir)
-			}
+			cfg := config.Default()
+			cfg.Logger.OutputDir = tt.outputDir
+			cfg.NATS.URL = tt.natsURL
 
 			// Run the logger
-			err := runLogger()
+			err := runLogger(cfg)
 
 			if tt.expectError && err == nil {
 				t.Error("Expected error, got none")
@@ -515,26 +578,12 @@ func TestRunLogger_Integration(t *testing.T) {
 	// This test requires a NATS server to be running
 	// For now, we'll test the error case when NATS is not available
 	t.Run("NATS server not available", func(t *testing.T) {
-		// Save original environment
-		originalOutputDir := os.Getenv("OUTPUT_DIR")
-		originalNATSURL := os.Getenv("NATS_URL")
-		defer func() {
-			os.Setenv("OUTPUT_DIR", originalOutputDir)
-			os.Setenv("NATS_URL", originalNATSURL)
-		}()
-
-		// Set up test environment
-		tempDir, err := os.MkdirTemp("", "logger-test")
-		if err != nil {
-			t.Fatalf("Failed to create temp dir: %v", err)
-		}
-		defer os.RemoveAll(tempDir)
-
-		os.Setenv("OUTPUT_DIR", tempDir)
-		os.Setenv("NATS_URL", "nats://localhost:4222") // Use localhost which should not be available
+		cfg := config.Default()
+		cfg.Logger.OutputDir = t.TempDir()
+		cfg.NATS.URL = "nats://localhost:4222" // Use localhost which should not be available
 
 		// Run the logger
-		err = runLogger()
+		err := runLogger(cfg)
 		if err == nil {
 			t.Error("Expected error when NATS server is not available")
 		}
@@ -590,7 +639,7 @@ func TestParseEnvironment_Integration(t *testing.T) {
 			os.Setenv("OUTPUT_DIR", tt.outputDir)
 			os.Setenv("NATS_URL", tt.natsURL)
 
-			outputDir, natsURL := parseEnvironment()
+			outputDir, natsURL := loadEnvironment(t)
 
 			if outputDir != tt.expectedOutputDir {
 				t.Errorf("Expected output dir %q, got %q", tt.expectedOutputDir, outputDir)
@@ -604,3 +653,14 @@ func TestParseEnvironment_Integration(t *testing.T) {
 }
 
 // Helper functions
+
+// loadEnvironment loads the logger configuration from the environment and
+// returns the output directory and NATS URL
+func loadEnvironment(t *testing.T) (string, string) {
+	t.Helper()
+	cfg, err := config.Load(config.Logger, "")
+	if err != nil {
+		t.Fatalf("config.Load() failed: %v", err)
+	}
+	return cfg.Logger.OutputDir, cfg.NATS.URL
+}
@@ -0,0 +1,97 @@
+// Command redis-migrate renames the keys written by the tracker from one key
+// prefix to another, e.g. when a deployment that had no prefix starts sharing
+// a Redis with another one:
+//
+//	redis-migrate -url redis://redis:6379 -from "" -to prod
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/saviobatista/sbs-logger/internal/redis"
+)
+
+// config holds the command line options
+type config struct {
+	url    string
+	from   string
+	to     string
+	dryRun bool
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		log.Printf("Migration failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the arguments and renames the keys
+func run(args []string, output io.Writer) error {
+	cfg, err := parseFlags(args, output)
+	if err != nil {
+		return err
+	}
+
+	opts, err := redis.ParseURL(cfg.url)
+	if err != nil {
+		return err
+	}
+	opts.KeyPrefix = cfg.to
+
+	client, err := redis.NewWithOptions(opts)
+	if err != nil {
+		return fmt.Errorf("failed to create Redis client: %w", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error closing redisClient: %v\n", err)
+		}
+	}()
+
+	renamed, err := client.MigrateKeys(context.Background(), cfg.from, cfg.dryRun)
+	if err != nil {
+		return err
+	}
+	if cfg.dryRun {
+		log.Printf("Would rename %d keys from prefix %q to %q", renamed, cfg.from, cfg.to)
+	} else {
+		log.Printf("Renamed %d keys from prefix %q to %q", renamed, cfg.from, cfg.to)
+	}
+	return nil
+}
+
+// parseFlags parses the command line, defaulting the URL to REDIS_URL or REDIS_ADDR
+func parseFlags(args []string, output io.Writer) (*config, error) {
+	defaultURL := os.Getenv("REDIS_URL")
+	if defaultURL == "" {
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			addr = "redis:6379" // Default to Docker service name
+		}
+		defaultURL = "redis://" + addr
+	}
+
+	cfg := &config{}
+	flags := flag.NewFlagSet("redis-migrate", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&cfg.url, "url", defaultURL, "Redis URL (default from REDIS_URL or REDIS_ADDR)")
+	flags.StringVar(&cfg.from, "from", "", "key prefix to rename from (empty for unprefixed keys)")
+	flags.StringVar(&cfg.to, "to", "", "key prefix to rename to (empty for unprefixed keys)")
+	flags.BoolVar(&cfg.dryRun, "dry-run", false, "only count the keys that would be renamed")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+	if cfg.from == cfg.to {
+		return nil, fmt.Errorf("-from and -to must be different prefixes")
+	}
+	return cfg, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		expected    config
+		expectError bool
+	}{
+		{
+			name:     "add prefix",
+			args:     []string{"-to", "prod"},
+			expected: config{url: "redis://redis:6379", to: "prod"},
+		},
+		{
+			name:     "url from environment",
+			args:     []string{"-from", "old", "-to", "new", "-dry-run"},
+			env:      map[string]string{"REDIS_URL": "rediss://cache:6380/1"},
+			expected: config{url: "rediss://cache:6380/1", from: "old", to: "new", dryRun: true},
+		},
+		{
+			name:     "address from environment",
+			args:     []string{"-from", "prod"},
+			env:      map[string]string{"REDIS_ADDR": "localhost:6379"},
+			expected: config{url: "redis://localhost:6379", from: "prod"},
+		},
+		{name: "same prefix", args: []string{"-from", "prod", "-to", "prod"}, expectError: true},
+		{name: "no prefixes", args: []string{}, expectError: true},
+		{name: "unknown flag", args: []string{"-force"}, expectError: true},
+		{name: "extra arguments", args: []string{"-to", "prod", "extra"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REDIS_URL", tt.env["REDIS_URL"])
+			t.Setenv("REDIS_ADDR", tt.env["REDIS_ADDR"])
+
+			cfg, err := parseFlags(tt.args, io.Discard)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFlags() unexpected error: %v", err)
+			}
+			if *cfg != tt.expected {
+				t.Errorf("parseFlags() = %+v, expected %+v", *cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRun_InvalidURL(t *testing.T) {
+	if err := run([]string{"-url", "http://cache", "-to", "prod"}, io.Discard); err == nil {
+		t.Error("Expected error for invalid URL")
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/filter"
+	"github.com/saviobatista/sbs-logger/internal/health"
+	"github.com/saviobatista/sbs-logger/internal/redis"
+	"github.com/saviobatista/sbs-logger/internal/types"
+	"github.com/saviobatista/sbs-logger/internal/watchlist"
+)
+
+// maxRequestBody limits the size of API request bodies
+const maxRequestBody = 1 << 20
+
+// maxNearbyRadiusKm limits the radius of nearby aircraft queries
+const maxNearbyRadiusKm = 1000
+
+// aircraftInfo is the latest state of an aircraft with the attributes
+// enriched into its flights from the ICAO address and the aircraft database
+type aircraftInfo struct {
+	*types.AircraftState
+	Registration string `json:"registration"`
+	AircraftType string `json:"aircraft_type"`
+	Operator     string `json:"operator"`
+	Country      string `json:"country"`
+	Military     bool   `json:"military"`
+}
+
+// nearbyAircraft is an aircraft found near a point with its latest state
+type nearbyAircraft struct {
+	redis.NearbyAircraft
+	State *aircraftInfo `json:"state,omitempty"`
+}
+
+// apiServer serves the tracker HTTP API
+type apiServer struct {
+	tracker *StateTracker
+	token   string // Bearer token required to change the tracker, empty disables changes
+}
+
+// newAPIHandler returns the HTTP handler of the tracker API. Requests
+// changing the watchlists or filters must carry token as a bearer token, and
+// are rejected when it is empty.
+func newAPIHandler(tracker *StateTracker, token string) http.Handler {
+	s := &apiServer{tracker: tracker, token: token}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /watchlists", s.listWatchlists)
+	mux.HandleFunc("GET /watchlists/{name}", s.getWatchlist)
+	mux.HandleFunc("PUT /watchlists/{name}", s.authorized(s.putWatchlist))
+	mux.HandleFunc("DELETE /watchlists/{name}", s.authorized(s.deleteWatchlist))
+	mux.HandleFunc("GET /filters", s.getFilters)
+	mux.HandleFunc("PUT /filters", s.authorized(s.putFilters))
+	mux.HandleFunc("PUT /filters/rules/{name}", s.authorized(s.putFilterRule))
+	mux.HandleFunc("DELETE /filters/rules/{name}", s.authorized(s.deleteFilterRule))
+	mux.HandleFunc("GET /filters/stats", s.getFilterStats)
+	mux.HandleFunc("GET /aircraft", s.listAircraft)
+	mux.HandleFunc("GET /aircraft/nearby", s.getNearbyAircraft)
+	mux.HandleFunc("GET /flights", s.listFlights)
+	return mux
+}
+
+// authorized wraps a handler changing the tracker so it only runs for
+// requests carrying the API token
+func (s *apiServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" {
+			writeError(w, http.StatusForbidden, errors.New("changes through the API are disabled, set tracker.api_token to enable them"))
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid API token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// listWatchlists returns all watchlists
+func (s *apiServer) listWatchlists(w http.ResponseWriter, _ *http.Request) {
+	lists := s.watchlists(w)
+	if lists == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, lists.All())
+}
+
+// getWatchlist returns a single watchlist
+func (s *apiServer) getWatchlist(w http.ResponseWriter, r *http.Request) {
+	lists := s.watchlists(w)
+	if lists == nil {
+		return
+	}
+	entry, ok := lists.Get(r.PathValue("name"))
+	if !ok {
+		writeError(w, http.StatusNotFound, watchlist.ErrNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// putWatchlist creates or replaces a watchlist. The name in the path takes
+// precedence over the one in the body.
+func (s *apiServer) putWatchlist(w http.ResponseWriter, r *http.Request) {
+	lists := s.watchlists(w)
+	if lists == nil {
+		return
+	}
+
+	var entry watchlist.Watchlist
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&entry); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid watchlist: %w", err))
+		return
+	}
+	entry.Name = r.PathValue("name")
+	if err := entry.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := lists.Put(&entry); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &entry)
+}
+
+// deleteWatchlist removes a watchlist
+func (s *apiServer) deleteWatchlist(w http.ResponseWriter, r *http.Request) {
+	lists := s.watchlists(w)
+	if lists == nil {
+		return
+	}
+
+	err := lists.Delete(r.PathValue("name"))
+	switch {
+	case errors.Is(err, watchlist.ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// watchlists returns the tracker watchlists, writing an error response when
+// watchlists are disabled
+func (s *apiServer) watchlists(w http.ResponseWriter) *watchlist.List {
+	if s.tracker.watchlists == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("watchlists are not enabled"))
+	}
+	return s.tracker.watchlists
+}
+
+// getFilters returns the default action and the ordered filter rules
+func (s *apiServer) getFilters(w http.ResponseWriter, _ *http.Request) {
+	filters := s.filters(w)
+	if filters == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, filters.Config())
+}
+
+// putFilters replaces the default action and all the filter rules
+func (s *apiServer) putFilters(w http.ResponseWriter, r *http.Request) {
+	filters := s.filters(w)
+	if filters == nil {
+		return
+	}
+
+	var config filter.Config
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&config); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid filter configuration: %w", err))
+		return
+	}
+	if err := config.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := filters.SetConfig(config); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, filters.Config())
+}
+
+// putFilterRule replaces a filter rule in place or appends it. The name in
+// the path takes precedence over the one in the body.
+func (s *apiServer) putFilterRule(w http.ResponseWriter, r *http.Request) {
+	filters := s.filters(w)
+	if filters == nil {
+		return
+	}
+
+	var rule filter.Rule
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid filter rule: %w", err))
+		return
+	}
+	rule.Name = r.PathValue("name")
+	if err := rule.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := filters.PutRule(&rule); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &rule)
+}
+
+// deleteFilterRule removes a filter rule
+func (s *apiServer) deleteFilterRule(w http.ResponseWriter, r *http.Request) {
+	filters := s.filters(w)
+	if filters == nil {
+		return
+	}
+
+	err := filters.DeleteRule(r.PathValue("name"))
+	switch {
+	case errors.Is(err, filter.ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getFilterStats returns the number of messages dropped by each rule
+func (s *apiServer) getFilterStats(w http.ResponseWriter, _ *http.Request) {
+	filters := s.filters(w)
+	if filters == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"dropped": filters.Dropped()})
+}
+
+// filters returns the tracker filter pipeline, writing an error response when
+// filtering is disabled
+func (s *apiServer) filters(w http.ResponseWriter) *filter.Pipeline {
+	if s.tracker.filters == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("filters are not enabled"))
+	}
+	return s.tracker.filters
+}
+
+// listAircraft returns the latest state of all live aircraft
+func (s *apiServer) listAircraft(w http.ResponseWriter, r *http.Request) {
+	states, err := s.tracker.redis.ListAircraftStates(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	aircraft := make([]*aircraftInfo, len(states))
+	for i, state := range states {
+		aircraft[i] = s.enrich(state)
+	}
+	writeJSON(w, http.StatusOK, aircraft)
+}
+
+// listFlights returns all active flights
+func (s *apiServer) listFlights(w http.ResponseWriter, r *http.Request) {
+	flights, err := s.tracker.redis.ListActiveFlights(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, flights)
+}
+
+// getNearbyAircraft returns the aircraft within radius km of lat/lon, nearest
+// first, with their latest state
+func (s *apiServer) getNearbyAircraft(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	lat, latErr := strconv.ParseFloat(query.Get("lat"), 64)
+	lon, lonErr := strconv.ParseFloat(query.Get("lon"), 64)
+	if latErr != nil || lonErr != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		writeError(w, http.StatusBadRequest, errors.New("lat and lon must be a valid position"))
+		return
+	}
+	radius, err := strconv.ParseFloat(query.Get("radius"), 64)
+	if err != nil || radius <= 0 || radius > maxNearbyRadiusKm {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("radius must be between 0 and %d km", maxNearbyRadiusKm))
+		return
+	}
+
+	found, err := s.tracker.redis.AircraftWithin(r.Context(), lat, lon, radius)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	aircraft := make([]nearbyAircraft, len(found))
+	for i, a := range found {
+		aircraft[i].NearbyAircraft = a
+		state, err := s.tracker.redis.GetAircraftState(r.Context(), a.HexIdent)
+		if err != nil {
+			log.Printf("Warning: Failed to get aircraft state of %s: %v", a.HexIdent, err)
+			continue
+		}
+		if state != nil {
+			aircraft[i].State = s.enrich(state)
+		}
+	}
+	writeJSON(w, http.StatusOK, aircraft)
+}
+
+// enrich adds the attributes of an aircraft to its state the same way they
+// are added to its flights
+func (s *apiServer) enrich(state *types.AircraftState) *aircraftInfo {
+	flight := &types.Flight{HexIdent: state.HexIdent}
+	s.tracker.enrichFlight(flight)
+	return &aircraftInfo{
+		AircraftState: state,
+		Registration:  flight.Registration,
+		AircraftType:  flight.AircraftType,
+		Operator:      flight.Operator,
+		Country:       flight.Country,
+		Military:      flight.Military,
+	}
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Warning: Failed to write API response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// setupAPI starts the tracker HTTP API on addr, if set. token is the bearer
+// token required to change the watchlists and filters.
+func setupAPI(tracker *StateTracker, addr, token string) (*http.Server, error) {
+	if addr == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           newAPIHandler(tracker, token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Tracker API stopped: %v", err)
+		}
+	}()
+	log.Printf("Tracker API listening on %s", listener.Addr())
+	return server, nil
+}
+
+// setupHealth starts the liveness and readiness endpoints on addr, if set,
+// reporting the NATS, Postgres and Redis connections and the last message
+// processed by the tracker
+func setupHealth(tracker *StateTracker, natsPing, dbPing, redisPing health.Check, addr string, maxMessageAge time.Duration) (*health.Checker, *http.Server, error) {
+	checker := health.New(tracker.stats.LastMessage, maxMessageAge)
+	checker.Add("nats", natsPing)
+	checker.Add("postgres", dbPing)
+	checker.Add("redis", redisPing)
+	server, err := checker.Serve(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return checker, server, nil
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/health"
+	"github.com/saviobatista/sbs-logger/internal/types"
+	"github.com/saviobatista/sbs-logger/internal/watchlist"
+)
+
+// testAPIToken is the token authorizing changes in the API tests
+const testAPIToken = "test-token"
+
+func TestAPI_Watchlists(t *testing.T) {
+	tracker := NewStateTracker(&mockDBClient{}, newMockRedisClient())
+	tracker.SetWatchlists(watchlist.New())
+	handler := newAPIHandler(tracker, testAPIToken)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "list empty", method: http.MethodGet, path: "/watchlists", expectedStatus: http.StatusOK, expectedBody: "[]"},
+		{name: "get missing", method: http.MethodGet, path: "/watchlists/vip", expectedStatus: http.StatusNotFound},
+		{name: "put", method: http.MethodPut, path: "/watchlists/vip", body: `{"hex_idents": ["ae1234"], "callsigns": ["SAM*"]}`,
+			expectedStatus: http.StatusOK, expectedBody: `"hex_idents":["AE1234"]`},
+		{name: "put invalid json", method: http.MethodPut, path: "/watchlists/vip", body: `{`, expectedStatus: http.StatusBadRequest},
+		{name: "put without entries", method: http.MethodPut, path: "/watchlists/empty", body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, path: "/watchlists/vip", expectedStatus: http.StatusOK, expectedBody: `"name":"vip"`},
+		{name: "list", method: http.MethodGet, path: "/watchlists", expectedStatus: http.StatusOK, expectedBody: `"callsigns":["SAM*"]`},
+		{name: "delete", method: http.MethodDelete, path: "/watchlists/vip", expectedStatus: http.StatusNoContent},
+		{name: "delete missing", method: http.MethodDelete, path: "/watchlists/vip", expectedStatus: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPost, path: "/watchlists", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testAPIToken)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedBody != "" && !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %s, got %s", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPI_WatchlistsDisabled(t *testing.T) {
+	tracker := NewStateTracker(&mockDBClient{}, newMockRedisClient())
+	rec := httptest.NewRecorder()
+	newAPIHandler(tracker, testAPIToken).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/watchlists", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body["error"] == "" {
+		t.Errorf("Expected JSON error body, got %v", err)
+	}
+}
+
+func TestAPI_Authorization(t *testing.T) {
+	tracker := NewStateTracker(&mockDBClient{}, newMockRedisClient())
+	tracker.SetWatchlists(watchlist.New())
+	body := `{"hex_idents": ["AE1234"]}`
+
+	tests := []struct {
+		name           string
+		token          string
+		authorization  string
+		method         string
+		expectedStatus int
+	}{
+		{name: "changes disabled", method: http.MethodPut, authorization: "Bearer anything", expectedStatus: http.StatusForbidden},
+		{name: "missing token", token: testAPIToken, method: http.MethodPut, expectedStatus: http.StatusUnauthorized},
+		{name: "wrong token", token: testAPIToken, method: http.MethodPut, authorization: "Bearer other-token", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", token: testAPIToken, method: http.MethodDelete, authorization: "Basic " + testAPIToken, expectedStatus: http.StatusUnauthorized},
+		{name: "valid token", token: testAPIToken, method: http.MethodPut, authorization: "Bearer " + testAPIToken, expectedStatus: http.StatusOK},
+		{name: "reads without token", token: testAPIToken, method: http.MethodGet, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/watchlists/vip", strings.NewReader(body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			newAPIHandler(tracker, tt.token).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+	if _, ok := tracker.watchlists.Get("vip"); !ok {
+		t.Error("Expected the authorized request to create the watchlist")
+	}
+}
+
+func TestSetupAPI(t *testing.T) {
+	tracker := NewStateTracker(&mockDBClient{}, newMockRedisClient())
+
+	server, err := setupAPI(tracker, "", "")
+	if err != nil || server != nil {
+		t.Errorf("Expected no API server without an address, got %v, %v", server, err)
+	}
+
+	if _, err := setupAPI(tracker, "invalid-address", ""); err == nil {
+		t.Error("Expected error for invalid address")
+	}
+
+	server, err = setupAPI(tracker, "127.0.0.1:0", testAPIToken)
+	if err != nil {
+		t.Fatalf("setupAPI() failed: %v", err)
+	}
+	if err := server.Close(); err != nil {
+		t.Errorf("Failed to close API server: %v", err)
+	}
+}
+
+func TestSetupHealth(t *testing.T) {
+	tracker := NewStateTracker(&mockDBClient{}, newMockRedisClient())
+	ok := func(context.Context) error { return nil }
+	failed := func(context.Context) error { return errors.New("connection refused") }
+
+	checker, server, err := setupHealth(tracker, ok, failed, ok, "", time.Minute)
+	if err != nil || server != nil {
+		t.Fatalf("Expected no health endpoint without an address, got %v, %v", server, err)
+	}
+	report := checker.Ready(context.Background())
+	if report.OK() || report.Checks["postgres"].Status != health.StatusFail {
+		t.Errorf("Expected the postgres check to fail, got %+v", report)
+	}
+	if report.Checks["nats"].Status != health.StatusOK || report.Checks["redis"].Status != health.StatusOK {
+		t.Errorf("Expected the nats and redis checks to pass, got %+v", report)
+	}
+
+	// A tracker that stopped processing messages is stalled
+	tracker.stats.LastMessageTime = time.Now().Add(-time.Hour)
+	if checker.Live().OK() {
+		t.Error("Expected a stalled tracker to fail the liveness check")
+	}
+
+	if _, _, err := setupHealth(tracker, ok, ok, ok, "invalid-address", time.Minute); err == nil {
+		t.Error("Expected error for invalid address")
+	}
+	_, server, err = setupHealth(tracker, ok, ok, ok, "127.0.0.1:0", time.Minute)
+	if err != nil {
+		t.Fatalf("setupHealth() failed: %v", err)
+	}
+	if err := server.Close(); err != nil {
+		t.Errorf("Failed to close health endpoint: %v", err)
+	}
+}
+
+func TestAPI_Filters(t *testing.T) {
+	tracker := NewStateTracker(&mockDBClient{}, newMockRedisClient())
+	handler := newAPIHandler(tracker, testAPIToken)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "default config", method: http.MethodGet, path: "/filters", expectedStatus: http.StatusOK, expectedBody: `"default":"allow"`},
+		{name: "put config", method: http.MethodPut, path: "/filters",
+			body:           `{"default": "deny", "rules": [{"name": "local", "action": "allow", "sources": ["local"]}]}`,
+			expectedStatus: http.StatusOK, expectedBody: `"sources":["LOCAL"]`},
+		{name: "put invalid config", method: http.MethodPut, path: "/filters", body: `{"default": "block"}`, expectedStatus: http.StatusBadRequest},
+		{name: "put rule", method: http.MethodPut, path: "/filters/rules/ground", body: `{"action": "deny", "message_types": [9]}`,
+			expectedStatus: http.StatusOK, expectedBody: `"name":"ground"`},
+		{name: "put invalid rule", method: http.MethodPut, path: "/filters/rules/bad", body: `{"action": "drop"}`, expectedStatus: http.StatusBadRequest},
+		{name: "rules are ordered", method: http.MethodGet, path: "/filters", expectedStatus: http.StatusOK, expectedBody: `"name":"local"`},
+		{name: "stats", method: http.MethodGet, path: "/filters/stats", expectedStatus: http.StatusOK, expectedBody: `"dropped":{}`},
+		{name: "delete rule", method: http.MethodDelete, path: "/filters/rules/ground", expectedStatus: http.StatusNoContent},
+		{name: "delete missing rule", method: http.MethodDelete, path: "/filters/rules/ground", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testAPIToken)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedBody != "" && !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %s, got %s", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+
+	config := tracker.filters.Config()
+	if config.Default != "deny" || len(config.Rules) != 1 || config.Rules[0].Name != "local" {
+		t.Errorf("Unexpected filter config after API calls: %+v", config)
+	}
+}
+
+func TestAPI_NearbyAircraft(t *testing.T) {
+	redisClient := newMockRedisClient()
+	redisClient.aircraftStates["NEAR01"] = &types.AircraftState{HexIdent: "NEAR01", Callsign: "TAM3054", Latitude: -23.55, Longitude: -46.65}
+	redisClient.aircraftStates["FAR001"] = &types.AircraftState{HexIdent: "FAR001", Latitude: -22.9, Longitude: -43.2}
+	tracker := NewStateTracker(&mockDBClient{}, redisClient)
+	tracker.SetRegistry(mockRegistry{"NEAR01": {HexIdent: "NEAR01", Registration: "PR-MYA", TypeCode: "A320", Operator: "LATAM"}})
+	handler := newAPIHandler(tracker, testAPIToken)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "nearby", query: "lat=-23.5&lon=-46.6&radius=50", expectedStatus: http.StatusOK, expectedBody: `"callsign":"TAM3054"`},
+		{name: "none nearby", query: "lat=40&lon=-74&radius=10", expectedStatus: http.StatusOK, expectedBody: "[]"},
+		{name: "missing position", query: "radius=10", expectedStatus: http.StatusBadRequest},
+		{name: "invalid latitude", query: "lat=95&lon=0&radius=10", expectedStatus: http.StatusBadRequest},
+		{name: "missing radius", query: "lat=-23.5&lon=-46.6", expectedStatus: http.StatusBadRequest},
+		{name: "radius too large", query: "lat=-23.5&lon=-46.6&radius=5000", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/aircraft/nearby?"+tt.query, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedBody != "" && !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %s, got %s", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+
+	var aircraft []nearbyAircraft
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/aircraft/nearby?lat=-23.5&lon=-46.6&radius=50", nil))
+	if err := json.NewDecoder(rec.Body).Decode(&aircraft); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(aircraft) != 1 || aircraft[0].HexIdent != "NEAR01" || aircraft[0].DistanceKm <= 0 {
+		t.Fatalf("Unexpected nearby aircraft: %+v", aircraft)
+	}
+	if state := aircraft[0].State; state == nil || state.Registration != "PR-MYA" || state.AircraftType != "A320" || state.Operator != "LATAM" {
+		t.Errorf("Expected the nearby aircraft enriched from the registry, got %+v", state)
+	}
+
+	redisClient.getError = fmt.Errorf("redis down")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/aircraft/nearby?lat=-23.5&lon=-46.6&radius=50", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestAPI_ListAircraftAndFlights(t *testing.T) {
+	redisClient := newMockRedisClient()
+	redisClient.aircraftStates["ABC123"] = &types.AircraftState{HexIdent: "ABC123", Callsign: "TEST123"}
+	redisClient.flights["ABC123"] = &types.Flight{HexIdent: "ABC123", SessionID: "session-1"}
+	tracker := NewStateTracker(&mockDBClient{}, redisClient)
+	tracker.SetRegistry(mockRegistry{"ABC123": {HexIdent: "ABC123", Registration: "PR-ABC", Operator: "Test Air"}})
+	handler := newAPIHandler(tracker, testAPIToken)
+
+	tests := []struct {
+		name           string
+		path           string
+		getError       error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "aircraft", path: "/aircraft", expectedStatus: http.StatusOK, expectedBody: `"callsign":"TEST123"`},
+		{name: "aircraft enriched", path: "/aircraft", expectedStatus: http.StatusOK, expectedBody: `"registration":"PR-ABC"`},
+		{name: "flights", path: "/flights", expectedStatus: http.StatusOK, expectedBody: `"session_id":"session-1"`},
+		{name: "aircraft error", path: "/aircraft", getError: fmt.Errorf("redis down"), expectedStatus: http.StatusInternalServerError},
+		{name: "flights error", path: "/flights", getError: fmt.Errorf("redis down"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redisClient.getError = tt.getError
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedBody != "" && !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %s, got %s", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
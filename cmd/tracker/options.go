@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/saviobatista/sbs-logger/internal/config"
+	"github.com/saviobatista/sbs-logger/internal/nats"
+	"github.com/saviobatista/sbs-logger/internal/redis"
+)
+
+// options holds the options the tracker passes to the NATS and Redis
+// packages, built from its configuration
+type options struct {
+	nats  *nats.Options
+	redis *redis.Options
+}
+
+// buildOptions builds the options of the tracker from cfg, so a
+// configuration those packages reject is reported before it is used
+func buildOptions(cfg *config.Config) (*options, error) {
+	natsOpts, err := natsOptions(&cfg.NATS)
+	if err != nil {
+		return nil, err
+	}
+	redisOpts, err := redisOptions(&cfg.Redis)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis settings: %w", err)
+	}
+	return &options{nats: natsOpts, redis: redisOpts}, nil
+}
+
+// natsOptions returns the options of the tracker's NATS connection
+func natsOptions(cfg *config.NATSConfig) (*nats.Options, error) {
+	conn := cfg.Connection(config.Tracker)
+	opts := &nats.Options{
+		URL:             cfg.URL,
+		Name:            "sbs-" + string(config.Tracker),
+		CredentialsFile: conn.CredsFile,
+		NKeyFile:        conn.NKeyFile,
+		User:            conn.User,
+		Password:        conn.Password,
+		TLSCAFile:       conn.TLSCAFile,
+		TLSCertFile:     conn.TLSCertFile,
+		TLSKeyFile:      conn.TLSKeyFile,
+		ReconnectWait:   conn.ReconnectWait,
+		MaxReconnects:   conn.MaxReconnects,
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid nats settings of the tracker: %w", err)
+	}
+	return opts, nil
+}
+
+// redisOptions returns the Redis connection options. Set fields override
+// the ones parsed from the URL, which takes precedence over the address.
+func redisOptions(cfg *config.RedisConfig) (*redis.Options, error) {
+	opts := &redis.Options{Addr: cfg.Addr}
+	if cfg.URL != "" {
+		parsed, err := redis.ParseURL(cfg.URL)
+		if err != nil {
+			return nil, err
+		}
+		opts = parsed
+	}
+
+	values := map[*string]string{
+		&opts.Username:         cfg.Username,
+		&opts.Password:         cfg.Password,
+		&opts.TLSCAFile:        cfg.TLSCAFile,
+		&opts.SentinelMaster:   cfg.SentinelMaster,
+		&opts.SentinelPassword: cfg.SentinelPassword,
+		&opts.KeyPrefix:        cfg.KeyPrefix,
+	}
+	for target, value := range values {
+		if value != "" {
+			*target = value
+		}
+	}
+	if cfg.DB != nil {
+		opts.DB = *cfg.DB
+	}
+	if cfg.TLS != nil {
+		opts.TLS = *cfg.TLS
+	}
+	if len(cfg.SentinelAddrs) > 0 {
+		opts.SentinelAddrs = cfg.SentinelAddrs
+	}
+	opts.TTLs = redis.TTLs{Flight: cfg.FlightTTL, AircraftState: cfg.AircraftTTL, Validation: cfg.ValidationTTL}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/saviobatista/sbs-logger/internal/config"
+	"github.com/saviobatista/sbs-logger/internal/geofence"
+)
+
+// reloader applies configuration changes to a running tracker. Filter rules,
+// geofences and timeouts are updated in place, keeping the NATS connection
+// and the in-memory flight state. Other changes are logged as needing a
+// restart, once each time the set of such changes differs.
+type reloader struct {
+	tracker *StateTracker
+	fences  *geofence.Set  // Nil without geofences
+	initial *config.Config // Configuration the tracker was started with
+	pending []string       // Settings last logged as needing a restart
+}
+
+// apply updates the tracker from cfg, unless the options built from it are
+// invalid
+func (r *reloader) apply(cfg *config.Config) error {
+	if _, err := buildOptions(cfg); err != nil {
+		return err
+	}
+
+	if changed := r.restartRequired(cfg); !slices.Equal(changed, r.pending) {
+		if len(changed) > 0 {
+			log.Printf("Warning: Changes to %s take effect after a restart", strings.Join(changed, ", "))
+		} else {
+			log.Printf("Settings needing a restart are back to their running values")
+		}
+		r.pending = changed
+	}
+
+	if err := r.reloadFilters(); err != nil {
+		log.Printf("Warning: Failed to reload filter rules from %s: %v", r.initial.Tracker.FilterRulesPath, err)
+	}
+	if r.fences != nil {
+		if err := r.fences.Reload(); err != nil {
+			log.Printf("Warning: Failed to reload geofences: %v", err)
+		}
+	}
+
+	r.tracker.SetFlightTimeout(cfg.Tracker.FlightTimeout)
+	if r.tracker.geofences != nil {
+		r.tracker.geofences.SetDwell(cfg.Tracker.GeofenceDwell)
+	}
+	if r.tracker.dedup != nil && cfg.Tracker.DedupWindow > 0 {
+		r.tracker.dedup.SetWindow(cfg.Tracker.DedupWindow)
+	}
+	return nil
+}
+
+// reloadFilters reads the filter rules file again, if set. As at startup, a
+// missing file keeps the current rules until it is created.
+func (r *reloader) reloadFilters() error {
+	if r.initial.Tracker.FilterRulesPath == "" {
+		return nil
+	}
+	if err := r.tracker.filters.Reload(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// restartRequired returns the settings of cfg that differ from the
+// configuration the tracker was started with and can't be applied to it
+func (r *reloader) restartRequired(cfg *config.Config) []string {
+	initial, next := &r.initial.Tracker, &cfg.Tracker
+	settings := []struct {
+		name    string
+		changed bool
+	}{
+		{"nats.url", r.initial.NATS.URL != cfg.NATS.URL},
+		{"nats", !reflect.DeepEqual(r.initial.NATS.Connection(config.Tracker), cfg.NATS.Connection(config.Tracker))},
+		{"redis", !reflect.DeepEqual(r.initial.Redis, cfg.Redis)},
+		{"shutdown_timeout", r.initial.ShutdownTimeout != cfg.ShutdownTimeout},
+		{"health", r.initial.Health != cfg.Health},
+		{"tracker.db_conn_str", initial.DBConnStr != next.DBConnStr},
+		{"tracker.api_addr", initial.APIAddr != next.APIAddr},
+		{"tracker.api_token", initial.APIToken != next.APIToken},
+		{"tracker.dedup_window", (initial.DedupWindow > 0) != (next.DedupWindow > 0)},
+		{"tracker.aircraft_db_path", initial.AircraftDBPath != next.AircraftDBPath},
+		{"tracker.airlines_path", initial.AirlinesPath != next.AirlinesPath},
+		{"tracker.routes_path", initial.RoutesPath != next.RoutesPath},
+		{"tracker.airports_path", initial.AirportsPath != next.AirportsPath},
+		{"tracker.runways_path", initial.RunwaysPath != next.RunwaysPath},
+		{"tracker.filter_rules_path", initial.FilterRulesPath != next.FilterRulesPath},
+		{"tracker.watchlist_path", initial.WatchlistPath != next.WatchlistPath},
+		{"tracker.geofences_path", initial.GeofencesPath != next.GeofencesPath},
+		{"tracker.alerts", !reflect.DeepEqual(initial.Alerts, next.Alerts)},
+	}
+
+	var changed []string
+	for _, setting := range settings {
+		if setting.changed {
+			changed = append(changed, setting.name)
+		}
+	}
+	return changed
+}
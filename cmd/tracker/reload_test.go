@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/config"
+	"github.com/saviobatista/sbs-logger/internal/dedup"
+)
+
+func TestReloader_Apply(t *testing.T) {
+	dir := t.TempDir()
+	filtersPath := filepath.Join(dir, "filters.json")
+	if err := os.WriteFile(filtersPath, []byte(`{"default": "allow"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write filter rules: %v", err)
+	}
+	fencesPath := filepath.Join(dir, "geofences.geojson")
+	if err := os.WriteFile(fencesPath, []byte(testGeofences), 0o600); err != nil {
+		t.Fatalf("Failed to write geofences: %v", err)
+	}
+
+	initial := config.Default()
+	initial.Tracker.FilterRulesPath = filtersPath
+	initial.Tracker.GeofencesPath = fencesPath
+
+	tracker := NewStateTracker(&mockDBClient{}, newMockRedisClient())
+	tracker.SetDeduplicator(dedup.New(initial.Tracker.DedupWindow))
+	if err := setupFilters(context.Background(), tracker, &initial.Tracker); err != nil {
+		t.Fatalf("setupFilters() failed: %v", err)
+	}
+	fences, err := setupGeofences(context.Background(), tracker, &initial.Tracker, &mockGeofenceStore{}, &mockPublisher{})
+	if err != nil {
+		t.Fatalf("setupGeofences() failed: %v", err)
+	}
+	r := &reloader{tracker: tracker, fences: fences, initial: initial}
+
+	// Files are re-read and timeouts updated in place
+	if err := os.WriteFile(filtersPath, []byte(`{"default": "deny", "rules": [{"name": "local", "action": "allow", "sources": ["local"]}]}`), 0o600); err != nil {
+		t.Fatalf("Failed to write filter rules: %v", err)
+	}
+	if err := os.WriteFile(fencesPath, []byte(`{"type": "FeatureCollection", "features": []}`), 0o600); err != nil {
+		t.Fatalf("Failed to write geofences: %v", err)
+	}
+	next := config.Default()
+	next.Tracker = initial.Tracker
+	next.Tracker.FlightTimeout = 10 * time.Minute
+	if err := r.apply(next); err != nil {
+		t.Fatalf("apply() failed: %v", err)
+	}
+
+	if cfg := tracker.filters.Config(); len(cfg.Rules) != 1 {
+		t.Errorf("Expected reloaded filter rules, got %+v", cfg)
+	}
+	if fences.Len() != 0 {
+		t.Errorf("Expected reloaded geofences, got %d", fences.Len())
+	}
+	if got := time.Duration(tracker.flightTimeout.Load()); got != 10*time.Minute {
+		t.Errorf("Expected flight timeout 10m, got %v", got)
+	}
+
+	// Invalid files keep the previous rules
+	if err := os.WriteFile(filtersPath, []byte(`{"default": "block"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write filter rules: %v", err)
+	}
+	if err := r.apply(next); err != nil {
+		t.Fatalf("apply() failed: %v", err)
+	}
+	if cfg := tracker.filters.Config(); len(cfg.Rules) != 1 {
+		t.Errorf("Expected previous filter rules to be kept, got %+v", cfg)
+	}
+
+	// A deleted file keeps the rules without failing, as at startup
+	if err := os.Remove(filtersPath); err != nil {
+		t.Fatalf("Failed to remove filter rules: %v", err)
+	}
+	if err := r.reloadFilters(); err != nil {
+		t.Errorf("Expected a missing filter rules file to be ignored, got %v", err)
+	}
+	if cfg := tracker.filters.Config(); len(cfg.Rules) != 1 {
+		t.Errorf("Expected previous filter rules to be kept, got %+v", cfg)
+	}
+}
+
+func TestReloader_Pending(t *testing.T) {
+	initial := config.Default()
+	r := &reloader{tracker: NewStateTracker(&mockDBClient{}, newMockRedisClient()), initial: initial}
+
+	next := config.Default()
+	next.Tracker.APIAddr = ":8080"
+	if err := r.apply(next); err != nil {
+		t.Fatalf("apply() failed: %v", err)
+	}
+	if !reflect.DeepEqual(r.pending, []string{"tracker.api_addr"}) {
+		t.Errorf("Expected the API address to need a restart, got %v", r.pending)
+	}
+
+	// Changing the value back leaves nothing pending
+	if err := r.apply(config.Default()); err != nil {
+		t.Fatalf("apply() failed: %v", err)
+	}
+	if len(r.pending) != 0 {
+		t.Errorf("Expected nothing to need a restart, got %v", r.pending)
+	}
+
+	// Invalid Redis settings are rejected without being reported as pending
+	invalid := config.Default()
+	invalid.Redis.URL = "http://cache"
+	if err := r.apply(invalid); err == nil {
+		t.Error("Expected invalid Redis settings to be rejected")
+	}
+	if len(r.pending) != 0 {
+		t.Errorf("Expected nothing to need a restart, got %v", r.pending)
+	}
+}
+
+func TestReloader_RestartRequired(t *testing.T) {
+	initial := config.Default()
+	r := &reloader{tracker: NewStateTracker(&mockDBClient{}, newMockRedisClient()), initial: initial}
+
+	tests := []struct {
+		name     string
+		modify   func(*config.Config)
+		expected []string
+	}{
+		{name: "unchanged", modify: func(*config.Config) {}},
+		{name: "reloadable", modify: func(c *config.Config) {
+			c.Tracker.DedupWindow = time.Second
+			c.Tracker.FlightTimeout = time.Minute
+			c.Tracker.GeofenceDwell = 0
+		}},
+		{name: "connections", modify: func(c *config.Config) {
+			c.NATS.URL = "nats://other:4222"
+			c.Redis.Addr = "other:6379"
+			c.Tracker.DBConnStr = "host=other"
+		}, expected: []string{"nats.url", "redis", "tracker.db_conn_str"}},
+		{name: "nats settings", modify: func(c *config.Config) {
+			c.NATS.Services = map[config.Service]config.NATSConnection{config.Tracker: {User: "tracker", Password: "secret"}}
+		}, expected: []string{"nats"}},
+		{name: "nats settings of another service", modify: func(c *config.Config) {
+			c.NATS.Services = map[config.Service]config.NATSConnection{config.Ingestor: {User: "ingestor"}}
+		}},
+		{name: "health settings", modify: func(c *config.Config) { c.Health.Addr = ":8081" }, expected: []string{"health"}},
+		{name: "dedup disabled", modify: func(c *config.Config) { c.Tracker.DedupWindow = 0 }, expected: []string{"tracker.dedup_window"}},
+		{name: "paths and alerts", modify: func(c *config.Config) {
+			c.Tracker.GeofencesPath = "geofences.geojson"
+			c.Tracker.Alerts.WebhookURL = "https://hooks.example.com"
+		}, expected: []string{"tracker.geofences_path", "tracker.alerts"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			tt.modify(cfg)
+			if got := r.restartRequired(cfg); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("restartRequired() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
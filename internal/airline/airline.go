@@ -0,0 +1,264 @@
+package airline
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/csvfile"
+	"github.com/saviobatista/sbs-logger/internal/filewatch"
+)
+
+// Airline holds the designators of an airline
+type Airline struct {
+	ICAO string `json:"icao"`
+	IATA string `json:"iata"`
+	Name string `json:"name"`
+}
+
+// Route holds the scheduled origin and destination of a callsign
+type Route struct {
+	Callsign    string `json:"callsign"`
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+}
+
+// Positional columns of the header-less OpenFlights airlines.dat
+// (id,name,alias,iata,icao,callsign,country,active)
+const (
+	ofColName = 1
+	ofColIATA = 3
+	ofColICAO = 4
+)
+
+// Decoder decodes callsigns into airlines and routes
+type Decoder struct {
+	airlinesPath string
+	routesPath   string
+	airlines     map[string]Airline
+	routes       map[string]Route
+	mu           sync.RWMutex
+}
+
+// New creates a Decoder using only the built-in airline table
+func New() *Decoder {
+	return &Decoder{
+		airlines: builtinAirlines,
+		routes:   make(map[string]Route),
+	}
+}
+
+// Open creates a Decoder that adds the airlines and routes loaded from local
+// files to the built-in table. Either path may be empty.
+func Open(airlinesPath, routesPath string) (*Decoder, error) {
+	d := New()
+	d.airlinesPath = airlinesPath
+	d.routesPath = routesPath
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reload reads the airline and route files again and replaces the loaded entries
+func (d *Decoder) Reload() error {
+	airlines := builtinAirlines
+	if d.airlinesPath != "" {
+		records, err := csvfile.ReadAll(d.airlinesPath)
+		if err != nil {
+			return fmt.Errorf("failed to load airlines: %w", err)
+		}
+		airlines = parseAirlines(records)
+	}
+
+	routes := make(map[string]Route)
+	if d.routesPath != "" {
+		records, err := csvfile.ReadAll(d.routesPath)
+		if err != nil {
+			return fmt.Errorf("failed to load routes: %w", err)
+		}
+		routes = parseRoutes(records)
+	}
+
+	d.mu.Lock()
+	d.airlines = airlines
+	d.routes = routes
+	d.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the files whenever one of them changes until the context is cancelled
+func (d *Decoder) Watch(ctx context.Context, interval time.Duration) {
+	var wg sync.WaitGroup
+	for _, path := range []string{d.airlinesPath, d.routesPath} {
+		if path == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			filewatch.Watch(ctx, path, interval, d.Reload)
+		}()
+	}
+	wg.Wait()
+}
+
+// Airline returns the airline operating a callsign
+func (d *Decoder) Airline(callsign string) (Airline, bool) {
+	prefix, ok := Prefix(callsign)
+	if !ok {
+		return Airline{}, false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	airline, exists := d.airlines[prefix]
+	return airline, exists
+}
+
+// Route returns the scheduled route of a callsign
+func (d *Decoder) Route(callsign string) (Route, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	route, exists := d.routes[normalize(callsign)]
+	return route, exists
+}
+
+// Len returns the number of loaded airlines and routes
+func (d *Decoder) Len() (airlines, routes int) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.airlines), len(d.routes)
+}
+
+// Prefix returns the ICAO airline designator of a callsign. Airline callsigns
+// are three letters followed by a flight number starting with a digit, so
+// registration callsigns like N123AB or PRABC are not decoded.
+func Prefix(callsign string) (string, bool) {
+	callsign = normalize(callsign)
+	if len(callsign) < 4 {
+		return "", false
+	}
+	for i := 0; i < 3; i++ {
+		if callsign[i] < 'A' || callsign[i] > 'Z' {
+			return "", false
+		}
+	}
+	if callsign[3] < '0' || callsign[3] > '9' {
+		return "", false
+	}
+	return callsign[:3], true
+}
+
+// normalize trims and upper-cases a callsign
+func normalize(callsign string) string {
+	return strings.ToUpper(strings.TrimSpace(callsign))
+}
+
+// parseAirlines builds the airline index from CSV records on top of the
+// built-in table. Header-less files are read with the OpenFlights airlines.dat
+// layout, files with a header row are matched by column name.
+func parseAirlines(records [][]string) map[string]Airline {
+	airlines := make(map[string]Airline, len(builtinAirlines)+len(records))
+	for icao, airline := range builtinAirlines {
+		airlines[icao] = airline
+	}
+	if len(records) == 0 {
+		return airlines
+	}
+
+	icaoCol, iataCol, nameCol := ofColICAO, ofColIATA, ofColName
+	if _, err := strconv.Atoi(csvfile.Field(records[0], 0)); err != nil {
+		columns := csvfile.NewColumns(records[0])
+		icaoCol = columns.Index("icao", "icao_code", "icaocode", "airlinecode")
+		iataCol = columns.Index("iata", "iata_code", "iatacode")
+		nameCol = columns.Index("name", "airline")
+		records = records[1:]
+	}
+	if icaoCol < 0 {
+		return airlines
+	}
+
+	for _, record := range records {
+		icao := normalize(csvfile.Field(record, icaoCol))
+		if !isDesignator(icao) {
+			continue
+		}
+		iata := normalize(csvfile.Field(record, iataCol))
+		if iata == `\N` || iata == "-" {
+			iata = ""
+		}
+		airlines[icao] = Airline{
+			ICAO: icao,
+			IATA: iata,
+			Name: csvfile.Field(record, nameCol),
+		}
+	}
+	return airlines
+}
+
+// parseRoutes builds the route index from CSV records. Files with a header row
+// are matched by column name and may give the airports either as separate
+// origin/destination columns or as a dash separated list (e.g. the VRS
+// standing data AirportCodes column). Header-less files are read as
+// callsign,origin,destination.
+func parseRoutes(records [][]string) map[string]Route {
+	routes := make(map[string]Route, len(records))
+	if len(records) == 0 {
+		return routes
+	}
+
+	callsignCol, originCol, destCol, airportsCol := 0, 1, 2, -1
+	columns := csvfile.NewColumns(records[0])
+	if columns.Index("callsign", "flight") >= 0 {
+		callsignCol = columns.Index("callsign", "flight")
+		originCol = columns.Index("origin", "from", "departure")
+		destCol = columns.Index("destination", "to", "arrival")
+		airportsCol = columns.Index("airportcodes", "airports", "route")
+		records = records[1:]
+	}
+
+	for _, record := range records {
+		callsign := normalize(csvfile.Field(record, callsignCol))
+		if callsign == "" {
+			continue
+		}
+
+		origin := csvfile.Field(record, originCol)
+		destination := csvfile.Field(record, destCol)
+		if airports := csvfile.Field(record, airportsCol); airports != "" {
+			codes := strings.Split(airports, "-")
+			origin = codes[0]
+			destination = codes[len(codes)-1]
+		}
+		if origin == "" && destination == "" {
+			continue
+		}
+
+		routes[callsign] = Route{
+			Callsign:    callsign,
+			Origin:      strings.ToUpper(origin),
+			Destination: strings.ToUpper(destination),
+		}
+	}
+	return routes
+}
+
+// isDesignator reports whether s is a three letter ICAO airline designator
+func isDesignator(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package airline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrefix(t *testing.T) {
+	tests := []struct {
+		callsign string
+		expected string
+		ok       bool
+	}{
+		{callsign: "TAM3456 ", expected: "TAM", ok: true},
+		{callsign: "baw12ab", expected: "BAW", ok: true},
+		{callsign: "UAL1", expected: "UAL", ok: true},
+		{callsign: "N123AB", ok: false},
+		{callsign: "PRABC", ok: false},
+		{callsign: "GOL", ok: false},
+		{callsign: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.callsign, func(t *testing.T) {
+			prefix, ok := Prefix(tt.callsign)
+			if ok != tt.ok || prefix != tt.expected {
+				t.Errorf("Prefix(%q) = %q, %v, expected %q, %v", tt.callsign, prefix, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDecoder_Builtin(t *testing.T) {
+	d := New()
+
+	airline, ok := d.Airline("TAM3456  ")
+	if !ok {
+		t.Fatal("Expected TAM to be decoded from the built-in table")
+	}
+	if airline.ICAO != "TAM" || airline.IATA != "JJ" {
+		t.Errorf("Unexpected airline: %+v", airline)
+	}
+
+	if _, ok := d.Airline("ZZZ123"); ok {
+		t.Error("Expected unknown designator not to be decoded")
+	}
+	if _, ok := d.Route("TAM3456"); ok {
+		t.Error("Expected no routes without a routes file")
+	}
+}
+
+func TestOpen_OpenFlightsAirlines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airlines.dat")
+	data := "1,\"Example Air\",\\N,\"XA\",\"XAA\",\"EXAMPLE\",\"Brazil\",\"Y\"\n" +
+		"2,\"No Code Air\",\\N,\\N,\"XAB\",\"NOCODE\",\"Brazil\",\"Y\"\n" +
+		"3,\"Invalid\",\\N,\"\",\"\",\"\",\"\",\"N\"\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	d, err := Open(path, "")
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	airline, ok := d.Airline("XAA100")
+	if !ok || airline.IATA != "XA" || airline.Name != "Example Air" {
+		t.Errorf("Unexpected airline: %+v, %v", airline, ok)
+	}
+	if airline, _ := d.Airline("XAB100"); airline.IATA != "" {
+		t.Errorf("Expected empty IATA for \\N, got %q", airline.IATA)
+	}
+	if _, ok := d.Airline("TAM3456"); !ok {
+		t.Error("Expected built-in airlines to remain available")
+	}
+}
+
+func TestOpen_HeaderAirlines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airlines.csv")
+	if err := os.WriteFile(path, []byte("ICAO;IATA;Name\nTAM;LA;Overridden\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	d, err := Open(path, "")
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if airline, _ := d.Airline("TAM3456"); airline.IATA != "LA" {
+		t.Errorf("Expected file to override the built-in entry, got %+v", airline)
+	}
+}
+
+func TestOpen_Routes(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected Route
+	}{
+		{
+			name:     "header-less",
+			data:     "TAM3456,sbgr,SBRJ\n",
+			expected: Route{Callsign: "TAM3456", Origin: "SBGR", Destination: "SBRJ"},
+		},
+		{
+			name:     "origin and destination columns",
+			data:     "Callsign,Origin,Destination\nTAM3456,SBGR,SBRJ\n",
+			expected: Route{Callsign: "TAM3456", Origin: "SBGR", Destination: "SBRJ"},
+		},
+		{
+			name:     "airport codes column with stops",
+			data:     "Callsign,Code,Number,AirlineCode,AirportCodes\nTAM3456,JJ3456,3456,TAM,SBGR-SBBR-SBRJ\n",
+			expected: Route{Callsign: "TAM3456", Origin: "SBGR", Destination: "SBRJ"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "routes.csv")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			d, err := Open("", path)
+			if err != nil {
+				t.Fatalf("Open() unexpected error: %v", err)
+			}
+
+			route, ok := d.Route("tam3456 ")
+			if !ok {
+				t.Fatal("Expected route to be found")
+			}
+			if route != tt.expected {
+				t.Errorf("Route() = %+v, expected %+v", route, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOpen_MissingFile(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.csv"), ""); err == nil {
+		t.Error("Expected error for missing airlines file")
+	}
+	if _, err := Open("", filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("Expected error for missing routes file")
+	}
+}
+
+func TestReload_KeepsDataOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.csv")
+	if err := os.WriteFile(path, []byte("TAM3456,SBGR,SBRJ\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	d, err := Open("", path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err := d.Reload(); err == nil {
+		t.Error("Expected reload error for removed file")
+	}
+	if _, ok := d.Route("TAM3456"); !ok {
+		t.Error("Expected previous routes to be kept after a failed reload")
+	}
+}
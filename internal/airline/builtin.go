@@ -0,0 +1,87 @@
+package airline
+
+// builtinAirlines holds the ICAO and IATA designators of common airlines, keyed
+// by ICAO designator. Entries loaded from an airlines file take precedence.
+var builtinAirlines = map[string]Airline{
+	"AAL": {ICAO: "AAL", IATA: "AA", Name: "American Airlines"},
+	"ACA": {ICAO: "ACA", IATA: "AC", Name: "Air Canada"},
+	"AEE": {ICAO: "AEE", IATA: "A3", Name: "Aegean Airlines"},
+	"AFR": {ICAO: "AFR", IATA: "AF", Name: "Air France"},
+	"AIC": {ICAO: "AIC", IATA: "AI", Name: "Air India"},
+	"AMX": {ICAO: "AMX", IATA: "AM", Name: "Aeromexico"},
+	"ANA": {ICAO: "ANA", IATA: "NH", Name: "All Nippon Airways"},
+	"ANZ": {ICAO: "ANZ", IATA: "NZ", Name: "Air New Zealand"},
+	"ARG": {ICAO: "ARG", IATA: "AR", Name: "Aerolineas Argentinas"},
+	"ASA": {ICAO: "ASA", IATA: "AS", Name: "Alaska Airlines"},
+	"AUA": {ICAO: "AUA", IATA: "OS", Name: "Austrian Airlines"},
+	"AVA": {ICAO: "AVA", IATA: "AV", Name: "Avianca"},
+	"AZU": {ICAO: "AZU", IATA: "AD", Name: "Azul Linhas Aereas"},
+	"BAW": {ICAO: "BAW", IATA: "BA", Name: "British Airways"},
+	"BEL": {ICAO: "BEL", IATA: "SN", Name: "Brussels Airlines"},
+	"CCA": {ICAO: "CCA", IATA: "CA", Name: "Air China"},
+	"CES": {ICAO: "CES", IATA: "MU", Name: "China Eastern Airlines"},
+	"CFG": {ICAO: "CFG", IATA: "DE", Name: "Condor"},
+	"CLX": {ICAO: "CLX", IATA: "CV", Name: "Cargolux"},
+	"CMP": {ICAO: "CMP", IATA: "CM", Name: "Copa Airlines"},
+	"CPA": {ICAO: "CPA", IATA: "CX", Name: "Cathay Pacific"},
+	"CSN": {ICAO: "CSN", IATA: "CZ", Name: "China Southern Airlines"},
+	"DAL": {ICAO: "DAL", IATA: "DL", Name: "Delta Air Lines"},
+	"DLH": {ICAO: "DLH", IATA: "LH", Name: "Lufthansa"},
+	"EIN": {ICAO: "EIN", IATA: "EI", Name: "Aer Lingus"},
+	"EJU": {ICAO: "EJU", IATA: "U2", Name: "easyJet Europe"},
+	"ENY": {ICAO: "ENY", IATA: "MQ", Name: "Envoy Air"},
+	"ETD": {ICAO: "ETD", IATA: "EY", Name: "Etihad Airways"},
+	"ETH": {ICAO: "ETH", IATA: "ET", Name: "Ethiopian Airlines"},
+	"EVA": {ICAO: "EVA", IATA: "BR", Name: "EVA Air"},
+	"EWG": {ICAO: "EWG", IATA: "EW", Name: "Eurowings"},
+	"EZY": {ICAO: "EZY", IATA: "U2", Name: "easyJet"},
+	"FDX": {ICAO: "FDX", IATA: "FX", Name: "FedEx Express"},
+	"FFT": {ICAO: "FFT", IATA: "F9", Name: "Frontier Airlines"},
+	"FIN": {ICAO: "FIN", IATA: "AY", Name: "Finnair"},
+	"GIA": {ICAO: "GIA", IATA: "GA", Name: "Garuda Indonesia"},
+	"GLO": {ICAO: "GLO", IATA: "G3", Name: "Gol Linhas Aereas"},
+	"GTI": {ICAO: "GTI", IATA: "5Y", Name: "Atlas Air"},
+	"IBE": {ICAO: "IBE", IATA: "IB", Name: "Iberia"},
+	"ICE": {ICAO: "ICE", IATA: "FI", Name: "Icelandair"},
+	"JAL": {ICAO: "JAL", IATA: "JL", Name: "Japan Airlines"},
+	"JBU": {ICAO: "JBU", IATA: "B6", Name: "JetBlue Airways"},
+	"KAL": {ICAO: "KAL", IATA: "KE", Name: "Korean Air"},
+	"KLM": {ICAO: "KLM", IATA: "KL", Name: "KLM Royal Dutch Airlines"},
+	"KQA": {ICAO: "KQA", IATA: "KQ", Name: "Kenya Airways"},
+	"LAN": {ICAO: "LAN", IATA: "LA", Name: "LATAM Airlines"},
+	"LOT": {ICAO: "LOT", IATA: "LO", Name: "LOT Polish Airlines"},
+	"LPE": {ICAO: "LPE", IATA: "LP", Name: "LATAM Airlines Peru"},
+	"MAS": {ICAO: "MAS", IATA: "MH", Name: "Malaysia Airlines"},
+	"MSR": {ICAO: "MSR", IATA: "MS", Name: "EgyptAir"},
+	"NAX": {ICAO: "NAX", IATA: "DY", Name: "Norwegian Air Shuttle"},
+	"NKS": {ICAO: "NKS", IATA: "NK", Name: "Spirit Airlines"},
+	"PAL": {ICAO: "PAL", IATA: "PR", Name: "Philippine Airlines"},
+	"PTB": {ICAO: "PTB", IATA: "2Z", Name: "Voepass Linhas Aereas"},
+	"QFA": {ICAO: "QFA", IATA: "QF", Name: "Qantas"},
+	"QTR": {ICAO: "QTR", IATA: "QR", Name: "Qatar Airways"},
+	"RAM": {ICAO: "RAM", IATA: "AT", Name: "Royal Air Maroc"},
+	"RPA": {ICAO: "RPA", IATA: "YX", Name: "Republic Airways"},
+	"RYR": {ICAO: "RYR", IATA: "FR", Name: "Ryanair"},
+	"SAA": {ICAO: "SAA", IATA: "SA", Name: "South African Airways"},
+	"SAS": {ICAO: "SAS", IATA: "SK", Name: "Scandinavian Airlines"},
+	"SIA": {ICAO: "SIA", IATA: "SQ", Name: "Singapore Airlines"},
+	"SKU": {ICAO: "SKU", IATA: "H2", Name: "Sky Airline"},
+	"SKW": {ICAO: "SKW", IATA: "OO", Name: "SkyWest Airlines"},
+	"SVA": {ICAO: "SVA", IATA: "SV", Name: "Saudia"},
+	"SWA": {ICAO: "SWA", IATA: "WN", Name: "Southwest Airlines"},
+	"SWR": {ICAO: "SWR", IATA: "LX", Name: "Swiss International Air Lines"},
+	"TAM": {ICAO: "TAM", IATA: "JJ", Name: "LATAM Airlines Brasil"},
+	"TAP": {ICAO: "TAP", IATA: "TP", Name: "TAP Air Portugal"},
+	"THA": {ICAO: "THA", IATA: "TG", Name: "Thai Airways"},
+	"THY": {ICAO: "THY", IATA: "TK", Name: "Turkish Airlines"},
+	"TRA": {ICAO: "TRA", IATA: "HV", Name: "Transavia"},
+	"TVF": {ICAO: "TVF", IATA: "TO", Name: "Transavia France"},
+	"UAE": {ICAO: "UAE", IATA: "EK", Name: "Emirates"},
+	"UAL": {ICAO: "UAL", IATA: "UA", Name: "United Airlines"},
+	"UPS": {ICAO: "UPS", IATA: "5X", Name: "UPS Airlines"},
+	"VIR": {ICAO: "VIR", IATA: "VS", Name: "Virgin Atlantic"},
+	"VLG": {ICAO: "VLG", IATA: "VY", Name: "Vueling"},
+	"VOZ": {ICAO: "VOZ", IATA: "VA", Name: "Virgin Australia"},
+	"WJA": {ICAO: "WJA", IATA: "WS", Name: "WestJet"},
+	"WZZ": {ICAO: "WZZ", IATA: "W6", Name: "Wizz Air"},
+}
@@ -0,0 +1,351 @@
+package airport
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/csvfile"
+	"github.com/saviobatista/sbs-logger/internal/filewatch"
+	"github.com/saviobatista/sbs-logger/internal/geo"
+)
+
+// Airport holds the location of an airport and its runways
+type Airport struct {
+	Ident     string    `json:"ident"` // ICAO code when assigned, otherwise the dataset identifier
+	IATA      string    `json:"iata"`
+	Name      string    `json:"name"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Elevation int       `json:"elevation"` // Feet above mean sea level
+	Runways   []*Runway `json:"runways"`
+}
+
+// Runway is one direction of a runway, from its threshold to the opposite end
+type Runway struct {
+	Ident        string  `json:"ident"`
+	Heading      float64 `json:"heading"` // True heading in degrees
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	EndLatitude  float64 `json:"end_latitude"`
+	EndLongitude float64 `json:"end_longitude"`
+}
+
+// hasPosition reports whether the runway thresholds are known
+func (r *Runway) hasPosition() bool {
+	return (r.Latitude != 0 || r.Longitude != 0) && (r.EndLatitude != 0 || r.EndLongitude != 0)
+}
+
+// cell is a one degree square of the spatial index
+type cell struct {
+	lat int
+	lon int
+}
+
+// Database is an in-memory airport and runway dataset loaded from local
+// OurAirports-style CSV files
+type Database struct {
+	airportsPath string
+	runwaysPath  string
+	airports     map[string]*Airport
+	grid         map[cell][]*Airport
+	mu           sync.RWMutex
+}
+
+// Open loads the airports file and, if runwaysPath is set, the runways file
+func Open(airportsPath, runwaysPath string) (*Database, error) {
+	d := &Database{
+		airportsPath: airportsPath,
+		runwaysPath:  runwaysPath,
+	}
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reload reads the files again and replaces the loaded airports
+func (d *Database) Reload() error {
+	records, err := csvfile.ReadAll(d.airportsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load airports: %w", err)
+	}
+	byIdent := parseAirports(records)
+
+	if d.runwaysPath != "" {
+		records, err := csvfile.ReadAll(d.runwaysPath)
+		if err != nil {
+			return fmt.Errorf("failed to load runways: %w", err)
+		}
+		parseRunways(records, byIdent)
+	}
+
+	airports := make(map[string]*Airport, len(byIdent))
+	grid := make(map[cell][]*Airport)
+	for _, a := range byIdent {
+		airports[a.Ident] = a
+		c := cellOf(a.Latitude, a.Longitude)
+		grid[c] = append(grid[c], a)
+	}
+
+	d.mu.Lock()
+	d.airports = airports
+	d.grid = grid
+	d.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the files whenever one of them changes until the context is cancelled
+func (d *Database) Watch(ctx context.Context, interval time.Duration) {
+	var wg sync.WaitGroup
+	for _, path := range []string{d.airportsPath, d.runwaysPath} {
+		if path == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			filewatch.Watch(ctx, path, interval, d.Reload)
+		}()
+	}
+	wg.Wait()
+}
+
+// Lookup returns an airport by identifier
+func (d *Database) Lookup(ident string) (*Airport, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	a, exists := d.airports[strings.ToUpper(ident)]
+	return a, exists
+}
+
+// Len returns the number of loaded airports
+func (d *Database) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.airports)
+}
+
+// Nearest returns the airport closest to a position within radiusKm, and its distance
+func (d *Database) Nearest(lat, lon, radiusKm float64) (*Airport, float64, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	latSpan := int(math.Ceil(radiusKm / 111))
+	lonSpan := 180
+	if cosLat := math.Cos(lat * math.Pi / 180); cosLat > 0.01 {
+		lonSpan = min(180, int(math.Ceil(radiusKm/(111*cosLat))))
+	}
+
+	var nearest *Airport
+	best := radiusKm
+	center := cellOf(lat, lon)
+	for dLat := -latSpan; dLat <= latSpan; dLat++ {
+		for dLon := -lonSpan; dLon <= lonSpan; dLon++ {
+			c := cell{lat: center.lat + dLat, lon: wrapLon(center.lon + dLon)}
+			for _, a := range d.grid[c] {
+				if dist := geo.Distance(lat, lon, a.Latitude, a.Longitude); dist <= best {
+					nearest, best = a, dist
+				}
+			}
+		}
+	}
+	if nearest == nil {
+		return nil, 0, false
+	}
+	return nearest, best, true
+}
+
+// Runway returns the runway direction of an airport best matching a position
+// and track: the heading must be within toleranceDeg of the track, and among
+// parallel runways the one closest to the position wins
+func (a *Airport) Runway(lat, lon, track, toleranceDeg float64) (*Runway, bool) {
+	var best *Runway
+	bestDist := math.MaxFloat64
+	bestDiff := math.MaxFloat64
+	for _, r := range a.Runways {
+		diff := geo.HeadingDiff(r.Heading, track)
+		if diff > toleranceDeg {
+			continue
+		}
+		dist := math.MaxFloat64
+		if r.hasPosition() && (lat != 0 || lon != 0) {
+			dist = geo.DistanceToSegment(lat, lon, r.Latitude, r.Longitude, r.EndLatitude, r.EndLongitude)
+		}
+		if dist < bestDist || (dist == bestDist && diff < bestDiff) {
+			best, bestDist, bestDiff = r, dist, diff
+		}
+	}
+	return best, best != nil
+}
+
+// cellOf returns the spatial index cell of a position
+func cellOf(lat, lon float64) cell {
+	return cell{lat: int(math.Floor(lat)), lon: int(math.Floor(lon))}
+}
+
+// wrapLon keeps a cell longitude within -180..179
+func wrapLon(lon int) int {
+	return ((lon+180)%360+360)%360 - 180
+}
+
+// parseAirports builds the airport index from an OurAirports airports.csv,
+// keyed by the dataset identifier used by runways.csv
+func parseAirports(records [][]string) map[string]*Airport {
+	airports := make(map[string]*Airport, len(records))
+	if len(records) == 0 {
+		return airports
+	}
+
+	columns := csvfile.NewColumns(records[0])
+	identCol := columns.Index("ident")
+	typeCol := columns.Index("type")
+	nameCol := columns.Index("name")
+	latCol := columns.Index("latitude_deg", "latitude", "lat")
+	lonCol := columns.Index("longitude_deg", "longitude", "lon")
+	elevCol := columns.Index("elevation_ft", "elevation")
+	icaoCol := columns.Index("icao_code", "gps_code", "icao")
+	iataCol := columns.Index("iata_code", "iata")
+	if identCol < 0 || latCol < 0 || lonCol < 0 {
+		return airports
+	}
+
+	for _, record := range records[1:] {
+		switch csvfile.Field(record, typeCol) {
+		case "closed", "heliport", "balloonport":
+			continue
+		}
+
+		lat, latErr := strconv.ParseFloat(csvfile.Field(record, latCol), 64)
+		lon, lonErr := strconv.ParseFloat(csvfile.Field(record, lonCol), 64)
+		if latErr != nil || lonErr != nil {
+			continue
+		}
+
+		ident := strings.ToUpper(csvfile.Field(record, identCol))
+		code := strings.ToUpper(csvfile.Field(record, icaoCol))
+		if code == "" {
+			code = ident
+		}
+		elevation, _ := strconv.Atoi(csvfile.Field(record, elevCol))
+
+		airports[ident] = &Airport{
+			Ident:     code,
+			IATA:      strings.ToUpper(csvfile.Field(record, iataCol)),
+			Name:      csvfile.Field(record, nameCol),
+			Latitude:  lat,
+			Longitude: lon,
+			Elevation: elevation,
+		}
+	}
+	return airports
+}
+
+// parseRunways attaches the runways of an OurAirports runways.csv to their
+// airports, one entry per direction
+func parseRunways(records [][]string, airports map[string]*Airport) {
+	if len(records) == 0 {
+		return
+	}
+
+	columns := csvfile.NewColumns(records[0])
+	airportCol := columns.Index("airport_ident")
+	closedCol := columns.Index("closed")
+	le := runwayColumns(columns, "le_")
+	he := runwayColumns(columns, "he_")
+	if airportCol < 0 {
+		return
+	}
+
+	for _, record := range records[1:] {
+		a, exists := airports[strings.ToUpper(csvfile.Field(record, airportCol))]
+		if !exists || csvfile.Field(record, closedCol) == "1" {
+			continue
+		}
+
+		low := le.parse(record)
+		high := he.parse(record)
+		for _, r := range []*Runway{low.towards(high), high.towards(low)} {
+			if r != nil {
+				a.Runways = append(a.Runways, r)
+			}
+		}
+	}
+}
+
+// runwayEndColumns holds the column indexes of one runway end
+type runwayEndColumns struct {
+	ident   int
+	lat     int
+	lon     int
+	heading int
+}
+
+// runwayEnd is a parsed runway end
+type runwayEnd struct {
+	ident      string
+	lat        float64
+	lon        float64
+	heading    float64
+	hasPos     bool
+	hasHeading bool
+}
+
+// runwayColumns returns the column indexes of the runway end with prefix
+func runwayColumns(columns csvfile.Columns, prefix string) runwayEndColumns {
+	return runwayEndColumns{
+		ident:   columns.Index(prefix + "ident"),
+		lat:     columns.Index(prefix + "latitude_deg"),
+		lon:     columns.Index(prefix + "longitude_deg"),
+		heading: columns.Index(prefix + "heading_degt"),
+	}
+}
+
+// parse reads a runway end from a record
+func (c runwayEndColumns) parse(record []string) runwayEnd {
+	end := runwayEnd{ident: strings.ToUpper(csvfile.Field(record, c.ident))}
+
+	lat, latErr := strconv.ParseFloat(csvfile.Field(record, c.lat), 64)
+	lon, lonErr := strconv.ParseFloat(csvfile.Field(record, c.lon), 64)
+	if latErr == nil && lonErr == nil {
+		end.lat, end.lon, end.hasPos = lat, lon, true
+	}
+	if heading, err := strconv.ParseFloat(csvfile.Field(record, c.heading), 64); err == nil {
+		end.heading, end.hasHeading = heading, true
+	}
+	return end
+}
+
+// towards builds the runway direction starting at this end. The heading falls
+// back to the bearing between thresholds, then to the runway designator.
+func (e runwayEnd) towards(opposite runwayEnd) *Runway {
+	if e.ident == "" {
+		return nil
+	}
+
+	r := &Runway{Ident: e.ident}
+	if e.hasPos && opposite.hasPos {
+		r.Latitude, r.Longitude = e.lat, e.lon
+		r.EndLatitude, r.EndLongitude = opposite.lat, opposite.lon
+	}
+
+	switch {
+	case e.hasHeading:
+		r.Heading = e.heading
+	case r.hasPosition():
+		r.Heading = geo.Bearing(r.Latitude, r.Longitude, r.EndLatitude, r.EndLongitude)
+	default:
+		number, err := strconv.Atoi(strings.TrimRight(e.ident, "LCRTW"))
+		if err != nil || number < 1 || number > 36 {
+			return nil
+		}
+		r.Heading = float64(number * 10)
+	}
+	return r
+}
@@ -0,0 +1,181 @@
+package airport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testAirports = `"id","ident","type","name","latitude_deg","longitude_deg","elevation_ft","continent","iso_country","iso_region","municipality","scheduled_service","gps_code","iata_code","local_code"
+5910,"SBGR","large_airport","Guarulhos International Airport",-23.431944,-46.467778,2461,"SA","BR","BR-SP","Sao Paulo","yes","SBGR","GRU",""
+5906,"SBSP","medium_airport","Congonhas Airport",-23.626110,-46.656387,2631,"SA","BR","BR-SP","Sao Paulo","yes","SBSP","CGH",""
+99999,"BR-0001","heliport","Some Heliport",-23.44,-46.47,2400,"SA","BR","BR-SP","Sao Paulo","no","","",""
+88888,"XX-CLOSED","closed","Closed Field",-23.43,-46.46,2400,"SA","BR","BR-SP","Sao Paulo","no","","",""
+`
+
+const testRunways = `"id","airport_ref","airport_ident","length_ft","width_ft","surface","lighted","closed","le_ident","le_latitude_deg","le_longitude_deg","le_elevation_ft","le_heading_degT","le_displaced_threshold_ft","he_ident","he_latitude_deg","he_longitude_deg","he_elevation_ft","he_heading_degT","he_displaced_threshold_ft"
+1,5910,"SBGR",12140,148,"ASP",1,0,"10L",-23.4219,-46.4876,2461,,,"28R",-23.4336,-46.4511,2450,,
+2,5910,"SBGR",9843,148,"ASP",1,0,"10R",-23.4258,-46.4855,2461,,,"28L",-23.4355,-46.4556,2450,,
+3,5906,"SBSP",6365,148,"ASP",1,0,"17R",,,,,,"35L",,,,,
+4,5906,"SBSP",4708,148,"ASP",1,1,"17L",,,,,,"35R",,,,,
+`
+
+func openTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	dir := t.TempDir()
+	airportsPath := filepath.Join(dir, "airports.csv")
+	runwaysPath := filepath.Join(dir, "runways.csv")
+	if err := os.WriteFile(airportsPath, []byte(testAirports), 0o600); err != nil {
+		t.Fatalf("Failed to write airports: %v", err)
+	}
+	if err := os.WriteFile(runwaysPath, []byte(testRunways), 0o600); err != nil {
+		t.Fatalf("Failed to write runways: %v", err)
+	}
+
+	d, err := Open(airportsPath, runwaysPath)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	return d
+}
+
+func TestOpen(t *testing.T) {
+	d := openTestDatabase(t)
+
+	if d.Len() != 2 {
+		t.Errorf("Expected 2 airports (heliports and closed skipped), got %d", d.Len())
+	}
+
+	gru, ok := d.Lookup("sbgr")
+	if !ok {
+		t.Fatal("Expected SBGR to be found")
+	}
+	if gru.IATA != "GRU" || gru.Elevation != 2461 {
+		t.Errorf("Unexpected airport: %+v", gru)
+	}
+	if len(gru.Runways) != 4 {
+		t.Errorf("Expected 4 runway directions, got %d", len(gru.Runways))
+	}
+
+	cgh, _ := d.Lookup("SBSP")
+	if len(cgh.Runways) != 2 {
+		t.Fatalf("Expected closed runway to be skipped, got %d directions", len(cgh.Runways))
+	}
+	if cgh.Runways[0].Heading != 170 || cgh.Runways[1].Heading != 350 {
+		t.Errorf("Expected headings from designators, got %v and %v", cgh.Runways[0].Heading, cgh.Runways[1].Heading)
+	}
+}
+
+func TestOpen_MissingFile(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.csv"), ""); err == nil {
+		t.Error("Expected error for missing airports file")
+	}
+}
+
+func TestDatabase_Nearest(t *testing.T) {
+	d := openTestDatabase(t)
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		expected string
+	}{
+		{name: "on the airport", lat: -23.43, lon: -46.47, expected: "SBGR"},
+		{name: "closer to congonhas", lat: -23.62, lon: -46.65, expected: "SBSP"},
+		{name: "nothing nearby", lat: -22.0, lon: -45.0, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _, ok := d.Nearest(tt.lat, tt.lon, DefaultRadiusKm)
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("Expected no airport, got %s", a.Ident)
+				}
+				return
+			}
+			if !ok || a.Ident != tt.expected {
+				t.Errorf("Nearest() = %v, expected %s", a, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAirport_Runway(t *testing.T) {
+	d := openTestDatabase(t)
+	gru, _ := d.Lookup("SBGR")
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		track    float64
+		expected string
+	}{
+		{name: "northern runway eastbound", lat: -23.4260, lon: -46.4740, track: 108, expected: "10L"},
+		{name: "southern runway eastbound", lat: -23.4300, lon: -46.4720, track: 108, expected: "10R"},
+		{name: "northern runway westbound", lat: -23.4260, lon: -46.4740, track: 288, expected: "28R"},
+		{name: "crosswind track", lat: -23.4260, lon: -46.4740, track: 200, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := gru.Runway(tt.lat, tt.lon, tt.track, DefaultRunwayTolerance)
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("Expected no runway, got %s", r.Ident)
+				}
+				return
+			}
+			if !ok || r.Ident != tt.expected {
+				t.Errorf("Runway() = %v, expected %s", r, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetector_Observe(t *testing.T) {
+	d := openTestDatabase(t)
+	detector := NewDetector(d)
+	now := time.Now()
+
+	ground := Observation{Time: now, Latitude: -23.4300, Longitude: -46.4720, Altitude: 2450, Track: 108, OnGround: true, GroundKnown: true}
+	if event := detector.Observe("E48D4E", ground); event != nil {
+		t.Errorf("Expected no event for the first observation, got %+v", event)
+	}
+
+	// Messages without the on-ground flag are ignored
+	if event := detector.Observe("E48D4E", Observation{Time: now}); event != nil {
+		t.Errorf("Expected no event without ground flag, got %+v", event)
+	}
+
+	airborne := ground
+	airborne.OnGround = false
+	airborne.Altitude = 2700
+	airborne.Time = now.Add(time.Minute)
+	event := detector.Observe("E48D4E", airborne)
+	if event == nil {
+		t.Fatal("Expected a takeoff event")
+	}
+	if event.Type != Takeoff || event.Airport.Ident != "SBGR" || event.Runway != "10R" || !event.Time.Equal(airborne.Time) {
+		t.Errorf("Unexpected takeoff event: %+v", event)
+	}
+
+	// A ground flag at cruise altitude is spurious
+	spurious := airborne
+	spurious.OnGround = true
+	spurious.Altitude = 35000
+	if event := detector.Observe("E48D4E", spurious); event != nil {
+		t.Errorf("Expected no event at cruise altitude, got %+v", event)
+	}
+
+	detector.Forget("E48D4E")
+	landing := ground
+	landing.Track = 288
+	landing.Latitude, landing.Longitude = -23.4260, -46.4740
+	detector.Observe("E48D4E", airborne)
+	event = detector.Observe("E48D4E", landing)
+	if event == nil || event.Type != Landing || event.Runway != "28R" {
+		t.Errorf("Expected a landing on 28R, got %+v", event)
+	}
+}
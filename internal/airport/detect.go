@@ -0,0 +1,110 @@
+package airport
+
+import (
+	"sync"
+	"time"
+)
+
+// Detection thresholds
+const (
+	DefaultRadiusKm        = 5.0  // Maximum distance from the airport reference point
+	DefaultMaxHeight       = 2000 // Maximum feet above airport elevation for a transition to count
+	DefaultRunwayTolerance = 30.0 // Maximum degrees between track and runway heading
+)
+
+// EventType is the kind of airport event
+type EventType string
+
+// Airport event types
+const (
+	Takeoff EventType = "takeoff"
+	Landing EventType = "landing"
+)
+
+// Event is a takeoff or landing detected at an airport
+type Event struct {
+	Type    EventType `json:"type"`
+	Airport *Airport  `json:"airport"`
+	Runway  string    `json:"runway"`
+	Time    time.Time `json:"time"`
+}
+
+// Observation is the latest known state of an aircraft
+type Observation struct {
+	Time        time.Time
+	Latitude    float64
+	Longitude   float64
+	Altitude    int
+	Track       float64
+	OnGround    bool
+	GroundKnown bool // Whether the current message reported OnGround
+}
+
+// Locator finds the airport nearest to a position
+type Locator interface {
+	Nearest(lat, lon, radiusKm float64) (*Airport, float64, bool)
+}
+
+// Detector detects takeoffs and landings from on-ground transitions
+type Detector struct {
+	locator  Locator
+	onGround map[string]bool // Last reported on-ground flag by hex ident
+	mu       sync.Mutex
+}
+
+// NewDetector creates a new Detector using locator to find airports
+func NewDetector(locator Locator) *Detector {
+	return &Detector{
+		locator:  locator,
+		onGround: make(map[string]bool),
+	}
+}
+
+// Observe records the state of an aircraft and returns the takeoff or landing
+// it completes, if any. Transitions away from any airport or well above its
+// elevation are ignored as spurious.
+func (d *Detector) Observe(hexIdent string, obs Observation) *Event {
+	if !obs.GroundKnown {
+		return nil
+	}
+
+	d.mu.Lock()
+	wasOnGround, known := d.onGround[hexIdent]
+	d.onGround[hexIdent] = obs.OnGround
+	d.mu.Unlock()
+
+	if !known || wasOnGround == obs.OnGround {
+		return nil
+	}
+	if obs.Latitude == 0 && obs.Longitude == 0 {
+		return nil
+	}
+
+	airport, _, ok := d.locator.Nearest(obs.Latitude, obs.Longitude, DefaultRadiusKm)
+	if !ok {
+		return nil
+	}
+	if obs.Altitude-airport.Elevation > DefaultMaxHeight {
+		return nil
+	}
+
+	event := &Event{
+		Type:    Landing,
+		Airport: airport,
+		Time:    obs.Time,
+	}
+	if wasOnGround {
+		event.Type = Takeoff
+	}
+	if runway, ok := airport.Runway(obs.Latitude, obs.Longitude, obs.Track, DefaultRunwayTolerance); ok {
+		event.Runway = runway.Ident
+	}
+	return event
+}
+
+// Forget removes the state kept for an aircraft
+func (d *Detector) Forget(hexIdent string) {
+	d.mu.Lock()
+	delete(d.onGround, hexIdent)
+	d.mu.Unlock()
+}
@@ -0,0 +1,357 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+// Alert types
+const (
+	TypeEmergencySquawk = "emergency_squawk"
+	TypeSquawkChange    = "squawk_change"
+	TypeEmergencyFlag   = "emergency_flag"
+	TypeSPI             = "spi"
+	TypeRapidDescent    = "rapid_descent"
+)
+
+// Alert severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// DefaultRapidDescentRate is the default descent rate in feet per minute that raises an alert
+const DefaultRapidDescentRate = 5000
+
+// DefaultNearbyRadiusKm is the default radius searched for traffic around critical alerts
+const DefaultNearbyRadiusKm = 10
+
+// DefaultQueueSize is the number of alerts buffered for delivery to the sinks
+const DefaultQueueSize = 256
+
+// emergencySquawks maps the reserved transponder codes to their meaning
+var emergencySquawks = map[string]string{
+	"7500": "unlawful interference",
+	"7600": "radio failure",
+	"7700": "general emergency",
+}
+
+// Rule detects an alert from the previous and current state of an aircraft.
+// prev is nil the first time an aircraft is seen.
+type Rule interface {
+	Evaluate(prev, curr *types.AircraftState) *types.Alert
+}
+
+// RuleFunc adapts a function to the Rule interface
+type RuleFunc func(prev, curr *types.AircraftState) *types.Alert
+
+// Evaluate calls f(prev, curr)
+func (f RuleFunc) Evaluate(prev, curr *types.AircraftState) *types.Alert {
+	return f(prev, curr)
+}
+
+// EmergencySquawkRule alerts on the 7500, 7600 and 7700 transponder codes
+func EmergencySquawkRule() Rule {
+	return RuleFunc(func(_, curr *types.AircraftState) *types.Alert {
+		meaning, ok := emergencySquawks[curr.Squawk]
+		if !ok {
+			return nil
+		}
+		return &types.Alert{
+			Type:     TypeEmergencySquawk,
+			Severity: SeverityCritical,
+			Message:  fmt.Sprintf("Squawk %s (%s)", curr.Squawk, meaning),
+		}
+	})
+}
+
+// SquawkChangeRule alerts when an aircraft changes transponder code or sets the SBS alert flag
+func SquawkChangeRule() Rule {
+	return RuleFunc(func(prev, curr *types.AircraftState) *types.Alert {
+		if curr.Squawk == "" {
+			return nil
+		}
+		if prev != nil && prev.Squawk != "" && prev.Squawk != curr.Squawk {
+			return &types.Alert{
+				Type:     TypeSquawkChange,
+				Severity: SeverityInfo,
+				Message:  fmt.Sprintf("Squawk changed from %s to %s", prev.Squawk, curr.Squawk),
+			}
+		}
+		if curr.Alert {
+			return &types.Alert{
+				Type:     TypeSquawkChange,
+				Severity: SeverityInfo,
+				Message:  fmt.Sprintf("Squawk change flagged, now %s", curr.Squawk),
+			}
+		}
+		return nil
+	})
+}
+
+// EmergencyFlagRule alerts when the SBS emergency flag is set
+func EmergencyFlagRule() Rule {
+	return RuleFunc(func(_, curr *types.AircraftState) *types.Alert {
+		if !curr.Emergency {
+			return nil
+		}
+		return &types.Alert{
+			Type:     TypeEmergencyFlag,
+			Severity: SeverityCritical,
+			Message:  "Emergency flag set",
+		}
+	})
+}
+
+// SPIRule alerts when the special position identification (ident) flag is set
+func SPIRule() Rule {
+	return RuleFunc(func(_, curr *types.AircraftState) *types.Alert {
+		if !curr.SPI {
+			return nil
+		}
+		return &types.Alert{
+			Type:     TypeSPI,
+			Severity: SeverityInfo,
+			Message:  "Ident (SPI) activated",
+		}
+	})
+}
+
+// RapidDescentRule alerts when an airborne aircraft descends faster than rate feet per minute
+func RapidDescentRule(rate int) Rule {
+	if rate <= 0 {
+		rate = DefaultRapidDescentRate
+	}
+	return RuleFunc(func(_, curr *types.AircraftState) *types.Alert {
+		if curr.OnGround || curr.VerticalRate > -rate {
+			return nil
+		}
+		return &types.Alert{
+			Type:     TypeRapidDescent,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Descending at %d ft/min", -curr.VerticalRate),
+		}
+	})
+}
+
+// DefaultRules returns the built-in rules
+func DefaultRules(rapidDescentRate int) []Rule {
+	return []Rule{
+		EmergencySquawkRule(),
+		SquawkChangeRule(),
+		EmergencyFlagRule(),
+		SPIRule(),
+		RapidDescentRule(rapidDescentRate),
+	}
+}
+
+// NearbyLookup returns the hex idents of the aircraft within radiusKm of a
+// position, nearest first
+type NearbyLookup func(ctx context.Context, lat, lon, radiusKm float64) ([]string, error)
+
+// raisedAlerts holds the alert keys raised for a flight
+type raisedAlerts struct {
+	keys    map[string]bool
+	updated time.Time // Time of the last state evaluated for the flight
+}
+
+// Engine evaluates rules on aircraft state changes and delivers new alerts to
+// its sinks. Each alert is raised at most once per flight.
+type Engine struct {
+	rules []Rule
+	sinks []Sink
+	seen  map[string]*raisedAlerts // Alerts already raised, by flight
+	queue chan *types.Alert
+	mu    sync.Mutex
+
+	nearby       NearbyLookup // Optional lookup of the traffic around critical alerts
+	nearbyRadius float64
+}
+
+// NewEngine creates a new Engine with the given rules and sinks
+func NewEngine(rules []Rule, sinks ...Sink) *Engine {
+	return &Engine{
+		rules: rules,
+		sinks: sinks,
+		seen:  make(map[string]*raisedAlerts),
+		queue: make(chan *types.Alert, DefaultQueueSize),
+	}
+}
+
+// AddSink adds a delivery sink. It must be called before Run.
+func (e *Engine) AddSink(s Sink) {
+	e.sinks = append(e.sinks, s)
+}
+
+// SetNearbyLookup lists the traffic within radiusKm in the message of critical
+// alerts with a known position. It must be called before Run.
+func (e *Engine) SetNearbyLookup(lookup NearbyLookup, radiusKm float64) {
+	e.nearby = lookup
+	e.nearbyRadius = radiusKm
+}
+
+// Evaluate runs the rules for a flight and queues the alerts not raised yet
+// for that flight. It returns the new alerts.
+func (e *Engine) Evaluate(flight *types.Flight, prev, curr *types.AircraftState) []*types.Alert {
+	e.mu.Lock()
+	if raised, exists := e.seen[flight.SessionID]; exists {
+		raised.updated = curr.Timestamp
+	}
+	e.mu.Unlock()
+
+	var raised []*types.Alert
+	for _, rule := range e.rules {
+		alert := rule.Evaluate(prev, curr)
+		if alert == nil {
+			continue
+		}
+		if !e.markSeen(flight.SessionID, alertKey(alert.Type, curr), curr.Timestamp) {
+			continue
+		}
+
+		alert.Time = curr.Timestamp
+		alert.HexIdent = curr.HexIdent
+		alert.Callsign = flight.Callsign
+		alert.SessionID = flight.SessionID
+		alert.Squawk = curr.Squawk
+		alert.Latitude = curr.Latitude
+		alert.Longitude = curr.Longitude
+		alert.Altitude = curr.Altitude
+		raised = append(raised, alert)
+		e.enqueue(alert)
+	}
+	return raised
+}
+
+// Raise queues an alert detected outside the rules, e.g. a watchlist event,
+// for delivery to the sinks
+func (e *Engine) Raise(alert *types.Alert) {
+	e.enqueue(alert)
+}
+
+// enqueue queues an alert for delivery without blocking, dropping it when the queue is full
+func (e *Engine) enqueue(alert *types.Alert) {
+	select {
+	case e.queue <- alert:
+	default:
+		log.Printf("Warning: Alert queue full, dropping %s alert for %s", alert.Type, alert.HexIdent)
+	}
+}
+
+// Forget clears the alerts raised for a flight
+func (e *Engine) Forget(sessionID string) {
+	e.mu.Lock()
+	delete(e.seen, sessionID)
+	e.mu.Unlock()
+}
+
+// Prune clears the alerts raised for the flights without a state evaluated
+// since before, e.g. aircraft that stopped reporting without their flight
+// ending, and returns how many flights it cleared
+func (e *Engine) Prune(before time.Time) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pruned := 0
+	for sessionID, raised := range e.seen {
+		if raised.updated.Before(before) {
+			delete(e.seen, sessionID)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// Run delivers queued alerts to the sinks until the context is cancelled,
+// then delivers whatever is still queued
+func (e *Engine) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case alert := <-e.queue:
+					e.deliver(context.Background(), alert)
+				default:
+					return
+				}
+			}
+		case alert := <-e.queue:
+			e.deliver(ctx, alert)
+		}
+	}
+}
+
+// deliver sends an alert to every sink
+func (e *Engine) deliver(ctx context.Context, alert *types.Alert) {
+	alert = e.annotate(ctx, alert)
+	for _, sink := range e.sinks {
+		if err := sink.Send(ctx, alert); err != nil {
+			log.Printf("Warning: Failed to deliver %s alert for %s to %s: %v", alert.Type, alert.HexIdent, sink.Name(), err)
+		}
+	}
+}
+
+// annotate returns a copy of a critical alert with the nearby traffic appended
+// to its message. Other alerts, and alerts without nearby traffic, are returned as is.
+func (e *Engine) annotate(ctx context.Context, alert *types.Alert) *types.Alert {
+	if e.nearby == nil || alert.Severity != SeverityCritical || (alert.Latitude == 0 && alert.Longitude == 0) {
+		return alert
+	}
+
+	hexIdents, err := e.nearby(ctx, alert.Latitude, alert.Longitude, e.nearbyRadius)
+	if err != nil {
+		log.Printf("Warning: Failed to look up traffic near %s alert for %s: %v", alert.Type, alert.HexIdent, err)
+		return alert
+	}
+
+	var traffic []string
+	for _, hexIdent := range hexIdents {
+		if hexIdent != alert.HexIdent {
+			traffic = append(traffic, hexIdent)
+		}
+	}
+	if len(traffic) == 0 {
+		return alert
+	}
+
+	annotated := *alert
+	annotated.Message = fmt.Sprintf("%s; nearby traffic within %g km: %s", alert.Message, e.nearbyRadius, strings.Join(traffic, ", "))
+	return &annotated
+}
+
+// alertKey identifies an alert within a flight. Squawk alerts are keyed by
+// code so each new code is reported once.
+func alertKey(alertType string, curr *types.AircraftState) string {
+	switch alertType {
+	case TypeEmergencySquawk, TypeSquawkChange:
+		return alertType + "|" + curr.Squawk
+	default:
+		return alertType
+	}
+}
+
+// markSeen records an alert key for a flight, evaluated at updated, and
+// reports whether it is new
+func (e *Engine) markSeen(sessionID, key string, updated time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	raised, exists := e.seen[sessionID]
+	if !exists {
+		raised = &raisedAlerts{keys: make(map[string]bool), updated: updated}
+		e.seen[sessionID] = raised
+	}
+	if raised.keys[key] {
+		return false
+	}
+	raised.keys[key] = true
+	return true
+}
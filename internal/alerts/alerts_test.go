@@ -0,0 +1,252 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+// recordingSink collects delivered alerts
+type recordingSink struct {
+	alerts []*types.Alert
+	err    error
+	mu     sync.Mutex
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(_ context.Context, alert *types.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, alert)
+	return s.err
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.alerts)
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		prev     *types.AircraftState
+		curr     *types.AircraftState
+		expected string
+	}{
+		{name: "hijack squawk", rule: EmergencySquawkRule(), curr: &types.AircraftState{Squawk: "7500"}, expected: TypeEmergencySquawk},
+		{name: "radio failure squawk", rule: EmergencySquawkRule(), curr: &types.AircraftState{Squawk: "7600"}, expected: TypeEmergencySquawk},
+		{name: "emergency squawk", rule: EmergencySquawkRule(), curr: &types.AircraftState{Squawk: "7700"}, expected: TypeEmergencySquawk},
+		{name: "normal squawk", rule: EmergencySquawkRule(), curr: &types.AircraftState{Squawk: "1234"}},
+		{name: "squawk change", rule: SquawkChangeRule(), prev: &types.AircraftState{Squawk: "1234"}, curr: &types.AircraftState{Squawk: "4321"}, expected: TypeSquawkChange},
+		{name: "squawk first seen", rule: SquawkChangeRule(), prev: &types.AircraftState{}, curr: &types.AircraftState{Squawk: "4321"}},
+		{name: "squawk alert flag", rule: SquawkChangeRule(), curr: &types.AircraftState{Squawk: "4321", Alert: true}, expected: TypeSquawkChange},
+		{name: "same squawk", rule: SquawkChangeRule(), prev: &types.AircraftState{Squawk: "1234"}, curr: &types.AircraftState{Squawk: "1234"}},
+		{name: "emergency flag", rule: EmergencyFlagRule(), curr: &types.AircraftState{Emergency: true}, expected: TypeEmergencyFlag},
+		{name: "spi flag", rule: SPIRule(), curr: &types.AircraftState{SPI: true}, expected: TypeSPI},
+		{name: "rapid descent", rule: RapidDescentRule(5000), curr: &types.AircraftState{VerticalRate: -6000}, expected: TypeRapidDescent},
+		{name: "normal descent", rule: RapidDescentRule(5000), curr: &types.AircraftState{VerticalRate: -1500}},
+		{name: "descent on ground", rule: RapidDescentRule(5000), curr: &types.AircraftState{VerticalRate: -6000, OnGround: true}},
+		{name: "default descent rate", rule: RapidDescentRule(0), curr: &types.AircraftState{VerticalRate: -5000}, expected: TypeRapidDescent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := tt.rule.Evaluate(tt.prev, tt.curr)
+			if tt.expected == "" {
+				if alert != nil {
+					t.Errorf("Expected no alert, got %+v", alert)
+				}
+				return
+			}
+			if alert == nil || alert.Type != tt.expected {
+				t.Errorf("Evaluate() = %+v, expected type %s", alert, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEngine_Evaluate_DeduplicatesPerFlight(t *testing.T) {
+	engine := NewEngine(DefaultRules(DefaultRapidDescentRate))
+	flight := &types.Flight{SessionID: "session-1", Callsign: "TAM3456"}
+	now := time.Now()
+
+	curr := &types.AircraftState{HexIdent: "E48D4E", Squawk: "7700", Latitude: -23.4, Longitude: -46.5, Altitude: 12000, Timestamp: now}
+	raised := engine.Evaluate(flight, nil, curr)
+	if len(raised) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(raised))
+	}
+	alert := raised[0]
+	if alert.SessionID != "session-1" || alert.Callsign != "TAM3456" || alert.HexIdent != "E48D4E" ||
+		alert.Squawk != "7700" || alert.Altitude != 12000 || !alert.Time.Equal(now) {
+		t.Errorf("Unexpected alert attributes: %+v", alert)
+	}
+
+	// The same squawk is not reported again for the flight
+	if raised := engine.Evaluate(flight, curr, curr); len(raised) != 0 {
+		t.Errorf("Expected repeated alert to be suppressed, got %d", len(raised))
+	}
+
+	// A new code is reported, as well as the change
+	next := *curr
+	next.Squawk = "7600"
+	if raised := engine.Evaluate(flight, curr, &next); len(raised) != 2 {
+		t.Errorf("Expected emergency and change alerts for a new code, got %d", len(raised))
+	}
+
+	// Another flight of the same aircraft gets its own alerts
+	if raised := engine.Evaluate(&types.Flight{SessionID: "session-2"}, nil, curr); len(raised) != 1 {
+		t.Errorf("Expected alert for a new flight, got %d", len(raised))
+	}
+
+	// Forgetting a flight allows its alerts again
+	engine.Forget("session-1")
+	if raised := engine.Evaluate(flight, nil, curr); len(raised) != 1 {
+		t.Errorf("Expected alert after Forget, got %d", len(raised))
+	}
+}
+
+func TestEngine_Prune(t *testing.T) {
+	engine := NewEngine(DefaultRules(DefaultRapidDescentRate))
+	now := time.Now()
+	stale := &types.Flight{SessionID: "stale"}
+	active := &types.Flight{SessionID: "active"}
+
+	curr := &types.AircraftState{HexIdent: "E48D4E", Squawk: "7700", Timestamp: now.Add(-10 * time.Minute)}
+	engine.Evaluate(stale, nil, curr)
+	engine.Evaluate(active, nil, curr)
+
+	// Evaluating a later state keeps the flight, even without a new alert
+	later := *curr
+	later.Timestamp = now
+	if raised := engine.Evaluate(active, curr, &later); len(raised) != 0 {
+		t.Fatalf("Expected no new alert, got %d", len(raised))
+	}
+
+	if pruned := engine.Prune(now.Add(-5 * time.Minute)); pruned != 1 {
+		t.Errorf("Expected 1 flight pruned, got %d", pruned)
+	}
+	if _, exists := engine.seen["stale"]; exists {
+		t.Error("Expected the stale flight to be pruned")
+	}
+	if raised := engine.Evaluate(active, &later, &later); len(raised) != 0 {
+		t.Errorf("Expected the alerts of the active flight to be kept, got %d", len(raised))
+	}
+}
+
+func TestEngine_Run(t *testing.T) {
+	failing := &recordingSink{err: errors.New("unavailable")}
+	sink := &recordingSink{}
+	engine := NewEngine(DefaultRules(0), failing)
+	engine.AddSink(sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		engine.Run(ctx)
+		close(done)
+	}()
+
+	engine.Evaluate(&types.Flight{SessionID: "s1"}, nil, &types.AircraftState{HexIdent: "ABC123", Emergency: true})
+
+	deadline := time.Now().Add(time.Second)
+	for sink.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if sink.count() != 1 {
+		t.Errorf("Expected 1 delivered alert, got %d", sink.count())
+	}
+	if failing.count() != 1 {
+		t.Errorf("Expected failing sink to be attempted once, got %d", failing.count())
+	}
+
+	// Alerts queued at shutdown are still delivered
+	cancel()
+	<-done
+	engine.Evaluate(&types.Flight{SessionID: "s2"}, nil, &types.AircraftState{HexIdent: "DEF456", SPI: true})
+	engine.Run(ctx)
+	if sink.count() != 2 {
+		t.Errorf("Expected queued alert to be delivered on shutdown, got %d", sink.count())
+	}
+}
+
+func TestEngine_QueueFull(t *testing.T) {
+	engine := NewEngine(DefaultRules(0))
+	for i := 0; i < DefaultQueueSize+10; i++ {
+		engine.Evaluate(&types.Flight{SessionID: fmt.Sprintf("session-%d", i)}, nil,
+			&types.AircraftState{HexIdent: "ABC123", Emergency: true})
+	}
+	if len(engine.queue) != DefaultQueueSize {
+		t.Errorf("Expected queue to be capped at %d, got %d", DefaultQueueSize, len(engine.queue))
+	}
+}
+
+func TestEngine_Raise(t *testing.T) {
+	sink := &recordingSink{}
+	engine := NewEngine(nil, sink)
+
+	alert := &types.Alert{Type: "watchlist_appeared", HexIdent: "ABC123", SessionID: "s1"}
+	engine.Raise(alert)
+	engine.Raise(alert)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	engine.Run(ctx)
+
+	// Raised alerts are not deduplicated by the engine
+	if sink.count() != 2 {
+		t.Errorf("Expected 2 delivered alerts, got %d", sink.count())
+	}
+}
+
+func TestEngine_NearbyTraffic(t *testing.T) {
+	sink := &recordingSink{}
+	engine := NewEngine(nil, sink)
+
+	var lookupErr error
+	engine.SetNearbyLookup(func(_ context.Context, lat, lon, radiusKm float64) ([]string, error) {
+		if radiusKm != DefaultNearbyRadiusKm {
+			t.Errorf("Expected radius %v, got %v", DefaultNearbyRadiusKm, radiusKm)
+		}
+		return []string{"ABC123", "DEF456", "789ABC"}, lookupErr
+	}, DefaultNearbyRadiusKm)
+
+	critical := &types.Alert{Type: TypeEmergencySquawk, Severity: SeverityCritical, HexIdent: "ABC123",
+		Message: "Squawk 7700", Latitude: -23.5, Longitude: -46.6}
+	engine.Raise(critical)
+	engine.Raise(&types.Alert{Type: TypeSPI, Severity: SeverityInfo, HexIdent: "ABC123", Message: "Ident",
+		Latitude: -23.5, Longitude: -46.6})
+	engine.Raise(&types.Alert{Type: TypeEmergencyFlag, Severity: SeverityCritical, HexIdent: "ABC123", Message: "No position"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	engine.Run(ctx)
+
+	expected := []string{"Squawk 7700; nearby traffic within 10 km: DEF456, 789ABC", "Ident", "No position"}
+	if sink.count() != len(expected) {
+		t.Fatalf("Expected %d delivered alerts, got %d", len(expected), sink.count())
+	}
+	for i, message := range expected {
+		if sink.alerts[i].Message != message {
+			t.Errorf("Expected message %q, got %q", message, sink.alerts[i].Message)
+		}
+	}
+	if critical.Message != "Squawk 7700" {
+		t.Errorf("Expected the raised alert to be left unchanged, got %q", critical.Message)
+	}
+
+	// Lookup failures deliver the alert without traffic
+	lookupErr = errors.New("redis down")
+	engine.Raise(critical)
+	engine.Run(ctx)
+	if got := sink.alerts[len(sink.alerts)-1].Message; got != "Squawk 7700" {
+		t.Errorf("Expected unannotated message on lookup failure, got %q", got)
+	}
+}
@@ -0,0 +1,224 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+// Sink delivers alerts to a destination
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, alert *types.Alert) error
+}
+
+// Publisher publishes data on a message subject
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// NATSSink publishes alerts as JSON on a NATS subject
+type NATSSink struct {
+	publisher Publisher
+	subject   string
+}
+
+// NewNATSSink creates a new NATSSink
+func NewNATSSink(publisher Publisher, subject string) *NATSSink {
+	return &NATSSink{publisher: publisher, subject: subject}
+}
+
+// Name returns the sink name
+func (s *NATSSink) Name() string {
+	return "nats:" + s.subject
+}
+
+// Send publishes an alert
+func (s *NATSSink) Send(_ context.Context, alert *types.Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+	return s.publisher.Publish(s.subject, data)
+}
+
+// WebhookSink posts alerts as JSON to an HTTP endpoint
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a new WebhookSink
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the sink name
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Send posts an alert
+func (s *WebhookSink) Send(ctx context.Context, alert *types.Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			fmt.Fprintf(os.Stderr, "error closing response body: %v\n", cerr)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// FileSink appends alerts as JSON lines to a log file
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSink creates a new FileSink
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Name returns the sink name
+func (s *FileSink) Name() string {
+	return "file:" + s.path
+}
+
+// Send appends an alert to the file. The file is reopened for every alert so
+// external log rotation is picked up.
+func (s *FileSink) Send(_ context.Context, alert *types.Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	//nolint:gosec // path comes from operator configuration
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open alert log: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write alert log: %w", err)
+	}
+	return file.Close()
+}
+
+// sendMail is the SMTP delivery function, replaced in tests
+var sendMail = smtp.SendMail
+
+// lineBreaks replaces the line breaks of the received values written to
+// the email body, which would otherwise add lines to it
+var lineBreaks = strings.NewReplacer("\r", " ", "\n", " ")
+
+// SMTPSink emails alerts through an SMTP server
+type SMTPSink struct {
+	addr string
+	from string
+	to   []string
+	auth smtp.Auth
+}
+
+// NewSMTPSink creates a new SMTPSink. Authentication is used when username is set.
+func NewSMTPSink(addr, from string, to []string, username, password string) *SMTPSink {
+	s := &SMTPSink{addr: addr, from: from, to: to}
+	if username != "" {
+		host := addr
+		if i := strings.LastIndex(addr, ":"); i >= 0 {
+			host = addr[:i]
+		}
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+// Name returns the sink name
+func (s *SMTPSink) Name() string {
+	return "smtp:" + s.addr
+}
+
+// Send emails an alert
+func (s *SMTPSink) Send(_ context.Context, alert *types.Alert) error {
+	// The callsign and message come from the received data, so the subject is
+	// encoded to keep line breaks in them from adding headers
+	subject := mime.QEncoding.Encode("utf-8",
+		fmt.Sprintf("[%s] %s %s: %s", strings.ToUpper(alert.Severity), alert.HexIdent, alert.Callsign, alert.Message))
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", s.from)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", subject)
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&body, "Type: %s\r\n", alert.Type)
+	fmt.Fprintf(&body, "Time: %s\r\n", alert.Time.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&body, "Aircraft: %s\r\n", alert.HexIdent)
+	fmt.Fprintf(&body, "Callsign: %s\r\n", lineBreaks.Replace(alert.Callsign))
+	fmt.Fprintf(&body, "Session: %s\r\n", alert.SessionID)
+	fmt.Fprintf(&body, "Squawk: %s\r\n", lineBreaks.Replace(alert.Squawk))
+	fmt.Fprintf(&body, "Position: %.5f, %.5f at %d ft\r\n", alert.Latitude, alert.Longitude, alert.Altitude)
+
+	if err := sendMail(s.addr, s.auth, s.from, s.to, []byte(body.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// AlertStore persists alerts
+type AlertStore interface {
+	StoreAlert(alert *types.Alert) error
+}
+
+// StoreSink records alerts in the alert history
+type StoreSink struct {
+	store AlertStore
+}
+
+// NewStoreSink creates a new StoreSink
+func NewStoreSink(store AlertStore) *StoreSink {
+	return &StoreSink{store: store}
+}
+
+// Name returns the sink name
+func (s *StoreSink) Name() string {
+	return "database"
+}
+
+// Send stores an alert
+func (s *StoreSink) Send(_ context.Context, alert *types.Alert) error {
+	return s.store.StoreAlert(alert)
+}
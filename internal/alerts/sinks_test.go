@@ -0,0 +1,186 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+func testAlert() *types.Alert {
+	return &types.Alert{
+		Time:      time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Type:      TypeEmergencySquawk,
+		Severity:  SeverityCritical,
+		HexIdent:  "E48D4E",
+		Callsign:  "TAM3456",
+		SessionID: "session-1",
+		Squawk:    "7700",
+		Message:   "Squawk 7700 (general emergency)",
+	}
+}
+
+type mockPublisher struct {
+	subject string
+	data    []byte
+	err     error
+}
+
+func (p *mockPublisher) Publish(subject string, data []byte) error {
+	p.subject = subject
+	p.data = data
+	return p.err
+}
+
+func TestNATSSink(t *testing.T) {
+	publisher := &mockPublisher{}
+	sink := NewNATSSink(publisher, "sbs.alerts")
+
+	if err := sink.Send(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if publisher.subject != "sbs.alerts" {
+		t.Errorf("Expected subject sbs.alerts, got %s", publisher.subject)
+	}
+	var alert types.Alert
+	if err := json.Unmarshal(publisher.data, &alert); err != nil || alert.HexIdent != "E48D4E" {
+		t.Errorf("Unexpected published alert: %s (%v)", publisher.data, err)
+	}
+
+	publisher.err = errors.New("disconnected")
+	if err := sink.Send(context.Background(), testAlert()); err == nil {
+		t.Error("Expected publish error to be returned")
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received types.Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON content type, got %s", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode alert: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := NewWebhookSink(server.URL).Send(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if received.SessionID != "session-1" {
+		t.Errorf("Expected alert to be posted, got %+v", received)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	if err := NewWebhookSink(failing.URL).Send(context.Background(), testAlert()); err == nil {
+		t.Error("Expected error for a non-2xx response")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.log")
+	sink := NewFileSink(path)
+
+	for i := 0; i < 2; i++ {
+		if err := sink.Send(context.Background(), testAlert()); err != nil {
+			t.Fatalf("Send() unexpected error: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read alert log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	var alert types.Alert
+	if err := json.Unmarshal([]byte(lines[0]), &alert); err != nil || alert.Type != TypeEmergencySquawk {
+		t.Errorf("Unexpected log line %q (%v)", lines[0], err)
+	}
+
+	if err := NewFileSink(filepath.Join(t.TempDir(), "missing", "alerts.log")).Send(context.Background(), testAlert()); err == nil {
+		t.Error("Expected error for a missing directory")
+	}
+}
+
+func TestSMTPSink(t *testing.T) {
+	original := sendMail
+	defer func() { sendMail = original }()
+
+	var (
+		gotAddr string
+		gotAuth smtp.Auth
+		gotTo   []string
+		gotMsg  string
+	)
+	sendMail = func(addr string, auth smtp.Auth, _ string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotTo, gotMsg = addr, auth, to, string(msg)
+		return nil
+	}
+
+	sink := NewSMTPSink("mail.example.com:587", "alerts@example.com", []string{"ops@example.com"}, "user", "secret")
+	if err := sink.Send(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if gotAddr != "mail.example.com:587" || gotAuth == nil || len(gotTo) != 1 {
+		t.Errorf("Unexpected delivery: addr=%s auth=%v to=%v", gotAddr, gotAuth, gotTo)
+	}
+	if !strings.Contains(gotMsg, "Subject: [CRITICAL] E48D4E TAM3456: Squawk 7700") {
+		t.Errorf("Unexpected message:\n%s", gotMsg)
+	}
+
+	// Line breaks in the subject are encoded rather than starting new headers
+	injected := testAlert()
+	injected.Callsign = "TAM3456\r\nBcc: victim@example.com"
+	if err := sink.Send(context.Background(), injected); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if strings.Contains(gotMsg, "\r\nBcc:") || !strings.Contains(gotMsg, "Subject: =?utf-8?q?") {
+		t.Errorf("Expected an encoded subject, got:\n%s", gotMsg)
+	}
+
+	if NewSMTPSink("localhost:25", "a@example.com", []string{"b@example.com"}, "", "").auth != nil {
+		t.Error("Expected no authentication without a username")
+	}
+
+	sendMail = func(string, smtp.Auth, string, []string, []byte) error { return errors.New("refused") }
+	if err := sink.Send(context.Background(), testAlert()); err == nil {
+		t.Error("Expected error when delivery fails")
+	}
+}
+
+type mockAlertStore struct {
+	alerts []*types.Alert
+}
+
+func (s *mockAlertStore) StoreAlert(alert *types.Alert) error {
+	s.alerts = append(s.alerts, alert)
+	return nil
+}
+
+func TestStoreSink(t *testing.T) {
+	store := &mockAlertStore{}
+	if err := NewStoreSink(store).Send(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if len(store.alerts) != 1 {
+		t.Errorf("Expected 1 stored alert, got %d", len(store.alerts))
+	}
+}
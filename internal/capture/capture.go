@@ -1,11 +1,14 @@
 package capture
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
 	"sync"
 	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/receiver"
 )
 
 // Message represents a captured SBS message
@@ -18,23 +21,65 @@ type Message struct {
 // Capture represents a network capture instance
 type Capture struct {
 	sources  []string
+	defaults receiver.Options            // Connection options of every source
+	options  map[string]receiver.Options // Connection options overridden by source
 	conns    map[string]net.Conn
 	msgChan  chan Message
 	wg       sync.WaitGroup
 	stopChan chan struct{}
+	ctx      context.Context // Cancelled on Stop, aborting pending dials
+	cancel   context.CancelFunc
 	mu       sync.Mutex
 }
 
+// readTimeout bounds each read, so the idle timeout and Stop are checked
+// while a source is quiet
+const readTimeout = 2 * time.Second
+
+// DefaultOptions returns the connection options used by New. The short
+// keepalive and read idle timeout notice dropped connections quickly.
+func DefaultOptions() receiver.Options {
+	opts := receiver.DefaultOptions()
+	opts.KeepAlive = 2 * time.Second
+	opts.ReadIdleTimeout = 3 * time.Second
+	return opts
+}
+
 // New creates a new Capture instance
 func New(sources []string) *Capture {
+	return NewWithOptions(sources, DefaultOptions())
+}
+
+// NewWithOptions creates a new Capture instance connecting to every source
+// with opts. Options left unset use those of DefaultOptions.
+func NewWithOptions(sources []string, opts receiver.Options) *Capture {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Capture{
 		sources:  sources,
+		defaults: opts.Merge(DefaultOptions()),
+		options:  make(map[string]receiver.Options),
 		conns:    make(map[string]net.Conn),
 		msgChan:  make(chan Message, 1000), // Buffer size of 1000 messages
 		stopChan: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 }
 
+// SetSourceOptions overrides the connection options of one source. Options
+// left unset use those of the capture. It must be called before Start.
+func (c *Capture) SetSourceOptions(source string, opts receiver.Options) {
+	c.options[source] = opts.Merge(c.defaults)
+}
+
+// sourceOptions returns the connection options of source
+func (c *Capture) sourceOptions(source string) receiver.Options {
+	if opts, ok := c.options[source]; ok {
+		return opts
+	}
+	return c.defaults
+}
+
 // Start begins listening for SBS messages from all sources
 func (c *Capture) Start() error {
 	for _, source := range c.sources {
@@ -44,7 +89,8 @@ func (c *Capture) Start() error {
 	return nil
 }
 
-// handleConnectionError handles connection errors and returns updated state
+// handleConnectionError handles connection errors and returns updated state,
+// waiting reconnectDelay before the next attempt unless the capture stops
 func (c *Capture) handleConnectionError(connected bool, disconnectTime time.Time, reconnectDelay time.Duration) (bool, time.Time) {
 	if connected {
 		if disconnectTime.IsZero() {
@@ -52,9 +98,9 @@ func (c *Capture) handleConnectionError(connected bool, disconnectTime time.Time
 		}
 		connected = false
 	}
-	if !connected && !disconnectTime.IsZero() {
-		// Only sleep and retry if we are not connected
-		time.Sleep(reconnectDelay)
+	select {
+	case <-c.stopChan:
+	case <-time.After(reconnectDelay):
 	}
 	return connected, disconnectTime
 }
@@ -62,11 +108,14 @@ func (c *Capture) handleConnectionError(connected bool, disconnectTime time.Time
 // configureTCPKeepalive configures TCP keepalive settings
 func (c *Capture) configureTCPKeepalive(conn net.Conn, source string) {
 	if tcpConn, ok := conn.(*net.TCPConn); ok {
-		if err := tcpConn.SetKeepAlive(true); err != nil {
+		keepAlive := c.sourceOptions(source).KeepAlive
+		if err := tcpConn.SetKeepAlive(keepAlive >= 0); err != nil {
 			fmt.Printf("Warning: failed to set keepalive for %s: %v\n", source, err)
 		}
-		if err := tcpConn.SetKeepAlivePeriod(2 * time.Second); err != nil {
-			fmt.Printf("Warning: failed to set keepalive period for %s: %v\n", source, err)
+		if keepAlive > 0 {
+			if err := tcpConn.SetKeepAlivePeriod(keepAlive); err != nil {
+				fmt.Printf("Warning: failed to set keepalive period for %s: %v\n", source, err)
+			}
 		}
 		if err := tcpConn.SetNoDelay(true); err != nil {
 			fmt.Printf("Warning: failed to set no delay for %s: %v\n", source, err)
@@ -96,6 +145,7 @@ func (c *Capture) handleSuccessfulConnection(connected bool, disconnectTime time
 // Stop gracefully stops the capture
 func (c *Capture) Stop() {
 	close(c.stopChan)
+	c.cancel()
 	c.mu.Lock()
 	for _, conn := range c.conns {
 		if err := conn.Close(); err != nil {
@@ -115,10 +165,11 @@ func (c *Capture) Messages() <-chan Message {
 func (c *Capture) connectToSource(source string) {
 	defer c.wg.Done()
 
-	reconnectDelay := 5 * time.Second
+	opts := c.sourceOptions(source)
 	connected := false
 	var disconnectTime time.Time
 	firstConnection := true
+	attempt := 0
 
 	for {
 		select {
@@ -130,11 +181,13 @@ func (c *Capture) connectToSource(source string) {
 				firstConnection = false
 			}
 
-			conn, err := net.Dial("tcp", source)
+			conn, err := opts.Dial(c.ctx, source)
 			if err != nil {
-				connected, disconnectTime = c.handleConnectionError(connected, disconnectTime, reconnectDelay)
+				connected, disconnectTime = c.handleConnectionError(connected, disconnectTime, opts.Backoff.Delay(attempt))
+				attempt++
 				continue
 			}
+			attempt = 0
 
 			// Configure TCP keepalive
 			c.configureTCPKeepalive(conn, source)
@@ -167,7 +220,8 @@ func (c *Capture) handleConnection(source string, conn net.Conn) {
 		}
 	}()
 
-	buffer := make([]byte, 1024)
+	opts := c.sourceOptions(source)
+	buffer := make([]byte, opts.BufferSize)
 	lastMessageTime := time.Now()
 
 	for {
@@ -175,8 +229,9 @@ func (c *Capture) handleConnection(source string, conn net.Conn) {
 		case <-c.stopChan:
 			return
 		default:
-			// Set a read deadline of 2 seconds
-			if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			// Wait a short time for each read, so a quiet source is only
+			// dropped once it stays quiet for the whole idle timeout
+			if err := conn.SetReadDeadline(time.Now().Add(min(readTimeout, opts.ReadIdleTimeout))); err != nil {
 				fmt.Printf("Warning: failed to set read deadline for %s: %v\n", source, err)
 			}
 
@@ -184,23 +239,14 @@ func (c *Capture) handleConnection(source string, conn net.Conn) {
 			if err != nil {
 				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 					// Check if we've gone too long without a message
-					if time.Since(lastMessageTime) > 3*time.Second {
+					if time.Since(lastMessageTime) > opts.ReadIdleTimeout {
 						return
 					}
-					// Reset the deadline and continue
-					if err := conn.SetReadDeadline(time.Time{}); err != nil {
-						fmt.Printf("Warning: failed to reset read deadline for %s: %v\n", source, err)
-					}
 					continue
 				}
 				return
 			}
 
-			// Reset the read deadline
-			if err := conn.SetReadDeadline(time.Time{}); err != nil {
-				fmt.Printf("Warning: failed to reset read deadline for %s: %v\n", source, err)
-			}
-
 			// Update last message time
 			lastMessageTime = time.Now()
 
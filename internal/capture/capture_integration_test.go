@@ -422,6 +422,7 @@ func TestCapture_Integration_MessageBuffer(t *testing.T) {
 	messagesReceived := 0
 	expectedMessages := 50
 
+wait:
 	for messagesReceived < expectedMessages {
 		select {
 		case msg := <-msgChan:
@@ -430,7 +431,7 @@ func TestCapture_Integration_MessageBuffer(t *testing.T) {
 			}
 		case <-timeout:
 			t.Logf("Timeout waiting for messages. Received %d, expected %d", messagesReceived, expectedMessages)
-			break
+			break wait
 		}
 	}
 
@@ -497,14 +498,15 @@ func TestCapture_Integration_ReadDeadline(t *testing.T) {
 	messagesReceived := 0
 	expectedMessages := 2
 
+wait:
 	for messagesReceived < expectedMessages {
 		select {
 		case msg := <-msgChan:
 			t.Logf("Received message %d: %s", messagesReceived+1, string(msg.Data))
 			messagesReceived++
 		case <-timeout:
-			t.Logf("Timeout waiting for messages. Received %d, expected %d", messagesReceived, expectedMessages)
-			break
+			t.Errorf("Timeout waiting for messages. Received %d, expected %d", messagesReceived, expectedMessages)
+			break wait
 		}
 	}
 
@@ -4,6 +4,8 @@ import (
 	"net"
 	"testing"
 	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/receiver"
 )
 
 func TestNew(t *testing.T) {
@@ -51,6 +53,52 @@ func TestNew_EmptySources(t *testing.T) {
 	}
 }
 
+func TestNewWithOptions(t *testing.T) {
+	capture := NewWithOptions([]string{"localhost:30003", "localhost:30004"}, receiver.Options{ReadIdleTimeout: time.Minute})
+	capture.SetSourceOptions("localhost:30004", receiver.Options{BufferSize: 4096})
+
+	expected := DefaultOptions()
+	expected.ReadIdleTimeout = time.Minute
+	if opts := capture.sourceOptions("localhost:30003"); opts != expected {
+		t.Errorf("Expected options %+v, got %+v", expected, opts)
+	}
+
+	expected.BufferSize = 4096
+	if opts := capture.sourceOptions("localhost:30004"); opts != expected {
+		t.Errorf("Expected overridden options %+v, got %+v", expected, opts)
+	}
+}
+
+func TestCapture_StopDuringBackoff(t *testing.T) {
+	// Nothing listens on the source, so the capture keeps backing off
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	source := listener.Addr().String()
+	if err := listener.Close(); err != nil {
+		t.Fatalf("Failed to close listener: %v", err)
+	}
+
+	opts := receiver.Options{Backoff: receiver.Backoff{Initial: time.Minute, Max: time.Minute}}
+	capture := NewWithOptions([]string{source}, opts)
+	if err := capture.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		capture.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Stop() waited for the reconnection delay")
+	}
+}
+
 func TestCapture_Start(t *testing.T) {
 	sources := []string{"localhost:30003"}
 	capture := New(sources)
@@ -121,7 +169,7 @@ func TestCapture_HandleConnectionError(t *testing.T) {
 	// Test when connected becomes false
 	connected := true
 	disconnectTime := time.Time{}
-	reconnectDelay := 5 * time.Second
+	reconnectDelay := 10 * time.Millisecond
 
 	connected, disconnectTime = capture.handleConnectionError(connected, disconnectTime, reconnectDelay)
 
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/filewatch"
+)
+
+// ReloadInterval is how often Watch polls the configuration file for changes
+const ReloadInterval = 10 * time.Second
+
+// Watch reloads the configuration of service whenever the file at path
+// changes or the process receives SIGHUP, and passes every valid
+// configuration to apply. Configurations that are invalid, or that apply
+// rejects, are logged and ignored, so the service keeps running with the
+// last good one. The environment still takes precedence over the file, so
+// Watch warns when it masks a value set in the file. It blocks until the
+// context is cancelled and any reload in progress has finished.
+func Watch(ctx context.Context, service Service, path string, interval time.Duration, apply func(*Config) error) {
+	var mu sync.Mutex
+	var masked []string // Environment variables last reported as masking the file
+	reload := func() error {
+		cfg, err := Load(service, path)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if names, err := maskedByEnv(path); err == nil && !slices.Equal(names, masked) {
+			if len(names) > 0 {
+				log.Printf("Warning: The environment variables %s override the values set in the configuration file", strings.Join(names, ", "))
+			}
+			masked = names
+		}
+		return apply(cfg)
+	}
+
+	if path != "" {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			filewatch.Watch(ctx, path, interval, reload)
+		}()
+		defer wg.Wait()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Printf("Received SIGHUP, reloading configuration")
+			if err := reload(); err != nil {
+				log.Printf("Warning: Failed to reload configuration: %v", err)
+				continue
+			}
+			log.Printf("Reloaded configuration")
+		}
+	}
+}
+
+// maskedByEnv returns the sorted names of the environment variables that
+// override a value set in the file at path
+func maskedByEnv(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	// The values replaced in the defaults tell apart the ones the file sets
+	fromDefaults := make(map[string]string)
+	if err := Default().applyEnv(fromDefaults); err != nil {
+		return nil, err
+	}
+	cfg := Default()
+	if err := cfg.readFile(path); err != nil {
+		return nil, err
+	}
+	fromFile := make(map[string]string)
+	if err := cfg.applyEnv(fromFile); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name, value := range fromFile {
+		if defaultValue, ok := fromDefaults[name]; !ok || defaultValue != value {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
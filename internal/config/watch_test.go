@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	setEnv(t, nil)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("ingestor:\n  sources: [\"a:30003\"]\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	applied := make(chan *Config, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, Ingestor, path, 10*time.Millisecond, func(cfg *Config) error { applied <- cfg; return nil })
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Invalid configurations are not applied
+	if err := os.WriteFile(path, []byte("ingestor:\n  sources: [\"bad\"]\n"), 0o600); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case cfg := <-applied:
+		t.Errorf("Expected invalid config to be ignored, got %+v", cfg.Ingestor)
+	default:
+	}
+
+	if err := os.WriteFile(path, []byte("ingestor:\n  sources: [\"a:30003\", \"b:30003\"]\n"), 0o600); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+	select {
+	case cfg := <-applied:
+		if len(cfg.Ingestor.Sources) != 2 {
+			t.Errorf("Expected 2 sources, got %v", cfg.Ingestor.Sources)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected config to be applied after file change")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Watch did not return when context was cancelled")
+	}
+}
+
+func TestMaskedByEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := "nats:\n  url: nats://file:4222\nredis:\n  db: 1\ningestor:\n  sources: [\"a:30003\"]\n"
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected []string
+	}{
+		{name: "no environment", env: nil, expected: nil},
+		{name: "value not set in the file", env: map[string]string{"SHUTDOWN_TIMEOUT": "1m"}, expected: nil},
+		{name: "same value as the file", env: map[string]string{"NATS_URL": "nats://file:4222"}, expected: nil},
+		{
+			name:     "values set in the file",
+			env:      map[string]string{"SOURCES": "b:30003", "NATS_URL": "nats://env:4222", "REDIS_DB": "2"},
+			expected: []string{"NATS_URL", "REDIS_DB", "SOURCES"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			masked, err := maskedByEnv(path)
+			if err != nil {
+				t.Fatalf("maskedByEnv() failed: %v", err)
+			}
+			if !slices.Equal(masked, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, masked)
+			}
+		})
+	}
+}
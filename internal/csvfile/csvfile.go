@@ -0,0 +1,108 @@
+package csvfile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadAll reads every record of a comma or semicolon separated file,
+// transparently decompressing files ending in .gz
+func ReadAll(path string) ([][]string, error) {
+	//nolint:gosec // path comes from operator configuration
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil {
+			fmt.Fprintf(os.Stderr, "error closing file: %v\n", cerr)
+		}
+	}()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+		}
+		defer func() {
+			if cerr := gz.Close(); cerr != nil {
+				fmt.Fprintf(os.Stderr, "error closing gzip reader: %v\n", cerr)
+			}
+		}()
+		r = gz
+	}
+
+	return Parse(r)
+}
+
+// Parse reads every record from r, detecting the delimiter from the first line
+func Parse(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = detectDelimiter(string(first))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse data: %w", err)
+	}
+	return records, nil
+}
+
+// detectDelimiter picks the delimiter used on the first line of data
+func detectDelimiter(data string) rune {
+	line := data
+	if i := strings.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	if strings.Count(line, ";") > strings.Count(line, ",") {
+		return ';'
+	}
+	return ','
+}
+
+// Columns maps lower-case column names to their index in a header row
+type Columns map[string]int
+
+// NewColumns builds the column index of a header row
+func NewColumns(header []string) Columns {
+	columns := make(Columns, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, exists := columns[name]; !exists {
+			columns[name] = i
+		}
+	}
+	return columns
+}
+
+// Index returns the index of the first matching column name, or -1
+func (c Columns) Index(names ...string) int {
+	for _, name := range names {
+		if i, exists := c[name]; exists {
+			return i
+		}
+	}
+	return -1
+}
+
+// Field returns the trimmed field at index, or an empty string if out of range
+func Field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
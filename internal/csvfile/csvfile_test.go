@@ -0,0 +1,118 @@
+package csvfile
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected [][]string
+	}{
+		{
+			name:     "comma separated",
+			data:     "icao,registration\nABC123,N123AB\n",
+			expected: [][]string{{"icao", "registration"}, {"ABC123", "N123AB"}},
+		},
+		{
+			name:     "semicolon separated",
+			data:     "ABC123;N123AB;B738;00;BOEING 737-800\n",
+			expected: [][]string{{"ABC123", "N123AB", "B738", "00", "BOEING 737-800"}},
+		},
+		{
+			name:     "ragged rows",
+			data:     "a,b,c\n1,2\n",
+			expected: [][]string{{"a", "b", "c"}, {"1", "2"}},
+		},
+		{
+			name:     "empty",
+			data:     "",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := Parse(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if len(records) != len(tt.expected) {
+				t.Fatalf("Parse() returned %d records, expected %d", len(records), len(tt.expected))
+			}
+			for i := range records {
+				if strings.Join(records[i], "|") != strings.Join(tt.expected[i], "|") {
+					t.Errorf("Parse() record %d = %v, expected %v", i, records[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(plain, []byte("a,b\n1,2\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	compressed := filepath.Join(dir, "data.csv.gz")
+	f, err := os.Create(compressed)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	gz := gzip.NewWriter(f)
+	_, _ = gz.Write([]byte("a;b\n1;2\n"))
+	_ = gz.Close()
+	_ = f.Close()
+
+	for _, path := range []string{plain, compressed} {
+		records, err := ReadAll(path)
+		if err != nil {
+			t.Fatalf("ReadAll(%s) unexpected error: %v", path, err)
+		}
+		if len(records) != 2 || records[1][1] != "2" {
+			t.Errorf("ReadAll(%s) = %v", path, records)
+		}
+	}
+
+	if _, err := ReadAll(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Error("ReadAll() expected error for missing file")
+	}
+}
+
+func TestColumns(t *testing.T) {
+	columns := NewColumns([]string{"\ufeffICAO", " Registration ", "Type", "type"})
+
+	if got := columns.Index("icao24", "icao"); got != 0 {
+		t.Errorf("Index(icao) = %d, expected 0", got)
+	}
+	if got := columns.Index("registration"); got != 1 {
+		t.Errorf("Index(registration) = %d, expected 1", got)
+	}
+	if got := columns.Index("type"); got != 2 {
+		t.Errorf("Index(type) = %d, expected first match 2", got)
+	}
+	if got := columns.Index("missing"); got != -1 {
+		t.Errorf("Index(missing) = %d, expected -1", got)
+	}
+}
+
+func TestField(t *testing.T) {
+	record := []string{" a ", "b"}
+	if got := Field(record, 0); got != "a" {
+		t.Errorf("Field(0) = %q, expected %q", got, "a")
+	}
+	if got := Field(record, 5); got != "" {
+		t.Errorf("Field(5) = %q, expected empty", got)
+	}
+	if got := Field(record, -1); got != "" {
+		t.Errorf("Field(-1) = %q, expected empty", got)
+	}
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -28,12 +29,24 @@ func (c *Client) Close() error {
 	return c.db.Close()
 }
 
+// Ping checks that the database is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
 // GetActiveFlights retrieves all active flights
 func (c *Client) GetActiveFlights() ([]*types.Flight, error) {
 	query := `
 		SELECT session_id, hex_ident, callsign, started_at, ended_at,
 			first_latitude, first_longitude, last_latitude, last_longitude,
-			max_altitude, max_ground_speed
+			max_altitude, max_ground_speed,
+			COALESCE(registration, ''), COALESCE(aircraft_type, ''),
+			COALESCE(operator, ''), COALESCE(country, ''),
+			COALESCE(military, FALSE),
+			COALESCE(airline_icao, ''), COALESCE(airline_iata, ''),
+			COALESCE(origin, ''), COALESCE(destination, ''),
+			COALESCE(departure_airport, ''), COALESCE(departure_runway, ''), takeoff_at,
+			COALESCE(arrival_airport, ''), COALESCE(arrival_runway, ''), landed_at
 		FROM flights
 		WHERE ended_at IS NULL
 	`
@@ -49,14 +62,25 @@ func (c *Client) GetActiveFlights() ([]*types.Flight, error) {
 
 	var flights []*types.Flight
 	for rows.Next() {
-		var f types.Flight
+		var (
+			f         types.Flight
+			takeoffAt sql.NullTime
+			landedAt  sql.NullTime
+		)
 		if err := rows.Scan(
 			&f.SessionID, &f.HexIdent, &f.Callsign, &f.StartedAt, &f.EndedAt,
 			&f.FirstLatitude, &f.FirstLongitude, &f.LastLatitude, &f.LastLongitude,
 			&f.MaxAltitude, &f.MaxGroundSpeed,
+			&f.Registration, &f.AircraftType, &f.Operator, &f.Country,
+			&f.Military,
+			&f.AirlineICAO, &f.AirlineIATA, &f.Origin, &f.Destination,
+			&f.DepartureAirport, &f.DepartureRunway, &takeoffAt,
+			&f.ArrivalAirport, &f.ArrivalRunway, &landedAt,
 		); err != nil {
 			return nil, err
 		}
+		f.TakeoffAt = takeoffAt.Time
+		f.LandedAt = landedAt.Time
 		flights = append(flights, &f)
 	}
 	return flights, rows.Err()
@@ -68,13 +92,23 @@ func (c *Client) CreateFlight(flight *types.Flight) error {
 		INSERT INTO flights (
 			session_id, hex_ident, callsign, started_at,
 			first_latitude, first_longitude, last_latitude, last_longitude,
-			max_altitude, max_ground_speed
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			max_altitude, max_ground_speed,
+			registration, aircraft_type, operator, country, military,
+			airline_icao, airline_iata, origin, destination,
+			departure_airport, departure_runway, takeoff_at,
+			arrival_airport, arrival_runway, landed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+			$16, $17, $18, $19, $20, $21, $22, $23, $24, $25)
 	`
 	_, err := c.db.Exec(query,
 		flight.SessionID, flight.HexIdent, flight.Callsign, flight.StartedAt,
 		flight.FirstLatitude, flight.FirstLongitude, flight.LastLatitude, flight.LastLongitude,
 		flight.MaxAltitude, flight.MaxGroundSpeed,
+		flight.Registration, flight.AircraftType, flight.Operator, flight.Country,
+		flight.Military,
+		flight.AirlineICAO, flight.AirlineIATA, flight.Origin, flight.Destination,
+		flight.DepartureAirport, flight.DepartureRunway, nullTime(flight.TakeoffAt),
+		flight.ArrivalAirport, flight.ArrivalRunway, nullTime(flight.LandedAt),
 	)
 	return err
 }
@@ -85,35 +119,92 @@ func (c *Client) UpdateFlight(flight *types.Flight) error {
 		UPDATE flights SET
 			callsign = $1, ended_at = $2,
 			last_latitude = $3, last_longitude = $4,
-			max_altitude = $5, max_ground_speed = $6
-		WHERE session_id = $7
+			max_altitude = $5, max_ground_speed = $6,
+			registration = $7, aircraft_type = $8,
+			operator = $9, country = $10,
+			military = $11,
+			airline_icao = $12, airline_iata = $13,
+			origin = $14, destination = $15,
+			departure_airport = $16, departure_runway = $17, takeoff_at = $18,
+			arrival_airport = $19, arrival_runway = $20, landed_at = $21
+		WHERE session_id = $22
 	`
 	_, err := c.db.Exec(query,
 		flight.Callsign, flight.EndedAt,
 		flight.LastLatitude, flight.LastLongitude,
 		flight.MaxAltitude, flight.MaxGroundSpeed,
+		flight.Registration, flight.AircraftType,
+		flight.Operator, flight.Country,
+		flight.Military,
+		flight.AirlineICAO, flight.AirlineIATA,
+		flight.Origin, flight.Destination,
+		flight.DepartureAirport, flight.DepartureRunway, nullTime(flight.TakeoffAt),
+		flight.ArrivalAirport, flight.ArrivalRunway, nullTime(flight.LandedAt),
 		flight.SessionID,
 	)
 	return err
 }
 
+// nullTime converts a zero time to a NULL timestamp
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 // StoreAircraftState stores an aircraft state
 func (c *Client) StoreAircraftState(state *types.AircraftState) error {
 	query := `
 		INSERT INTO aircraft_states (
 			time, hex_ident, callsign, altitude, ground_speed,
 			track, latitude, longitude, vertical_rate, squawk,
-			on_ground, msg_type
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			on_ground, msg_type, source
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 	_, err := c.db.Exec(query,
 		state.Timestamp, state.HexIdent, state.Callsign, state.Altitude,
 		state.GroundSpeed, state.Track, state.Latitude, state.Longitude,
 		state.VerticalRate, state.Squawk, state.OnGround, state.MsgType,
+		state.Source,
 	)
 	return err
 }
 
+// GetAircraftStates retrieves the stored states of an aircraft for a time range
+func (c *Client) GetAircraftStates(hexIdent string, start, end time.Time) ([]*types.AircraftState, error) {
+	query := `
+		SELECT time, hex_ident, COALESCE(callsign, ''), COALESCE(altitude, 0),
+			COALESCE(ground_speed, 0), COALESCE(track, 0),
+			COALESCE(latitude, 0), COALESCE(longitude, 0),
+			COALESCE(vertical_rate, 0), COALESCE(squawk, ''),
+			COALESCE(on_ground, false), COALESCE(msg_type, 0), COALESCE(source, '')
+		FROM aircraft_states
+		WHERE hex_ident = $1 AND time BETWEEN $2 AND $3
+		ORDER BY time
+	`
+	rows, err := c.db.Query(query, hexIdent, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			fmt.Fprintf(os.Stderr, "error closing rows: %v\n", cerr)
+		}
+	}()
+
+	var states []*types.AircraftState
+	for rows.Next() {
+		var s types.AircraftState
+		if err := rows.Scan(
+			&s.Timestamp, &s.HexIdent, &s.Callsign, &s.Altitude,
+			&s.GroundSpeed, &s.Track, &s.Latitude, &s.Longitude,
+			&s.VerticalRate, &s.Squawk, &s.OnGround, &s.MsgType, &s.Source,
+		); err != nil {
+			return nil, err
+		}
+		states = append(states, &s)
+	}
+	return states, rows.Err()
+}
+
 // StoreSystemStats stores system statistics
 func (c *Client) StoreSystemStats(stats map[string]interface{}) error {
 	query := `
@@ -255,3 +346,145 @@ func (c *Client) GetSystemStats(start, end time.Time) ([]map[string]interface{},
 
 	return stats, rows.Err()
 }
+
+// GetFlightsPerCountry retrieves the number of flights per country of registration per day
+func (c *Client) GetFlightsPerCountry(start, end time.Time) ([]map[string]interface{}, error) {
+	query := `
+		SELECT day, country, flights, military_flights
+		FROM flights_per_country_daily
+		WHERE day BETWEEN $1 AND $2
+		ORDER BY day DESC, flights DESC
+	`
+
+	rows, err := c.db.Query(query, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			fmt.Fprintf(os.Stderr, "error closing rows: %v\n", cerr)
+		}
+	}()
+
+	var stats []map[string]interface{}
+	for rows.Next() {
+		var (
+			day             time.Time
+			country         string
+			flights         int64
+			militaryFlights int64
+		)
+		if err := rows.Scan(&day, &country, &flights, &militaryFlights); err != nil {
+			return nil, err
+		}
+		stats = append(stats, map[string]interface{}{
+			"day":              day,
+			"country":          country,
+			"flights":          flights,
+			"military_flights": militaryFlights,
+		})
+	}
+
+	return stats, rows.Err()
+}
+
+// StoreAlert stores an alert in the alert history
+func (c *Client) StoreAlert(alert *types.Alert) error {
+	query := `
+		INSERT INTO alerts (
+			time, session_id, hex_ident, callsign, type, severity,
+			squawk, message, latitude, longitude, altitude
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err := c.db.Exec(query,
+		alert.Time, alert.SessionID, alert.HexIdent, alert.Callsign, alert.Type, alert.Severity,
+		alert.Squawk, alert.Message, alert.Latitude, alert.Longitude, alert.Altitude,
+	)
+	return err
+}
+
+// GetAlerts retrieves the alerts raised in a time range
+func (c *Client) GetAlerts(start, end time.Time) ([]*types.Alert, error) {
+	query := `
+		SELECT time, COALESCE(session_id, ''), hex_ident, COALESCE(callsign, ''),
+			type, severity, COALESCE(squawk, ''), COALESCE(message, ''),
+			COALESCE(latitude, 0), COALESCE(longitude, 0), COALESCE(altitude, 0)
+		FROM alerts
+		WHERE time BETWEEN $1 AND $2
+		ORDER BY time DESC
+	`
+
+	rows, err := c.db.Query(query, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			fmt.Fprintf(os.Stderr, "error closing rows: %v\n", cerr)
+		}
+	}()
+
+	var alerts []*types.Alert
+	for rows.Next() {
+		var a types.Alert
+		if err := rows.Scan(
+			&a.Time, &a.SessionID, &a.HexIdent, &a.Callsign,
+			&a.Type, &a.Severity, &a.Squawk, &a.Message,
+			&a.Latitude, &a.Longitude, &a.Altitude,
+		); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, &a)
+	}
+	return alerts, rows.Err()
+}
+
+// StoreGeofenceEvent stores a geofence enter, exit or dwell event
+func (c *Client) StoreGeofenceEvent(event *types.GeofenceEvent) error {
+	query := `
+		INSERT INTO geofence_events (
+			time, session_id, hex_ident, callsign, type, fence,
+			latitude, longitude, altitude
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := c.db.Exec(query,
+		event.Time, event.SessionID, event.HexIdent, event.Callsign, event.Type, event.Fence,
+		event.Latitude, event.Longitude, event.Altitude,
+	)
+	return err
+}
+
+// GetGeofenceEvents retrieves the geofence events of a fence in a time range,
+// or of every fence when fence is empty
+func (c *Client) GetGeofenceEvents(fence string, start, end time.Time) ([]*types.GeofenceEvent, error) {
+	query := `
+		SELECT time, COALESCE(session_id, ''), hex_ident, COALESCE(callsign, ''),
+			type, fence, COALESCE(latitude, 0), COALESCE(longitude, 0), COALESCE(altitude, 0)
+		FROM geofence_events
+		WHERE time BETWEEN $1 AND $2 AND ($3 = '' OR fence = $3)
+		ORDER BY time DESC
+	`
+
+	rows, err := c.db.Query(query, start, end, fence)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			fmt.Fprintf(os.Stderr, "error closing rows: %v\n", cerr)
+		}
+	}()
+
+	var events []*types.GeofenceEvent
+	for rows.Next() {
+		var e types.GeofenceEvent
+		if err := rows.Scan(
+			&e.Time, &e.SessionID, &e.HexIdent, &e.Callsign,
+			&e.Type, &e.Fence, &e.Latitude, &e.Longitude, &e.Altitude,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
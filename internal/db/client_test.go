@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -57,6 +59,29 @@ func TestNew_Unit(t *testing.T) {
 	}
 }
 
+func TestClient_Ping_Unit(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectPing()
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	client := &Client{db: db}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() should not fail: %v", err)
+	}
+	if err := client.Ping(context.Background()); err == nil {
+		t.Error("Expected Ping() to fail")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
 func TestClient_Close_Unit(t *testing.T) {
 	// Test with proper mock DB
 	db, mock, err := sqlmock.New()
@@ -95,15 +120,14 @@ func TestClient_GetActiveFlights_Unit(t *testing.T) {
 					"session_id", "hex_ident", "callsign", "started_at", "ended_at",
 					"first_latitude", "first_longitude", "last_latitude", "last_longitude",
 					"max_altitude", "max_ground_speed",
+					"registration", "aircraft_type", "operator", "country", "military",
+					"airline_icao", "airline_iata", "origin", "destination",
+					"departure_airport", "departure_runway", "takeoff_at", "arrival_airport", "arrival_runway", "landed_at",
 				}).
-					AddRow("session1", "ABC123", "TEST123", time.Now(), endTime, 40.7128, -74.0060, 41.0000, -75.0000, 35000, 450.5).
-					AddRow("session2", "DEF456", "TEST456", time.Now(), endTime, 42.0000, -73.0000, 43.0000, -72.0000, 30000, 400.0)
-
-				mock.ExpectQuery(`SELECT session_id, hex_ident, callsign, started_at, ended_at,
-			first_latitude, first_longitude, last_latitude, last_longitude,
-			max_altitude, max_ground_speed
-		FROM flights
-		WHERE ended_at IS NULL`).
+					AddRow("session1", "ABC123", "TEST123", time.Now(), endTime, 40.7128, -74.0060, 41.0000, -75.0000, 35000, 450.5, "N123AB", "B738", "Example Airlines", "United States", false, "TAM", "JJ", "SBGR", "SBRJ", "SBGR", "10L", time.Now(), "", "", nil).
+					AddRow("session2", "DEF456", "TEST456", time.Now(), endTime, 42.0000, -73.0000, 43.0000, -72.0000, 30000, 400.0, "", "", "", "", true, "", "", "", "", "", "", nil, "", "", nil)
+
+				mock.ExpectQuery(`SELECT session_id, hex_ident, callsign, started_at, ended_at`).
 					WillReturnRows(rows)
 			},
 			expectError:   false,
@@ -116,13 +140,12 @@ func TestClient_GetActiveFlights_Unit(t *testing.T) {
 					"session_id", "hex_ident", "callsign", "started_at", "ended_at",
 					"first_latitude", "first_longitude", "last_latitude", "last_longitude",
 					"max_altitude", "max_ground_speed",
+					"registration", "aircraft_type", "operator", "country", "military",
+					"airline_icao", "airline_iata", "origin", "destination",
+					"departure_airport", "departure_runway", "takeoff_at", "arrival_airport", "arrival_runway", "landed_at",
 				})
 
-				mock.ExpectQuery(`SELECT session_id, hex_ident, callsign, started_at, ended_at,
-			first_latitude, first_longitude, last_latitude, last_longitude,
-			max_altitude, max_ground_speed
-		FROM flights
-		WHERE ended_at IS NULL`).
+				mock.ExpectQuery(`SELECT session_id, hex_ident, callsign, started_at, ended_at`).
 					WillReturnRows(rows)
 			},
 			expectError:   false,
@@ -131,11 +154,7 @@ func TestClient_GetActiveFlights_Unit(t *testing.T) {
 		{
 			name: "database query error",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT session_id, hex_ident, callsign, started_at, ended_at,
-			first_latitude, first_longitude, last_latitude, last_longitude,
-			max_altitude, max_ground_speed
-		FROM flights
-		WHERE ended_at IS NULL`).
+				mock.ExpectQuery(`SELECT session_id, hex_ident, callsign, started_at, ended_at`).
 					WillReturnError(sql.ErrConnDone)
 			},
 			expectError: true,
@@ -148,15 +167,14 @@ func TestClient_GetActiveFlights_Unit(t *testing.T) {
 					"session_id", "hex_ident", "callsign", "started_at", "ended_at",
 					"first_latitude", "first_longitude", "last_latitude", "last_longitude",
 					"max_altitude", "max_ground_speed",
+					"registration", "aircraft_type", "operator", "country", "military",
+					"airline_icao", "airline_iata", "origin", "destination",
+					"departure_airport", "departure_runway", "takeoff_at", "arrival_airport", "arrival_runway", "landed_at",
 				}).
-					AddRow("session1", "ABC123", "TEST123", time.Now(), endTime, 40.7128, -74.0060, 41.0000, -75.0000, 35000, 450.5).
+					AddRow("session1", "ABC123", "TEST123", time.Now(), endTime, 40.7128, -74.0060, 41.0000, -75.0000, 35000, 450.5, "N123AB", "B738", "Example Airlines", "United States", false, "TAM", "JJ", "SBGR", "SBRJ", "", "", nil, "", "", nil).
 					RowError(0, sql.ErrNoRows)
 
-				mock.ExpectQuery(`SELECT session_id, hex_ident, callsign, started_at, ended_at,
-			first_latitude, first_longitude, last_latitude, last_longitude,
-			max_altitude, max_ground_speed
-		FROM flights
-		WHERE ended_at IS NULL`).
+				mock.ExpectQuery(`SELECT session_id, hex_ident, callsign, started_at, ended_at`).
 					WillReturnRows(rows)
 			},
 			expectError: true,
@@ -205,6 +223,18 @@ func TestClient_CreateFlight_Unit(t *testing.T) {
 		LastLongitude:  -75.0000,
 		MaxAltitude:    35000,
 		MaxGroundSpeed: 450.5,
+		Registration:   "N123AB",
+		AircraftType:   "B738",
+		Operator:       "Example Airlines",
+		Country:        "United States",
+		AirlineICAO:    "TAM",
+		AirlineIATA:    "JJ",
+		Origin:         "SBGR",
+		Destination:    "SBRJ",
+
+		DepartureAirport: "SBGR",
+		DepartureRunway:  "10L",
+		TakeoffAt:        time.Now(),
 	}
 
 	tests := []struct {
@@ -216,7 +246,7 @@ func TestClient_CreateFlight_Unit(t *testing.T) {
 			name: "successful flight creation",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec(`INSERT INTO flights`).
-					WithArgs("test-session", "ABC123", "TEST123", sqlmock.AnyArg(), 40.7128, -74.0060, 41.0000, -75.0000, 35000, 450.5).
+					WithArgs("test-session", "ABC123", "TEST123", sqlmock.AnyArg(), 40.7128, -74.0060, 41.0000, -75.0000, 35000, 450.5, "N123AB", "B738", "Example Airlines", "United States", false, "TAM", "JJ", "SBGR", "SBRJ", "SBGR", "10L", sqlmock.AnyArg(), "", "", nil).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			expectError: false,
@@ -225,7 +255,7 @@ func TestClient_CreateFlight_Unit(t *testing.T) {
 			name: "database execution error",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec(`INSERT INTO flights`).
-					WithArgs("test-session", "ABC123", "TEST123", sqlmock.AnyArg(), 40.7128, -74.0060, 41.0000, -75.0000, 35000, 450.5).
+					WithArgs("test-session", "ABC123", "TEST123", sqlmock.AnyArg(), 40.7128, -74.0060, 41.0000, -75.0000, 35000, 450.5, "N123AB", "B738", "Example Airlines", "United States", false, "TAM", "JJ", "SBGR", "SBRJ", "SBGR", "10L", sqlmock.AnyArg(), "", "", nil).
 					WillReturnError(sql.ErrConnDone)
 			},
 			expectError: true,
@@ -269,6 +299,12 @@ func TestClient_UpdateFlight_Unit(t *testing.T) {
 		LastLongitude:  -76.0000,
 		MaxAltitude:    40000,
 		MaxGroundSpeed: 500.0,
+		Registration:   "N123AB",
+		AircraftType:   "B738",
+
+		ArrivalAirport: "SBRJ",
+		ArrivalRunway:  "02R",
+		LandedAt:       endTime,
 	}
 
 	tests := []struct {
@@ -280,7 +316,7 @@ func TestClient_UpdateFlight_Unit(t *testing.T) {
 			name: "successful flight update",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec(`UPDATE flights SET`).
-					WithArgs("UPDATED123", endTime, 42.0000, -76.0000, 40000, 500.0, "test-session").
+					WithArgs("UPDATED123", endTime, 42.0000, -76.0000, 40000, 500.0, "N123AB", "B738", "", "", false, "", "", "", "", "", "", nil, "SBRJ", "02R", sqlmock.AnyArg(), "test-session").
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			expectError: false,
@@ -289,7 +325,7 @@ func TestClient_UpdateFlight_Unit(t *testing.T) {
 			name: "database execution error",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec(`UPDATE flights SET`).
-					WithArgs("UPDATED123", endTime, 42.0000, -76.0000, 40000, 500.0, "test-session").
+					WithArgs("UPDATED123", endTime, 42.0000, -76.0000, 40000, 500.0, "N123AB", "B738", "", "", false, "", "", "", "", "", "", nil, "SBRJ", "02R", sqlmock.AnyArg(), "test-session").
 					WillReturnError(sql.ErrConnDone)
 			},
 			expectError: true,
@@ -338,6 +374,7 @@ func TestClient_StoreAircraftState_Unit(t *testing.T) {
 		OnGround:     false,
 		MsgType:      8,
 		Timestamp:    timestamp,
+		Source:       "receiver-1",
 	}
 
 	tests := []struct {
@@ -349,7 +386,7 @@ func TestClient_StoreAircraftState_Unit(t *testing.T) {
 			name: "successful aircraft state storage",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec(`INSERT INTO aircraft_states`).
-					WithArgs(timestamp, "ABC123", "TEST123", 35000, 450.5, 180.0, 40.7128, -74.0060, 1000, "1234", false, 8).
+					WithArgs(timestamp, "ABC123", "TEST123", 35000, 450.5, 180.0, 40.7128, -74.0060, 1000, "1234", false, 8, "receiver-1").
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			expectError: false,
@@ -358,7 +395,7 @@ func TestClient_StoreAircraftState_Unit(t *testing.T) {
 			name: "database execution error",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec(`INSERT INTO aircraft_states`).
-					WithArgs(timestamp, "ABC123", "TEST123", 35000, 450.5, 180.0, 40.7128, -74.0060, 1000, "1234", false, 8).
+					WithArgs(timestamp, "ABC123", "TEST123", 35000, 450.5, 180.0, 40.7128, -74.0060, 1000, "1234", false, 8, "receiver-1").
 					WillReturnError(sql.ErrConnDone)
 			},
 			expectError: true,
@@ -392,6 +429,79 @@ func TestClient_StoreAircraftState_Unit(t *testing.T) {
 	}
 }
 
+func TestClient_GetAircraftStates_Unit(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now()
+	columns := []string{
+		"time", "hex_ident", "callsign", "altitude", "ground_speed", "track",
+		"latitude", "longitude", "vertical_rate", "squawk", "on_ground", "msg_type", "source",
+	}
+
+	tests := []struct {
+		name           string
+		setupMock      func(sqlmock.Sqlmock)
+		expectError    bool
+		expectedSource []string
+	}{
+		{
+			name: "states with sources",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(start, "ABC123", "TEST123", 35000, 450.5, 180.0, 40.7128, -74.0060, 0, "1234", false, 8, "receiver-1").
+					AddRow(end, "ABC123", "TEST123", 35100, 451.0, 181.0, 40.8, -74.1, 0, "1234", false, 8, "receiver-2")
+				mock.ExpectQuery(`SELECT time, hex_ident`).
+					WithArgs("ABC123", start, end).
+					WillReturnRows(rows)
+			},
+			expectError:    false,
+			expectedSource: []string{"receiver-1", "receiver-2"},
+		},
+		{
+			name: "database query error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT time, hex_ident`).
+					WithArgs("ABC123", start, end).
+					WillReturnError(sql.ErrConnDone)
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create mock DB: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			client := &Client{db: db}
+			states, err := client.GetAircraftStates("ABC123", start, end)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if len(states) != len(tt.expectedSource) {
+				t.Fatalf("Expected %d states, got %d", len(tt.expectedSource), len(states))
+			}
+			for i, source := range tt.expectedSource {
+				if states[i].Source != source {
+					t.Errorf("Expected state %d source %q, got %q", i, source, states[i].Source)
+				}
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
 func TestClient_StoreSystemStats_Unit(t *testing.T) {
 	lastMessageTime := time.Now()
 	stats := map[string]interface{}{
@@ -489,6 +599,233 @@ func TestClient_StoreSystemStats_Unit(t *testing.T) {
 	}
 }
 
+func TestClient_GetFlightsPerCountry_Unit(t *testing.T) {
+	start := time.Now().Add(-7 * 24 * time.Hour)
+	end := time.Now()
+	day := time.Now().Truncate(24 * time.Hour)
+
+	tests := []struct {
+		name          string
+		setupMock     func(sqlmock.Sqlmock)
+		expectError   bool
+		expectedCount int
+	}{
+		{
+			name: "flights per country",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"day", "country", "flights", "military_flights"}).
+					AddRow(day, "Brazil", int64(42), int64(3)).
+					AddRow(day, "Unknown", int64(5), int64(0))
+				mock.ExpectQuery(`SELECT day, country, flights, military_flights`).
+					WithArgs(start, end).
+					WillReturnRows(rows)
+			},
+			expectError:   false,
+			expectedCount: 2,
+		},
+		{
+			name: "database query error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT day, country, flights, military_flights`).
+					WithArgs(start, end).
+					WillReturnError(sql.ErrConnDone)
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create mock DB: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			client := &Client{db: db}
+			stats, err := client.GetFlightsPerCountry(start, end)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if len(stats) != tt.expectedCount {
+				t.Fatalf("Expected %d rows, got %d", tt.expectedCount, len(stats))
+			}
+			if tt.expectedCount > 0 {
+				if stats[0]["country"] != "Brazil" || stats[0]["flights"] != int64(42) || stats[0]["military_flights"] != int64(3) {
+					t.Errorf("Unexpected first row: %v", stats[0])
+				}
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_StoreAlert_Unit(t *testing.T) {
+	alert := &types.Alert{
+		Time:      time.Now(),
+		Type:      "emergency_squawk",
+		Severity:  "critical",
+		HexIdent:  "E48D4E",
+		Callsign:  "TAM3456",
+		SessionID: "session-1",
+		Squawk:    "7700",
+		Message:   "Squawk 7700 (general emergency)",
+		Latitude:  -23.4,
+		Longitude: -46.5,
+		Altitude:  12000,
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO alerts`).
+		WithArgs(sqlmock.AnyArg(), "session-1", "E48D4E", "TAM3456", "emergency_squawk", "critical",
+			"7700", "Squawk 7700 (general emergency)", -23.4, -46.5, 12000).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO alerts`).
+		WillReturnError(sql.ErrConnDone)
+
+	client := &Client{db: db}
+	if err := client.StoreAlert(alert); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if err := client.StoreAlert(alert); err == nil {
+		t.Error("Expected error, got none")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestClient_GetAlerts_Unit(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{
+		"time", "session_id", "hex_ident", "callsign", "type", "severity",
+		"squawk", "message", "latitude", "longitude", "altitude",
+	}).AddRow(end, "session-1", "E48D4E", "TAM3456", "emergency_squawk", "critical", "7700", "Squawk 7700", -23.4, -46.5, 12000)
+	mock.ExpectQuery(`SELECT time, COALESCE\(session_id`).
+		WithArgs(start, end).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT time, COALESCE\(session_id`).
+		WithArgs(start, end).
+		WillReturnError(sql.ErrConnDone)
+
+	client := &Client{db: db}
+	alerts, err := client.GetAlerts(start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].SessionID != "session-1" || alerts[0].Squawk != "7700" {
+		t.Errorf("Unexpected alerts: %+v", alerts)
+	}
+
+	if _, err := client.GetAlerts(start, end); err == nil {
+		t.Error("Expected error, got none")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestClient_StoreGeofenceEvent_Unit(t *testing.T) {
+	event := &types.GeofenceEvent{
+		Time:      time.Now(),
+		Type:      "enter",
+		Fence:     "noise",
+		HexIdent:  "E48D4E",
+		Callsign:  "TAM3456",
+		SessionID: "session-1",
+		Latitude:  -23.4,
+		Longitude: -46.5,
+		Altitude:  2500,
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO geofence_events`).
+		WithArgs(sqlmock.AnyArg(), "session-1", "E48D4E", "TAM3456", "enter", "noise", -23.4, -46.5, 2500).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO geofence_events`).
+		WillReturnError(sql.ErrConnDone)
+
+	client := &Client{db: db}
+	if err := client.StoreGeofenceEvent(event); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if err := client.StoreGeofenceEvent(event); err == nil {
+		t.Error("Expected error, got none")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestClient_GetGeofenceEvents_Unit(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{
+		"time", "session_id", "hex_ident", "callsign", "type", "fence",
+		"latitude", "longitude", "altitude",
+	}).AddRow(end, "session-1", "E48D4E", "TAM3456", "dwell", "noise", -23.4, -46.5, 2500)
+	mock.ExpectQuery(`SELECT time, COALESCE\(session_id`).
+		WithArgs(start, end, "noise").
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT time, COALESCE\(session_id`).
+		WithArgs(start, end, "").
+		WillReturnError(sql.ErrConnDone)
+
+	client := &Client{db: db}
+	events, err := client.GetGeofenceEvents("noise", start, end)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != "dwell" || events[0].Fence != "noise" || events[0].SessionID != "session-1" {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+
+	if _, err := client.GetGeofenceEvents("", start, end); err == nil {
+		t.Error("Expected error, got none")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
 func TestClient_GetSystemStats_Unit(t *testing.T) {
 	start := time.Now().Add(-time.Hour)
 	end := time.Now()
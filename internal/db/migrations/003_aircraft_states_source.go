@@ -0,0 +1,14 @@
+package migrations
+
+// AircraftStatesSource indexes the receiver that produced each aircraft state
+var AircraftStatesSource = &Migration{
+	ID:   "003_aircraft_states_source",
+	Name: "003_aircraft_states_source",
+	UpSQL: `
+	-- Index the source column so states can be audited per receiver
+	CREATE INDEX IF NOT EXISTS idx_aircraft_states_source ON aircraft_states (source, time DESC);
+	`,
+	DownSQL: `
+	DROP INDEX IF EXISTS idx_aircraft_states_source;
+	`,
+}
@@ -0,0 +1,25 @@
+package migrations
+
+// FlightAircraftInfo adds aircraft registry attributes to flights
+var FlightAircraftInfo = &Migration{
+	ID:   "004_flight_aircraft_info",
+	Name: "004_flight_aircraft_info",
+	UpSQL: `
+	-- Aircraft attributes from the local aircraft database
+	ALTER TABLE flights
+		ADD COLUMN IF NOT EXISTS registration TEXT,
+		ADD COLUMN IF NOT EXISTS aircraft_type TEXT,
+		ADD COLUMN IF NOT EXISTS operator TEXT,
+		ADD COLUMN IF NOT EXISTS country TEXT;
+
+	CREATE INDEX IF NOT EXISTS idx_flights_registration ON flights (registration);
+	`,
+	DownSQL: `
+	DROP INDEX IF EXISTS idx_flights_registration;
+	ALTER TABLE flights
+		DROP COLUMN IF EXISTS registration,
+		DROP COLUMN IF EXISTS aircraft_type,
+		DROP COLUMN IF EXISTS operator,
+		DROP COLUMN IF EXISTS country;
+	`,
+}
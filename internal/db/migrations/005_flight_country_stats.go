@@ -0,0 +1,28 @@
+package migrations
+
+// FlightCountryStats adds the military flag to flights and a per-country daily view
+var FlightCountryStats = &Migration{
+	ID:   "005_flight_country_stats",
+	Name: "005_flight_country_stats",
+	UpSQL: `
+	-- Military flag derived from the ICAO address allocation
+	ALTER TABLE flights ADD COLUMN IF NOT EXISTS military BOOLEAN NOT NULL DEFAULT FALSE;
+
+	CREATE INDEX IF NOT EXISTS idx_flights_country ON flights (country, started_at DESC);
+
+	-- Flights per country of registration per day
+	CREATE OR REPLACE VIEW flights_per_country_daily AS
+	SELECT
+		date_trunc('day', started_at) AS day,
+		COALESCE(NULLIF(country, ''), 'Unknown') AS country,
+		COUNT(*) AS flights,
+		COUNT(*) FILTER (WHERE military) AS military_flights
+	FROM flights
+	GROUP BY 1, 2;
+	`,
+	DownSQL: `
+	DROP VIEW IF EXISTS flights_per_country_daily;
+	DROP INDEX IF EXISTS idx_flights_country;
+	ALTER TABLE flights DROP COLUMN IF EXISTS military;
+	`,
+}
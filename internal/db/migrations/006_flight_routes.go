@@ -0,0 +1,25 @@
+package migrations
+
+// FlightRoutes adds the decoded airline and route to flights
+var FlightRoutes = &Migration{
+	ID:   "006_flight_routes",
+	Name: "006_flight_routes",
+	UpSQL: `
+	-- Airline designators and route decoded from the callsign
+	ALTER TABLE flights
+		ADD COLUMN IF NOT EXISTS airline_icao TEXT,
+		ADD COLUMN IF NOT EXISTS airline_iata TEXT,
+		ADD COLUMN IF NOT EXISTS origin TEXT,
+		ADD COLUMN IF NOT EXISTS destination TEXT;
+
+	CREATE INDEX IF NOT EXISTS idx_flights_airline_icao ON flights (airline_icao, started_at DESC);
+	`,
+	DownSQL: `
+	DROP INDEX IF EXISTS idx_flights_airline_icao;
+	ALTER TABLE flights
+		DROP COLUMN IF EXISTS airline_icao,
+		DROP COLUMN IF EXISTS airline_iata,
+		DROP COLUMN IF EXISTS origin,
+		DROP COLUMN IF EXISTS destination;
+	`,
+}
@@ -0,0 +1,31 @@
+package migrations
+
+// FlightAirports adds the detected departure and arrival to flights
+var FlightAirports = &Migration{
+	ID:   "007_flight_airports",
+	Name: "007_flight_airports",
+	UpSQL: `
+	-- Departure and arrival detected from on-ground transitions
+	ALTER TABLE flights
+		ADD COLUMN IF NOT EXISTS departure_airport TEXT,
+		ADD COLUMN IF NOT EXISTS departure_runway TEXT,
+		ADD COLUMN IF NOT EXISTS takeoff_at TIMESTAMPTZ,
+		ADD COLUMN IF NOT EXISTS arrival_airport TEXT,
+		ADD COLUMN IF NOT EXISTS arrival_runway TEXT,
+		ADD COLUMN IF NOT EXISTS landed_at TIMESTAMPTZ;
+
+	CREATE INDEX IF NOT EXISTS idx_flights_departure_airport ON flights (departure_airport, takeoff_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_flights_arrival_airport ON flights (arrival_airport, landed_at DESC);
+	`,
+	DownSQL: `
+	DROP INDEX IF EXISTS idx_flights_arrival_airport;
+	DROP INDEX IF EXISTS idx_flights_departure_airport;
+	ALTER TABLE flights
+		DROP COLUMN IF EXISTS departure_airport,
+		DROP COLUMN IF EXISTS departure_runway,
+		DROP COLUMN IF EXISTS takeoff_at,
+		DROP COLUMN IF EXISTS arrival_airport,
+		DROP COLUMN IF EXISTS arrival_runway,
+		DROP COLUMN IF EXISTS landed_at;
+	`,
+}
@@ -0,0 +1,32 @@
+package migrations
+
+// Alerts adds the alert history table
+var Alerts = &Migration{
+	ID:   "008_alerts",
+	Name: "008_alerts",
+	UpSQL: `
+	-- Alerts raised by the tracker rules
+	CREATE TABLE IF NOT EXISTS alerts (
+		time TIMESTAMPTZ NOT NULL,
+		session_id TEXT,
+		hex_ident TEXT NOT NULL,
+		callsign TEXT,
+		type TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		squawk TEXT,
+		message TEXT,
+		latitude DOUBLE PRECISION,
+		longitude DOUBLE PRECISION,
+		altitude INTEGER
+	);
+
+	SELECT create_hypertable('alerts', 'time', if_not_exists => TRUE);
+
+	CREATE INDEX IF NOT EXISTS idx_alerts_hex_ident ON alerts (hex_ident, time DESC);
+	CREATE INDEX IF NOT EXISTS idx_alerts_session_id ON alerts (session_id);
+	CREATE INDEX IF NOT EXISTS idx_alerts_type ON alerts (type, time DESC);
+	`,
+	DownSQL: `
+	DROP TABLE IF EXISTS alerts;
+	`,
+}
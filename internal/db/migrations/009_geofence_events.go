@@ -0,0 +1,30 @@
+package migrations
+
+// GeofenceEvents adds the geofence event history table
+var GeofenceEvents = &Migration{
+	ID:   "009_geofence_events",
+	Name: "009_geofence_events",
+	UpSQL: `
+	-- Geofence enter, exit and dwell events raised by the tracker
+	CREATE TABLE IF NOT EXISTS geofence_events (
+		time TIMESTAMPTZ NOT NULL,
+		session_id TEXT,
+		hex_ident TEXT NOT NULL,
+		callsign TEXT,
+		type TEXT NOT NULL,
+		fence TEXT NOT NULL,
+		latitude DOUBLE PRECISION,
+		longitude DOUBLE PRECISION,
+		altitude INTEGER
+	);
+
+	SELECT create_hypertable('geofence_events', 'time', if_not_exists => TRUE);
+
+	CREATE INDEX IF NOT EXISTS idx_geofence_events_fence ON geofence_events (fence, time DESC);
+	CREATE INDEX IF NOT EXISTS idx_geofence_events_hex_ident ON geofence_events (hex_ident, time DESC);
+	CREATE INDEX IF NOT EXISTS idx_geofence_events_session_id ON geofence_events (session_id);
+	`,
+	DownSQL: `
+	DROP TABLE IF EXISTS geofence_events;
+	`,
+}
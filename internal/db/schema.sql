@@ -24,6 +24,7 @@ SELECT create_hypertable('aircraft_states', 'time');
 -- Create indexes
 CREATE INDEX IF NOT EXISTS idx_aircraft_states_hex_ident ON aircraft_states (hex_ident);
 CREATE INDEX IF NOT EXISTS idx_aircraft_states_callsign ON aircraft_states (callsign);
+CREATE INDEX IF NOT EXISTS idx_aircraft_states_source ON aircraft_states (source, time DESC);
 
 -- Create flights table
 CREATE TABLE IF NOT EXISTS flights (
@@ -37,13 +38,87 @@ CREATE TABLE IF NOT EXISTS flights (
     last_latitude DOUBLE PRECISION,
     last_longitude DOUBLE PRECISION,
     max_altitude INTEGER,
-    max_ground_speed INTEGER
+    max_ground_speed INTEGER,
+    registration TEXT,
+    aircraft_type TEXT,
+    operator TEXT,
+    country TEXT,
+    military BOOLEAN NOT NULL DEFAULT FALSE,
+    airline_icao TEXT,
+    airline_iata TEXT,
+    origin TEXT,
+    destination TEXT,
+    departure_airport TEXT,
+    departure_runway TEXT,
+    takeoff_at TIMESTAMPTZ,
+    arrival_airport TEXT,
+    arrival_runway TEXT,
+    landed_at TIMESTAMPTZ
 );
 
 -- Create indexes for flights
 CREATE INDEX IF NOT EXISTS idx_flights_hex_ident ON flights (hex_ident);
 CREATE INDEX IF NOT EXISTS idx_flights_started_at ON flights (started_at);
 CREATE INDEX IF NOT EXISTS idx_flights_ended_at ON flights (ended_at);
+CREATE INDEX IF NOT EXISTS idx_flights_registration ON flights (registration);
+CREATE INDEX IF NOT EXISTS idx_flights_country ON flights (country, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_flights_airline_icao ON flights (airline_icao, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_flights_departure_airport ON flights (departure_airport, takeoff_at DESC);
+CREATE INDEX IF NOT EXISTS idx_flights_arrival_airport ON flights (arrival_airport, landed_at DESC);
+
+-- Create view of flights per country of registration per day
+CREATE OR REPLACE VIEW flights_per_country_daily AS
+SELECT
+    date_trunc('day', started_at) AS day,
+    COALESCE(NULLIF(country, ''), 'Unknown') AS country,
+    COUNT(*) AS flights,
+    COUNT(*) FILTER (WHERE military) AS military_flights
+FROM flights
+GROUP BY 1, 2;
+
+-- Create alerts table
+CREATE TABLE IF NOT EXISTS alerts (
+    time TIMESTAMPTZ NOT NULL,
+    session_id TEXT,
+    hex_ident TEXT NOT NULL,
+    callsign TEXT,
+    type TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    squawk TEXT,
+    message TEXT,
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    altitude INTEGER
+);
+
+-- Create hypertable for alerts
+SELECT create_hypertable('alerts', 'time');
+
+-- Create indexes for alerts
+CREATE INDEX IF NOT EXISTS idx_alerts_hex_ident ON alerts (hex_ident, time DESC);
+CREATE INDEX IF NOT EXISTS idx_alerts_session_id ON alerts (session_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_type ON alerts (type, time DESC);
+
+-- Create geofence events table
+CREATE TABLE IF NOT EXISTS geofence_events (
+    time TIMESTAMPTZ NOT NULL,
+    session_id TEXT,
+    hex_ident TEXT NOT NULL,
+    callsign TEXT,
+    type TEXT NOT NULL,
+    fence TEXT NOT NULL,
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    altitude INTEGER
+);
+
+-- Create hypertable for geofence events
+SELECT create_hypertable('geofence_events', 'time');
+
+-- Create indexes for geofence events
+CREATE INDEX IF NOT EXISTS idx_geofence_events_fence ON geofence_events (fence, time DESC);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_hex_ident ON geofence_events (hex_ident, time DESC);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_session_id ON geofence_events (session_id);
 
 -- Create statistics table
 CREATE TABLE IF NOT EXISTS system_stats (
@@ -0,0 +1,228 @@
+package dedup
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+// DefaultWindow is the default time window in which identical messages are merged
+const DefaultWindow = 2 * time.Second
+
+// ReceiverStats holds per-receiver deduplication counters
+type ReceiverStats struct {
+	Messages   uint64 `json:"messages"`   // Messages heard by the receiver
+	Duplicates uint64 `json:"duplicates"` // Messages already heard by another receiver
+	Unique     uint64 `json:"unique"`     // Messages heard only by this receiver
+
+	// Messages this receiver heard along with each other receiver
+	SharedWith map[string]uint64 `json:"shared_with,omitempty"`
+}
+
+// entry tracks a single message heard by one or more receivers
+type entry struct {
+	key       string
+	firstSeen time.Time
+	sources   []string
+}
+
+// Deduplicator merges identical messages heard by several receivers
+type Deduplicator struct {
+	window    time.Duration
+	entries   map[string]*entry
+	queue     []*entry // Entries in arrival order, used for expiry
+	receivers map[string]*ReceiverStats
+	latest    time.Time
+	mu        sync.Mutex
+}
+
+// New creates a new Deduplicator with the given time window
+func New(window time.Duration) *Deduplicator {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Deduplicator{
+		window:    window,
+		entries:   make(map[string]*entry),
+		receivers: make(map[string]*ReceiverStats),
+	}
+}
+
+// SetWindow changes the time window, e.g. on a configuration reload. Messages
+// already seen are kept and expire with the new window.
+func (d *Deduplicator) SetWindow(window time.Duration) {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.window = window
+}
+
+// Observe records a parsed message heard by source and reports whether it is a
+// duplicate of a message already heard by another receiver within the window
+func (d *Deduplicator) Observe(source string, state *types.AircraftState) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if state.Timestamp.After(d.latest) {
+		d.latest = state.Timestamp
+	}
+	d.expire()
+
+	rs := d.receiver(source)
+	rs.Messages++
+
+	key := contentKey(state)
+	if e, exists := d.entries[key]; exists && state.Timestamp.Sub(e.firstSeen) <= d.window {
+		if !containsSource(e.sources, source) {
+			e.sources = append(e.sources, source)
+			rs.Duplicates++
+			return true
+		}
+		// The same receiver repeated the message, so it is new information
+		d.finalize(e)
+	}
+
+	e := &entry{
+		key:       key,
+		firstSeen: state.Timestamp,
+		sources:   []string{source},
+	}
+	d.entries[key] = e
+	d.queue = append(d.queue, e)
+	return false
+}
+
+// Flush finalizes all pending entries so their receivers are accounted for,
+// e.g. before the final statistics are reported on shutdown
+func (d *Deduplicator) Flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, e := range d.queue {
+		if d.entries[e.key] == e {
+			d.finalize(e)
+		}
+	}
+	d.queue = nil
+}
+
+// ReceiverStats returns a copy of the per-receiver counters
+func (d *Deduplicator) ReceiverStats() map[string]ReceiverStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := make(map[string]ReceiverStats, len(d.receivers))
+	for source, rs := range d.receivers {
+		stats[source] = ReceiverStats{
+			Messages:   rs.Messages,
+			Duplicates: rs.Duplicates,
+			Unique:     rs.Unique,
+			SharedWith: maps.Clone(rs.SharedWith),
+		}
+	}
+	return stats
+}
+
+// String returns a string representation of the per-receiver counters
+func (d *Deduplicator) String() string {
+	stats := d.ReceiverStats()
+
+	sources := make([]string, 0, len(stats))
+	for source := range stats {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var b strings.Builder
+	for i, source := range sources {
+		rs := stats[source]
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Receiver %s: Messages: %d, Duplicates: %d, Unique: %d",
+			source, rs.Messages, rs.Duplicates, rs.Unique)
+		for _, other := range slices.Sorted(maps.Keys(rs.SharedWith)) {
+			fmt.Fprintf(&b, ", Shared with %s: %d", other, rs.SharedWith[other])
+		}
+	}
+	return b.String()
+}
+
+// expire finalizes entries that fell out of the window
+func (d *Deduplicator) expire() {
+	n := 0
+	for _, e := range d.queue {
+		if d.latest.Sub(e.firstSeen) <= d.window {
+			break
+		}
+		if d.entries[e.key] == e {
+			d.finalize(e)
+		}
+		n++
+	}
+	d.queue = d.queue[n:]
+}
+
+// finalize removes an entry and credits its receiver if it was heard only
+// once, or every pair of receivers that heard it otherwise
+func (d *Deduplicator) finalize(e *entry) {
+	if d.entries[e.key] == e {
+		delete(d.entries, e.key)
+	}
+	if len(e.sources) == 1 {
+		d.receiver(e.sources[0]).Unique++
+		return
+	}
+	for _, source := range e.sources {
+		rs := d.receiver(source)
+		if rs.SharedWith == nil {
+			rs.SharedWith = make(map[string]uint64)
+		}
+		for _, other := range e.sources {
+			if other != source {
+				rs.SharedWith[other]++
+			}
+		}
+	}
+}
+
+// receiver returns the counters for a source, creating them if needed
+func (d *Deduplicator) receiver(source string) *ReceiverStats {
+	rs, exists := d.receivers[source]
+	if !exists {
+		rs = &ReceiverStats{}
+		d.receivers[source] = rs
+	}
+	return rs
+}
+
+// contentKey builds the deduplication key from the message content, ignoring
+// the receiver-specific timestamps and session fields. The flags are part of
+// the key so a message raising one is never merged into one that didn't.
+func contentKey(state *types.AircraftState) string {
+	return fmt.Sprintf("%s|%d|%s|%d|%g|%g|%g|%g|%d|%s|%t|%t|%t|%t|%t",
+		state.HexIdent, state.MsgType, state.Callsign, state.Altitude,
+		state.GroundSpeed, state.Track, state.Latitude, state.Longitude,
+		state.VerticalRate, state.Squawk, state.OnGround,
+		state.HasFlags, state.Alert, state.Emergency, state.SPI,
+	)
+}
+
+// containsSource reports whether source is in sources
+func containsSource(sources []string, source string) bool {
+	for _, s := range sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package dedup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+func testState(hexIdent string, altitude int, timestamp time.Time) *types.AircraftState {
+	return &types.AircraftState{
+		HexIdent:  hexIdent,
+		Altitude:  altitude,
+		MsgType:   5,
+		Timestamp: timestamp,
+	}
+}
+
+func TestNew(t *testing.T) {
+	d := New(0)
+	if d.window != DefaultWindow {
+		t.Errorf("Expected default window %v, got %v", DefaultWindow, d.window)
+	}
+
+	d = New(5 * time.Second)
+	if d.window != 5*time.Second {
+		t.Errorf("Expected window 5s, got %v", d.window)
+	}
+
+	d.SetWindow(10 * time.Second)
+	if d.window != 10*time.Second {
+		t.Errorf("Expected window 10s, got %v", d.window)
+	}
+	d.SetWindow(0)
+	if d.window != DefaultWindow {
+		t.Errorf("Expected default window %v, got %v", DefaultWindow, d.window)
+	}
+}
+
+func TestDeduplicator_Observe(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		observe  func(d *Deduplicator) []bool
+		expected []bool
+	}{
+		{
+			name: "same message from two receivers",
+			observe: func(d *Deduplicator) []bool {
+				return []bool{
+					d.Observe("rx1", testState("ABC123", 35000, base)),
+					d.Observe("rx2", testState("ABC123", 35000, base.Add(100*time.Millisecond))),
+				}
+			},
+			expected: []bool{false, true},
+		},
+		{
+			name: "different content",
+			observe: func(d *Deduplicator) []bool {
+				return []bool{
+					d.Observe("rx1", testState("ABC123", 35000, base)),
+					d.Observe("rx2", testState("ABC123", 35100, base)),
+				}
+			},
+			expected: []bool{false, false},
+		},
+		{
+			name: "different flags",
+			observe: func(d *Deduplicator) []bool {
+				emergency := testState("ABC123", 35000, base.Add(100*time.Millisecond))
+				emergency.HasFlags = true
+				emergency.Emergency = true
+				flagsCleared := testState("ABC123", 35000, base.Add(200*time.Millisecond))
+				flagsCleared.HasFlags = true
+				return []bool{
+					d.Observe("rx1", testState("ABC123", 35000, base)),
+					d.Observe("rx2", emergency),
+					d.Observe("rx1", flagsCleared),
+				}
+			},
+			expected: []bool{false, false, false},
+		},
+		{
+			name: "different aircraft",
+			observe: func(d *Deduplicator) []bool {
+				return []bool{
+					d.Observe("rx1", testState("ABC123", 35000, base)),
+					d.Observe("rx2", testState("DEF456", 35000, base)),
+				}
+			},
+			expected: []bool{false, false},
+		},
+		{
+			name: "outside the window",
+			observe: func(d *Deduplicator) []bool {
+				return []bool{
+					d.Observe("rx1", testState("ABC123", 35000, base)),
+					d.Observe("rx2", testState("ABC123", 35000, base.Add(3*time.Second))),
+				}
+			},
+			expected: []bool{false, false},
+		},
+		{
+			name: "same receiver repeating",
+			observe: func(d *Deduplicator) []bool {
+				return []bool{
+					d.Observe("rx1", testState("ABC123", 35000, base)),
+					d.Observe("rx1", testState("ABC123", 35000, base.Add(time.Second))),
+					d.Observe("rx2", testState("ABC123", 35000, base.Add(time.Second))),
+				}
+			},
+			expected: []bool{false, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(2 * time.Second)
+			got := tt.observe(d)
+			for i := range tt.expected {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Observe() call %d = %v, expected %v", i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDeduplicator_ReceiverStats(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := New(2 * time.Second)
+
+	// Heard by both receivers
+	d.Observe("rx1", testState("ABC123", 35000, base))
+	d.Observe("rx2", testState("ABC123", 35000, base))
+
+	// Heard only by rx1
+	d.Observe("rx1", testState("DEF456", 12000, base))
+
+	// Heard only by rx2
+	d.Observe("rx2", testState("GHI789", 8000, base))
+
+	// Advance past the window so the entries above are finalized
+	d.Observe("rx1", testState("JKL012", 1000, base.Add(5*time.Second)))
+
+	stats := d.ReceiverStats()
+
+	if stats["rx1"].Messages != 3 {
+		t.Errorf("Expected rx1 messages 3, got %d", stats["rx1"].Messages)
+	}
+	if stats["rx1"].Unique != 1 {
+		t.Errorf("Expected rx1 unique 1, got %d", stats["rx1"].Unique)
+	}
+	if stats["rx2"].Duplicates != 1 {
+		t.Errorf("Expected rx2 duplicates 1, got %d", stats["rx2"].Duplicates)
+	}
+	if stats["rx2"].Unique != 1 {
+		t.Errorf("Expected rx2 unique 1, got %d", stats["rx2"].Unique)
+	}
+	if stats["rx1"].SharedWith["rx2"] != 1 || stats["rx2"].SharedWith["rx1"] != 1 {
+		t.Errorf("Expected ABC123 shared by rx1 and rx2, got %v and %v", stats["rx1"].SharedWith, stats["rx2"].SharedWith)
+	}
+
+	// The returned counters are a copy
+	stats["rx1"].SharedWith["rx2"] = 10
+	if d.ReceiverStats()["rx1"].SharedWith["rx2"] != 1 {
+		t.Error("Expected ReceiverStats to return a copy of the shared counters")
+	}
+
+	// Flush finalizes the pending JKL012 entry
+	d.Flush()
+	stats = d.ReceiverStats()
+	if stats["rx1"].Unique != 2 {
+		t.Errorf("Expected rx1 unique 2 after flush, got %d", stats["rx1"].Unique)
+	}
+	if len(d.entries) != 0 {
+		t.Errorf("Expected no pending entries after flush, got %d", len(d.entries))
+	}
+}
+
+func TestDeduplicator_String(t *testing.T) {
+	d := New(time.Second)
+	d.Observe("rx2", testState("ABC123", 35000, time.Now()))
+	d.Observe("rx1", testState("ABC123", 35000, time.Now()))
+
+	s := d.String()
+	if !strings.HasPrefix(s, "Receiver rx1:") {
+		t.Errorf("Expected receivers to be sorted, got %q", s)
+	}
+	if !strings.Contains(s, "Receiver rx2: Messages: 1, Duplicates: 0") {
+		t.Errorf("Expected rx2 counters in output, got %q", s)
+	}
+
+	d.Flush()
+	if s := d.String(); !strings.Contains(s, "Receiver rx1: Messages: 1, Duplicates: 1, Unique: 0, Shared with rx2: 1") {
+		t.Errorf("Expected shared counters in output, got %q", s)
+	}
+}
@@ -0,0 +1,65 @@
+package filewatch
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// DefaultInterval is the default polling interval for file changes
+const DefaultInterval = time.Minute
+
+// fileInfo holds the attributes used to detect a file change
+type fileInfo struct {
+	modTime time.Time
+	size    int64
+}
+
+// stat returns the change attributes of a file
+func stat(path string) (fileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileInfo{}, err
+	}
+	return fileInfo{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// Watch polls path every interval and calls reload whenever its modification
+// time or size changes. It blocks until the context is cancelled.
+func Watch(ctx context.Context, path string, interval time.Duration, reload func() error) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	last, err := stat(path)
+	if err != nil {
+		log.Printf("Warning: Failed to stat %s: %v", path, err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current, err := stat(path)
+			if err != nil {
+				log.Printf("Warning: Failed to stat %s: %v", path, err)
+				continue
+			}
+			if current == last {
+				continue
+			}
+			last = current
+
+			if err := reload(); err != nil {
+				log.Printf("Warning: Failed to reload %s: %v", path, err)
+				continue
+			}
+			log.Printf("Reloaded %s", path)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package filewatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatch_ReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	var reloads int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, path, 10*time.Millisecond, func() error {
+			atomic.AddInt32(&reloads, 1)
+			return nil
+		})
+		close(done)
+	}()
+
+	// No change, no reload
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&reloads) != 0 {
+		t.Errorf("Expected no reloads before change, got %d", reloads)
+	}
+
+	if err := os.WriteFile(path, []byte("abc"), 0o600); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&reloads) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&reloads) != 1 {
+		t.Errorf("Expected 1 reload after change, got %d", reloads)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Watch did not return when context was cancelled")
+	}
+}
+
+func TestWatch_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.csv")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	called := false
+	Watch(ctx, path, 10*time.Millisecond, func() error {
+		called = true
+		return nil
+	})
+
+	if called {
+		t.Error("Expected reload not to be called for a missing file")
+	}
+}
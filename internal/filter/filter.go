@@ -0,0 +1,405 @@
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/filewatch"
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+// Action is what a rule does with the messages it matches
+type Action string
+
+// Rule actions
+const (
+	Allow Action = "allow"
+	Deny  Action = "deny"
+)
+
+// DefaultRule is the name the dropped counter of the default action is kept under
+const DefaultRule = "default"
+
+// ErrNotFound is returned when a rule does not exist
+var ErrNotFound = errors.New("filter rule not found")
+
+// Area is a latitude/longitude bounding box
+type Area struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+// Rule allows or denies the messages matching all of its conditions. Unset
+// conditions match everything. Hex ident, callsign and source entries are
+// case insensitive and may use the * and ? wildcards.
+type Rule struct {
+	Name         string   `json:"name"`
+	Action       Action   `json:"action"`
+	HexIdents    []string `json:"hex_idents,omitempty"`
+	Callsigns    []string `json:"callsigns,omitempty"`
+	Sources      []string `json:"sources,omitempty"`
+	MessageTypes []int    `json:"message_types,omitempty"`
+	MinAltitude  *int     `json:"min_altitude,omitempty"`
+	MaxAltitude  *int     `json:"max_altitude,omitempty"`
+	Area         *Area    `json:"area,omitempty"`
+}
+
+// Validate checks the action, name, patterns and ranges of a rule
+func (r *Rule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("filter rule name is required")
+	}
+	if r.Name == DefaultRule {
+		return fmt.Errorf("filter rule name %q is reserved", DefaultRule)
+	}
+	if err := r.Action.validate(); err != nil {
+		return fmt.Errorf("filter rule %q: %w", r.Name, err)
+	}
+	for _, patterns := range [][]string{r.HexIdents, r.Callsigns, r.Sources} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q in filter rule %q: %w", pattern, r.Name, err)
+			}
+		}
+	}
+	if r.MinAltitude != nil && r.MaxAltitude != nil && *r.MinAltitude > *r.MaxAltitude {
+		return fmt.Errorf("filter rule %q: min_altitude is above max_altitude", r.Name)
+	}
+	if a := r.Area; a != nil {
+		if a.MinLatitude > a.MaxLatitude || a.MinLongitude > a.MaxLongitude ||
+			a.MinLatitude < -90 || a.MaxLatitude > 90 || a.MinLongitude < -180 || a.MaxLongitude > 180 {
+			return fmt.Errorf("filter rule %q: invalid area", r.Name)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether a state satisfies all the conditions of the rule.
+// Area conditions never match states without a known position.
+func (r *Rule) Matches(state *types.AircraftState) bool {
+	if len(r.HexIdents) > 0 && !matchAny(r.HexIdents, state.HexIdent) {
+		return false
+	}
+	if len(r.Callsigns) > 0 && !matchAny(r.Callsigns, state.Callsign) {
+		return false
+	}
+	if len(r.Sources) > 0 && !matchAny(r.Sources, state.Source) {
+		return false
+	}
+	if len(r.MessageTypes) > 0 && !slices.Contains(r.MessageTypes, state.MsgType) {
+		return false
+	}
+	if r.MinAltitude != nil && state.Altitude < *r.MinAltitude {
+		return false
+	}
+	if r.MaxAltitude != nil && state.Altitude > *r.MaxAltitude {
+		return false
+	}
+	if a := r.Area; a != nil {
+		if state.Latitude == 0 && state.Longitude == 0 {
+			return false
+		}
+		if state.Latitude < a.MinLatitude || state.Latitude > a.MaxLatitude ||
+			state.Longitude < a.MinLongitude || state.Longitude > a.MaxLongitude {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize upper-cases and trims the patterns of a rule
+func (r *Rule) normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	for _, patterns := range [][]string{r.HexIdents, r.Callsigns, r.Sources} {
+		for i, pattern := range patterns {
+			patterns[i] = strings.ToUpper(strings.TrimSpace(pattern))
+		}
+	}
+}
+
+// validate checks that an action is allow or deny
+func (a Action) validate() error {
+	if a != Allow && a != Deny {
+		return fmt.Errorf("invalid action %q, expected %q or %q", a, Allow, Deny)
+	}
+	return nil
+}
+
+// Config is the default action and the ordered list of rules of a pipeline
+type Config struct {
+	Default Action  `json:"default"`
+	Rules   []*Rule `json:"rules"`
+}
+
+// Validate normalizes and checks the rules of a configuration. An empty
+// default action means allow.
+func (c *Config) Validate() error {
+	if c.Default == "" {
+		c.Default = Allow
+	}
+	if err := c.Default.validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+
+	names := make(map[string]bool, len(c.Rules))
+	for _, rule := range c.Rules {
+		if rule == nil {
+			return fmt.Errorf("empty filter rule")
+		}
+		rule.normalize()
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate filter rule %q", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+// Pipeline applies the rules in order to each message: the first matching
+// rule decides, and messages matching no rule get the default action. When
+// backed by a file, changes are written back to it.
+type Pipeline struct {
+	path    string
+	config  Config
+	dropped map[string]uint64 // Dropped messages by rule name
+	mu      sync.RWMutex
+}
+
+// New creates a pipeline that allows every message and is not backed by a file
+func New() *Pipeline {
+	return &Pipeline{
+		config:  Config{Default: Allow},
+		dropped: make(map[string]uint64),
+	}
+}
+
+// Open loads a pipeline from a JSON configuration file. A missing file gives
+// the default pipeline that is created on the first change.
+func Open(path string) (*Pipeline, error) {
+	p := New()
+	p.path = path
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload reads the configuration file again and replaces the rules
+func (p *Pipeline) Reload() error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read filter rules: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse filter rules: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.config = config
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the rules whenever the file changes until the context is cancelled
+func (p *Pipeline) Watch(ctx context.Context, interval time.Duration) {
+	if p.path == "" {
+		return
+	}
+	filewatch.Watch(ctx, p.path, interval, p.Reload)
+}
+
+// Allow applies the rules to a state and reports whether it passes, counting
+// the message against the rule that dropped it
+func (p *Pipeline) Allow(state *types.AircraftState) bool {
+	p.mu.RLock()
+	name, action := DefaultRule, p.config.Default
+	for _, rule := range p.config.Rules {
+		if rule.Matches(state) {
+			name, action = rule.Name, rule.Action
+			break
+		}
+	}
+	p.mu.RUnlock()
+
+	if action == Allow {
+		return true
+	}
+
+	p.mu.Lock()
+	p.dropped[name]++
+	p.mu.Unlock()
+	return false
+}
+
+// Config returns a copy of the configuration
+func (p *Pipeline) Config() Config {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	config := Config{Default: p.config.Default, Rules: make([]*Rule, len(p.config.Rules))}
+	copy(config.Rules, p.config.Rules)
+	return config
+}
+
+// SetConfig replaces the default action and all the rules
+func (p *Pipeline) SetConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous := p.config
+	p.config = config
+	if err := p.save(); err != nil {
+		p.config = previous
+		return err
+	}
+	return nil
+}
+
+// PutRule replaces the rule with the same name in place, or appends it
+func (p *Pipeline) PutRule(rule *Rule) error {
+	rule.normalize()
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rules := make([]*Rule, 0, len(p.config.Rules)+1)
+	replaced := false
+	for _, r := range p.config.Rules {
+		if r.Name == rule.Name {
+			r, replaced = rule, true
+		}
+		rules = append(rules, r)
+	}
+	if !replaced {
+		rules = append(rules, rule)
+	}
+	return p.replaceRules(rules)
+}
+
+// DeleteRule removes a rule, returning ErrNotFound if it does not exist
+func (p *Pipeline) DeleteRule(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rules := make([]*Rule, 0, len(p.config.Rules))
+	for _, r := range p.config.Rules {
+		if r.Name != name {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == len(p.config.Rules) {
+		return ErrNotFound
+	}
+	return p.replaceRules(rules)
+}
+
+// Dropped returns the number of messages dropped by each rule, with messages
+// dropped by a deny default counted under DefaultRule
+func (p *Pipeline) Dropped() map[string]uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	dropped := make(map[string]uint64, len(p.dropped))
+	for name, count := range p.dropped {
+		dropped[name] = count
+	}
+	return dropped
+}
+
+// String returns the dropped message counters, one rule per line
+func (p *Pipeline) String() string {
+	dropped := p.Dropped()
+
+	names := make([]string, 0, len(dropped))
+	for name := range dropped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Rule %s: Dropped: %d", name, dropped[name])
+	}
+	return b.String()
+}
+
+// replaceRules swaps the rules and saves them, restoring the previous rules
+// on failure. The caller must hold the lock.
+func (p *Pipeline) replaceRules(rules []*Rule) error {
+	previous := p.config.Rules
+	p.config.Rules = rules
+	if err := p.save(); err != nil {
+		p.config.Rules = previous
+		return err
+	}
+	return nil
+}
+
+// save writes the configuration to the backing file, if any. The caller must hold the lock.
+func (p *Pipeline) save() error {
+	if p.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(p.config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal filter rules: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial file
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write filter rules: %w", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("failed to write filter rules: %w", err)
+	}
+	return nil
+}
+
+// matchAny reports whether value matches any of the patterns
+func matchAny(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	value = strings.ToUpper(strings.TrimSpace(value))
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
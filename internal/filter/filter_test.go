@@ -0,0 +1,196 @@
+package filter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestRule_Matches(t *testing.T) {
+	state := &types.AircraftState{
+		HexIdent:  "E48D4E",
+		Callsign:  "TAM3054",
+		Source:    "receiver-1",
+		MsgType:   8,
+		Altitude:  12000,
+		Latitude:  -23.5,
+		Longitude: -46.6,
+	}
+
+	tests := []struct {
+		name     string
+		rule     Rule
+		state    *types.AircraftState
+		expected bool
+	}{
+		{name: "no conditions", rule: Rule{}, expected: true},
+		{name: "hex pattern", rule: Rule{HexIdents: []string{"E4*"}}, expected: true},
+		{name: "hex mismatch", rule: Rule{HexIdents: []string{"A*"}}},
+		{name: "callsign", rule: Rule{Callsigns: []string{"TAM????"}}, expected: true},
+		{name: "source", rule: Rule{Sources: []string{"RECEIVER-*"}}, expected: true},
+		{name: "message type", rule: Rule{MessageTypes: []int{5, 8}}, expected: true},
+		{name: "message type mismatch", rule: Rule{MessageTypes: []int{4}}},
+		{name: "altitude band", rule: Rule{MinAltitude: intPtr(10000), MaxAltitude: intPtr(20000)}, expected: true},
+		{name: "below floor", rule: Rule{MinAltitude: intPtr(15000)}},
+		{name: "area", rule: Rule{Area: &Area{MinLatitude: -24, MaxLatitude: -23, MinLongitude: -47, MaxLongitude: -46}}, expected: true},
+		{name: "outside area", rule: Rule{Area: &Area{MinLatitude: 40, MaxLatitude: 41, MinLongitude: -75, MaxLongitude: -73}}},
+		{name: "area without position", rule: Rule{Area: &Area{MinLatitude: -1, MaxLatitude: 1, MinLongitude: -1, MaxLongitude: 1}},
+			state: &types.AircraftState{HexIdent: "E48D4E"}},
+		{name: "all conditions must match", rule: Rule{HexIdents: []string{"E48D4E"}, Callsigns: []string{"GLO*"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.normalize()
+			s := state
+			if tt.state != nil {
+				s = tt.state
+			}
+			if got := tt.rule.Matches(s); got != tt.expected {
+				t.Errorf("Matches() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectError bool
+	}{
+		{name: "empty config", config: Config{}},
+		{name: "valid rules", config: Config{Default: Deny, Rules: []*Rule{{Name: "local", Action: Allow}}}},
+		{name: "invalid default", config: Config{Default: "drop"}, expectError: true},
+		{name: "missing name", config: Config{Rules: []*Rule{{Action: Deny}}}, expectError: true},
+		{name: "reserved name", config: Config{Rules: []*Rule{{Name: DefaultRule, Action: Deny}}}, expectError: true},
+		{name: "invalid action", config: Config{Rules: []*Rule{{Name: "a", Action: "drop"}}}, expectError: true},
+		{name: "duplicate name", config: Config{Rules: []*Rule{{Name: "a", Action: Deny}, {Name: "a", Action: Allow}}}, expectError: true},
+		{name: "bad pattern", config: Config{Rules: []*Rule{{Name: "a", Action: Deny, Callsigns: []string{"["}}}}, expectError: true},
+		{name: "inverted altitude", config: Config{Rules: []*Rule{{Name: "a", Action: Deny, MinAltitude: intPtr(5000), MaxAltitude: intPtr(1000)}}}, expectError: true},
+		{name: "invalid area", config: Config{Rules: []*Rule{{Name: "a", Action: Deny, Area: &Area{MinLatitude: 10, MaxLatitude: 5}}}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Validate() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestPipeline_Allow(t *testing.T) {
+	p := New()
+	if !p.Allow(&types.AircraftState{HexIdent: "E48D4E"}) {
+		t.Fatal("Expected the default pipeline to allow every message")
+	}
+
+	err := p.SetConfig(Config{
+		Default: Deny,
+		Rules: []*Rule{
+			{Name: "no-tests", Action: Deny, HexIdents: []string{"000000"}},
+			{Name: "low-traffic", Action: Allow, MaxAltitude: intPtr(10000)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SetConfig() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		state    *types.AircraftState
+		expected bool
+	}{
+		{state: &types.AircraftState{HexIdent: "000000", Altitude: 5000}},                 // First rule wins
+		{state: &types.AircraftState{HexIdent: "E48D4E", Altitude: 5000}, expected: true}, // Allowed by second rule
+		{state: &types.AircraftState{HexIdent: "E48D4E", Altitude: 35000}},                // Default deny
+		{state: &types.AircraftState{HexIdent: "000000", Altitude: 35000}},
+	}
+	for i, tt := range tests {
+		if got := p.Allow(tt.state); got != tt.expected {
+			t.Errorf("Allow(%d) = %v, expected %v", i, got, tt.expected)
+		}
+	}
+
+	dropped := p.Dropped()
+	if dropped["no-tests"] != 2 || dropped[DefaultRule] != 1 || dropped["low-traffic"] != 0 {
+		t.Errorf("Unexpected dropped counters: %v", dropped)
+	}
+	if s := p.String(); !strings.Contains(s, "Rule no-tests: Dropped: 2") || !strings.Contains(s, "Rule default: Dropped: 1") {
+		t.Errorf("Unexpected String(): %q", s)
+	}
+}
+
+func TestPipeline_PutDeleteRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters.json")
+	p, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	rules := []*Rule{
+		{Name: "a", Action: Deny, HexIdents: []string{"aaaaaa"}},
+		{Name: "b", Action: Deny, HexIdents: []string{"bbbbbb"}},
+		{Name: "a", Action: Allow, HexIdents: []string{"cccccc"}}, // Replaces a in place
+	}
+	for _, rule := range rules {
+		if err := p.PutRule(rule); err != nil {
+			t.Fatalf("PutRule() unexpected error: %v", err)
+		}
+	}
+	if err := p.PutRule(&Rule{Name: "invalid"}); err == nil {
+		t.Error("Expected invalid rule to be rejected")
+	}
+
+	config := p.Config()
+	if len(config.Rules) != 2 || config.Rules[0].Name != "a" || config.Rules[0].Action != Allow || config.Rules[1].Name != "b" {
+		t.Fatalf("Unexpected rules: %+v", config.Rules)
+	}
+
+	// Changes are written back to the file
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if got := reopened.Config(); len(got.Rules) != 2 || got.Rules[0].HexIdents[0] != "CCCCCC" {
+		t.Errorf("Expected saved rules, got %+v", got.Rules)
+	}
+
+	if err := p.DeleteRule("a"); err != nil {
+		t.Fatalf("DeleteRule() unexpected error: %v", err)
+	}
+	if err := p.DeleteRule("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := reopened.Reload(); err != nil {
+		t.Fatalf("Reload() unexpected error: %v", err)
+	}
+	if got := reopened.Config(); len(got.Rules) != 1 || got.Rules[0].Name != "b" {
+		t.Errorf("Expected deletion to be saved, got %+v", got.Rules)
+	}
+}
+
+func TestOpen_Errors(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"invalid.json": "{not json",
+		"action.json":  `{"default": "allow", "rules": [{"name": "a", "action": "drop"}]}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := Open(path); err == nil {
+			t.Errorf("Expected error opening %s", name)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package geo
+
+import "math"
+
+// EarthRadiusKm is the mean radius of the Earth in kilometres
+const EarthRadiusKm = 6371.0
+
+// Distance returns the great-circle distance between two points in kilometres
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := radians(lat1)
+	phi2 := radians(lat2)
+	dPhi := radians(lat2 - lat1)
+	dLambda := radians(lon2 - lon1)
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Bearing returns the initial true bearing from the first point to the second in degrees
+func Bearing(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := radians(lat1)
+	phi2 := radians(lat2)
+	dLambda := radians(lon2 - lon1)
+
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+// HeadingDiff returns the absolute difference between two headings in degrees (0-180)
+func HeadingDiff(a, b float64) float64 {
+	diff := math.Mod(math.Abs(a-b), 360)
+	if diff > 180 {
+		diff = 360 - diff
+	}
+	return diff
+}
+
+// DistanceToSegment returns the distance in kilometres from a point to the
+// segment between two other points, using a local flat-earth projection that
+// is accurate for the short distances found around an airport
+func DistanceToSegment(lat, lon, lat1, lon1, lat2, lon2 float64) float64 {
+	scale := math.Cos(radians(lat))
+	px, py := lon*scale, lat
+	ax, ay := lon1*scale, lat1
+	bx, by := lon2*scale, lat2
+
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/length))
+	}
+	cx, cy := ax+t*dx, ay+t*dy
+	return Distance(lat, lon, cy, cx/scale)
+}
+
+// radians converts degrees to radians
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// degrees converts radians to degrees
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,95 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		expected               float64
+	}{
+		{name: "same point", lat1: -23.43, lon1: -46.47, lat2: -23.43, lon2: -46.47, expected: 0},
+		{name: "one degree of latitude", lat1: 0, lon1: 0, lat2: 1, lon2: 0, expected: 111.19},
+		{name: "GRU to GIG", lat1: -23.4356, lon1: -46.4731, lat2: -22.8100, lon2: -43.2506, expected: 336.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.expected) > 1 {
+				t.Errorf("Distance() = %.2f, expected %.2f", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBearing(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		expected               float64
+	}{
+		{name: "north", lat1: 0, lon1: 0, lat2: 1, lon2: 0, expected: 0},
+		{name: "east", lat1: 0, lon1: 0, lat2: 0, lon2: 1, expected: 90},
+		{name: "south", lat1: 1, lon1: 0, lat2: 0, lon2: 0, expected: 180},
+		{name: "west", lat1: 0, lon1: 1, lat2: 0, lon2: 0, expected: 270},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Bearing(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.expected) > 0.01 {
+				t.Errorf("Bearing() = %.2f, expected %.2f", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHeadingDiff(t *testing.T) {
+	tests := []struct {
+		a, b     float64
+		expected float64
+	}{
+		{a: 90, b: 80, expected: 10},
+		{a: 350, b: 10, expected: 20},
+		{a: 10, b: 350, expected: 20},
+		{a: 0, b: 180, expected: 180},
+		{a: 720, b: 0, expected: 0},
+	}
+
+	for _, tt := range tests {
+		if got := HeadingDiff(tt.a, tt.b); math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("HeadingDiff(%v, %v) = %v, expected %v", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestDistanceToSegment(t *testing.T) {
+	// Segment along the equator from 0 to 0.1 degrees of longitude
+	tests := []struct {
+		name     string
+		lat, lon float64
+		expected float64
+	}{
+		{name: "on the segment", lat: 0, lon: 0.05, expected: 0},
+		{name: "beside the segment", lat: 0.01, lon: 0.05, expected: 1.11},
+		{name: "beyond the end", lat: 0, lon: 0.11, expected: 1.11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceToSegment(tt.lat, tt.lon, 0, 0, 0, 0.1)
+			if math.Abs(got-tt.expected) > 0.01 {
+				t.Errorf("DistanceToSegment() = %.3f, expected %.3f", got, tt.expected)
+			}
+		})
+	}
+
+	// A degenerate segment is a point
+	if got := DistanceToSegment(0.01, 0, 0, 0, 0, 0); math.Abs(got-1.11) > 0.01 {
+		t.Errorf("DistanceToSegment() for a point = %.3f, expected 1.11", got)
+	}
+}
@@ -0,0 +1,285 @@
+package geofence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/filewatch"
+)
+
+// ring is a closed polygon boundary as [longitude, latitude] points
+type ring [][2]float64
+
+// polygon is an outer boundary followed by optional holes
+type polygon []ring
+
+// Fence is a named area made of one or more polygons, optionally limited to
+// an altitude band in feet
+type Fence struct {
+	Name     string
+	Floor    *int          // Lowest altitude inside the fence, unbounded when nil
+	Ceiling  *int          // Highest altitude inside the fence, unbounded when nil
+	Dwell    time.Duration // Overrides the monitor dwell time when non-zero
+	polygons []polygon
+	minLat   float64
+	maxLat   float64
+	minLon   float64
+	maxLon   float64
+}
+
+// Contains reports whether a position and altitude are inside the fence
+func (f *Fence) Contains(lat, lon float64, altitude int) bool {
+	if f.Floor != nil && altitude < *f.Floor {
+		return false
+	}
+	if f.Ceiling != nil && altitude > *f.Ceiling {
+		return false
+	}
+	if lat < f.minLat || lat > f.maxLat || lon < f.minLon || lon > f.maxLon {
+		return false
+	}
+
+	for _, p := range f.polygons {
+		if p.contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether a point is inside the outer ring and outside every hole
+func (p polygon) contains(lat, lon float64) bool {
+	if len(p) == 0 || !p[0].contains(lat, lon) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.contains(lat, lon) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether a point is inside the ring using ray casting
+func (r ring) contains(lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		xi, yi := r[i][0], r[i][1]
+		xj, yj := r[j][0], r[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Set is the collection of geofences loaded from a GeoJSON file
+type Set struct {
+	path   string
+	fences []*Fence
+	mu     sync.RWMutex
+}
+
+// Open loads the geofences from a GeoJSON file
+func Open(path string) (*Set, error) {
+	s := &Set{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the GeoJSON file again and replaces the loaded geofences
+func (s *Set) Reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read geofences: %w", err)
+	}
+
+	fences, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse geofences: %w", err)
+	}
+
+	s.mu.Lock()
+	s.fences = fences
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the geofences whenever the file changes until the context is cancelled
+func (s *Set) Watch(ctx context.Context, interval time.Duration) {
+	filewatch.Watch(ctx, s.path, interval, s.Reload)
+}
+
+// Containing returns the geofences that contain a position and altitude
+func (s *Set) Containing(lat, lon float64, altitude int) []*Fence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var fences []*Fence
+	for _, f := range s.fences {
+		if f.Contains(lat, lon, altitude) {
+			fences = append(fences, f)
+		}
+	}
+	return fences
+}
+
+// Len returns the number of loaded geofences
+func (s *Set) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.fences)
+}
+
+// geoJSON holds the members of a GeoJSON object used by Parse
+type geoJSON struct {
+	Type        string          `json:"type"`
+	ID          interface{}     `json:"id"`
+	Features    []geoJSON       `json:"features"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Properties  struct {
+		Name         string   `json:"name"`
+		Floor        *int     `json:"floor"`
+		Ceiling      *int     `json:"ceiling"`
+		DwellSeconds *float64 `json:"dwell_seconds"`
+	} `json:"properties"`
+}
+
+// Parse reads geofences from a GeoJSON FeatureCollection or Feature with
+// Polygon or MultiPolygon geometries. The feature properties name, floor and
+// ceiling (feet) and dwell_seconds configure each fence; features without a
+// name use their id.
+func Parse(data []byte) ([]*Fence, error) {
+	var doc geoJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var features []geoJSON
+	switch doc.Type {
+	case "FeatureCollection":
+		features = doc.Features
+	case "Feature":
+		features = []geoJSON{doc}
+	default:
+		return nil, fmt.Errorf("unsupported GeoJSON type %q", doc.Type)
+	}
+
+	fences := make([]*Fence, 0, len(features))
+	names := make(map[string]bool, len(features))
+	for i, feature := range features {
+		fence, err := parseFeature(feature)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+		if fence.Name == "" {
+			fence.Name = fmt.Sprintf("fence-%d", i+1)
+		}
+		if names[fence.Name] {
+			return nil, fmt.Errorf("duplicate geofence name %q", fence.Name)
+		}
+		names[fence.Name] = true
+		fences = append(fences, fence)
+	}
+	return fences, nil
+}
+
+// parseFeature converts a GeoJSON feature into a Fence
+func parseFeature(feature geoJSON) (*Fence, error) {
+	if feature.Geometry == nil {
+		return nil, fmt.Errorf("missing geometry")
+	}
+
+	var polygons []polygon
+	switch feature.Geometry.Type {
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		p, err := toPolygon(coords)
+		if err != nil {
+			return nil, err
+		}
+		polygons = append(polygons, p)
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+		for _, c := range coords {
+			p, err := toPolygon(c)
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, p)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q", feature.Geometry.Type)
+	}
+
+	props := feature.Properties
+	if props.Floor != nil && props.Ceiling != nil && *props.Floor > *props.Ceiling {
+		return nil, fmt.Errorf("floor %d is above ceiling %d", *props.Floor, *props.Ceiling)
+	}
+
+	fence := &Fence{
+		Name:     props.Name,
+		Floor:    props.Floor,
+		Ceiling:  props.Ceiling,
+		polygons: polygons,
+		minLat:   math.Inf(1),
+		maxLat:   math.Inf(-1),
+		minLon:   math.Inf(1),
+		maxLon:   math.Inf(-1),
+	}
+	if fence.Name == "" && feature.ID != nil {
+		fence.Name = fmt.Sprint(feature.ID)
+	}
+	if props.DwellSeconds != nil && *props.DwellSeconds > 0 {
+		fence.Dwell = time.Duration(*props.DwellSeconds * float64(time.Second))
+	}
+
+	// Bounding box of the outer rings for a cheap first check
+	for _, p := range polygons {
+		for _, point := range p[0] {
+			fence.minLon = math.Min(fence.minLon, point[0])
+			fence.maxLon = math.Max(fence.maxLon, point[0])
+			fence.minLat = math.Min(fence.minLat, point[1])
+			fence.maxLat = math.Max(fence.maxLat, point[1])
+		}
+	}
+	return fence, nil
+}
+
+// toPolygon converts GeoJSON polygon coordinates into a polygon
+func toPolygon(coords [][][]float64) (polygon, error) {
+	if len(coords) == 0 {
+		return nil, fmt.Errorf("polygon has no rings")
+	}
+
+	p := make(polygon, 0, len(coords))
+	for _, c := range coords {
+		if len(c) < 4 {
+			return nil, fmt.Errorf("polygon ring needs at least 4 positions, got %d", len(c))
+		}
+		r := make(ring, 0, len(c))
+		for _, position := range c {
+			if len(position) < 2 {
+				return nil, fmt.Errorf("invalid position %v", position)
+			}
+			r = append(r, [2]float64{position[0], position[1]})
+		}
+		p = append(p, r)
+	}
+	return p, nil
+}
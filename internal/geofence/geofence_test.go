@@ -0,0 +1,195 @@
+package geofence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testGeoJSON has a square zone with a hole limited to 0-3000 ft, and an
+// unnamed multipolygon zone with a custom dwell time
+const testGeoJSON = `{
+	"type": "FeatureCollection",
+	"features": [
+		{
+			"type": "Feature",
+			"properties": {"name": "noise", "floor": 0, "ceiling": 3000},
+			"geometry": {
+				"type": "Polygon",
+				"coordinates": [
+					[[-46.7, -23.7], [-46.5, -23.7], [-46.5, -23.5], [-46.7, -23.5], [-46.7, -23.7]],
+					[[-46.62, -23.62], [-46.58, -23.62], [-46.58, -23.58], [-46.62, -23.58], [-46.62, -23.62]]
+				]
+			}
+		},
+		{
+			"type": "Feature",
+			"id": 42,
+			"properties": {"dwell_seconds": 60},
+			"geometry": {
+				"type": "MultiPolygon",
+				"coordinates": [
+					[[[10, 10], [11, 10], [11, 11], [10, 11], [10, 10]]],
+					[[[20, 20], [21, 20], [21, 21], [20, 21], [20, 20]]]
+				]
+			}
+		}
+	]
+}`
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zones.geojson")
+	if err := os.WriteFile(path, []byte(testGeoJSON), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Expected 2 geofences, got %d", s.Len())
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		altitude int
+		expected string
+	}{
+		{name: "inside square", lat: -23.65, lon: -46.65, altitude: 1500, expected: "noise"},
+		{name: "inside hole", lat: -23.6, lon: -46.6, altitude: 1500},
+		{name: "above ceiling", lat: -23.65, lon: -46.65, altitude: 5000},
+		{name: "outside", lat: -23.8, lon: -46.65, altitude: 1500},
+		{name: "second polygon of multipolygon", lat: 20.5, lon: 20.5, altitude: 35000, expected: "42"},
+		{name: "between multipolygon parts", lat: 15, lon: 15, altitude: 35000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fences := s.Containing(tt.lat, tt.lon, tt.altitude)
+			if tt.expected == "" {
+				if len(fences) != 0 {
+					t.Errorf("Expected no geofence, got %s", fences[0].Name)
+				}
+				return
+			}
+			if len(fences) != 1 || fences[0].Name != tt.expected {
+				t.Errorf("Expected geofence %s, got %d fences", tt.expected, len(fences))
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"invalid json":         `{`,
+		"unsupported type":     `{"type": "Point", "coordinates": [0, 0]}`,
+		"missing geometry":     `{"type": "Feature", "properties": {}}`,
+		"unsupported geometry": `{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}}`,
+		"open ring":            `{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [0, 0]]]}}`,
+		"floor above ceiling": `{"type": "Feature", "properties": {"floor": 5000, "ceiling": 1000},
+			"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}}`,
+		"duplicate name": `{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"name": "a"}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}},
+			{"type": "Feature", "properties": {"name": "a"}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}}
+		]}`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(data)); err == nil {
+				t.Error("Expected error, got none")
+			}
+		})
+	}
+}
+
+func TestOpen_MissingFile(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.geojson")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestMonitor_Observe(t *testing.T) {
+	fences, err := Parse([]byte(testGeoJSON))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	s := &Set{fences: fences}
+	m := NewMonitor(s, 2*time.Minute)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name     string
+		pos      Position
+		expected []string
+	}{
+		{name: "outside", pos: Position{Latitude: -23.8, Longitude: -46.65, Altitude: 1500}},
+		{name: "enter", pos: Position{Latitude: -23.65, Longitude: -46.65, Altitude: 1500}, expected: []string{EventEnter}},
+		{name: "still inside", pos: Position{Latitude: -23.66, Longitude: -46.65, Altitude: 1400}},
+		{name: "dwell", pos: Position{Latitude: -23.67, Longitude: -46.65, Altitude: 1200}, expected: []string{EventDwell}},
+		{name: "dwell raised once", pos: Position{Latitude: -23.68, Longitude: -46.65, Altitude: 1000}},
+		{name: "climb above ceiling", pos: Position{Latitude: -23.68, Longitude: -46.65, Altitude: 3500}, expected: []string{EventExit}},
+		{name: "descend back in", pos: Position{Latitude: -23.68, Longitude: -46.65, Altitude: 2500}, expected: []string{EventEnter}},
+		{name: "no position", pos: Position{}},
+	}
+
+	for i, step := range steps {
+		step.pos.Time = start.Add(time.Duration(i) * time.Minute)
+		events := m.Observe("E48D4E", step.pos)
+		if len(events) != len(step.expected) {
+			t.Fatalf("%s: expected %d events, got %d", step.name, len(step.expected), len(events))
+		}
+		for j, event := range events {
+			if event.Type != step.expected[j] || event.Fence != "noise" || event.HexIdent != "E48D4E" {
+				t.Errorf("%s: unexpected event %+v", step.name, event)
+			}
+		}
+	}
+
+	// Forgetting an aircraft inside a geofence exits it at the last position
+	events := m.Forget("E48D4E")
+	if len(events) != 1 || events[0].Type != EventExit || events[0].Altitude != 2500 {
+		t.Errorf("Expected exit event on Forget, got %+v", events)
+	}
+	if events := m.Forget("E48D4E"); len(events) != 0 {
+		t.Errorf("Expected no events for forgotten aircraft, got %d", len(events))
+	}
+}
+
+func TestMonitor_FenceDwell(t *testing.T) {
+	fences, err := Parse([]byte(testGeoJSON))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	// Monitor dwell is disabled, but fence 42 sets its own
+	m := NewMonitor(&Set{fences: fences}, 0)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.Observe("ABC123", Position{Time: start, Latitude: 10.5, Longitude: 10.5, Altitude: 10000})
+	events := m.Observe("ABC123", Position{Time: start.Add(time.Minute), Latitude: 10.6, Longitude: 10.5, Altitude: 10000})
+	if len(events) != 1 || events[0].Type != EventDwell || events[0].Fence != "42" {
+		t.Errorf("Expected dwell event for fence 42, got %+v", events)
+	}
+}
+
+func TestMonitor_SetDwell(t *testing.T) {
+	fences, err := Parse([]byte(testGeoJSON))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	m := NewMonitor(&Set{fences: fences}, 0)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if events := m.Observe("E48D4E", Position{Time: start, Latitude: -23.65, Longitude: -46.65, Altitude: 1500}); len(events) != 1 {
+		t.Fatalf("Expected enter event, got %+v", events)
+	}
+
+	// Aircraft already inside keep their entry time
+	m.SetDwell(2 * time.Minute)
+	events := m.Observe("E48D4E", Position{Time: start.Add(3 * time.Minute), Latitude: -23.66, Longitude: -46.65, Altitude: 1500})
+	if len(events) != 1 || events[0].Type != EventDwell {
+		t.Errorf("Expected dwell event after enabling dwell, got %+v", events)
+	}
+}
@@ -0,0 +1,168 @@
+package geofence
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+// DefaultDwell is the default time an aircraft must stay inside a geofence to raise a dwell event
+const DefaultDwell = 5 * time.Minute
+
+// Geofence event types
+const (
+	EventEnter = "enter"
+	EventExit  = "exit"
+	EventDwell = "dwell"
+)
+
+// Position is the latest known position of an aircraft
+type Position struct {
+	Time      time.Time
+	Latitude  float64
+	Longitude float64
+	Altitude  int
+}
+
+// Locator finds the geofences containing a position
+type Locator interface {
+	Containing(lat, lon float64, altitude int) []*Fence
+}
+
+// visit is the time an aircraft has been inside a geofence
+type visit struct {
+	entered time.Time
+	dwelled bool
+}
+
+// presence is the geofences an aircraft is currently inside
+type presence struct {
+	visits map[string]*visit // By fence name
+	last   Position
+}
+
+// Monitor tracks which geofences each aircraft is inside and produces enter,
+// exit and dwell events as positions are observed
+type Monitor struct {
+	locator  Locator
+	dwell    time.Duration
+	aircraft map[string]*presence // By hex ident
+	mu       sync.Mutex
+}
+
+// NewMonitor creates a new Monitor. A dwell event is raised once per visit
+// after an aircraft stays inside a geofence for dwell; zero disables dwell
+// events except for geofences that set their own dwell time.
+func NewMonitor(locator Locator, dwell time.Duration) *Monitor {
+	return &Monitor{
+		locator:  locator,
+		dwell:    dwell,
+		aircraft: make(map[string]*presence),
+	}
+}
+
+// SetDwell changes the default dwell time, e.g. on a configuration reload,
+// keeping the aircraft currently inside geofences
+func (m *Monitor) SetDwell(dwell time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dwell = dwell
+}
+
+// Observe records the position of an aircraft and returns the events it causes
+func (m *Monitor) Observe(hexIdent string, pos Position) []*types.GeofenceEvent {
+	if pos.Latitude == 0 && pos.Longitude == 0 {
+		return nil
+	}
+	fences := m.locator.Containing(pos.Latitude, pos.Longitude, pos.Altitude)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.aircraft[hexIdent]
+	if p == nil {
+		if len(fences) == 0 {
+			return nil
+		}
+		p = &presence{visits: make(map[string]*visit)}
+		m.aircraft[hexIdent] = p
+	}
+	p.last = pos
+
+	var events []*types.GeofenceEvent
+	current := make(map[string]bool, len(fences))
+	for _, f := range fences {
+		current[f.Name] = true
+
+		v, inside := p.visits[f.Name]
+		if !inside {
+			p.visits[f.Name] = &visit{entered: pos.Time}
+			events = append(events, newEvent(EventEnter, f.Name, hexIdent, pos))
+			continue
+		}
+
+		dwell := m.dwell
+		if f.Dwell > 0 {
+			dwell = f.Dwell
+		}
+		if !v.dwelled && dwell > 0 && pos.Time.Sub(v.entered) >= dwell {
+			v.dwelled = true
+			events = append(events, newEvent(EventDwell, f.Name, hexIdent, pos))
+		}
+	}
+
+	for _, name := range sortedNames(p.visits) {
+		if !current[name] {
+			delete(p.visits, name)
+			events = append(events, newEvent(EventExit, name, hexIdent, pos))
+		}
+	}
+	if len(p.visits) == 0 {
+		delete(m.aircraft, hexIdent)
+	}
+	return events
+}
+
+// Forget drops an aircraft, e.g. when its flight ends, and returns exit events
+// at its last known position for the geofences it was still inside
+func (m *Monitor) Forget(hexIdent string) []*types.GeofenceEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.aircraft[hexIdent]
+	if p == nil {
+		return nil
+	}
+	delete(m.aircraft, hexIdent)
+
+	events := make([]*types.GeofenceEvent, 0, len(p.visits))
+	for _, name := range sortedNames(p.visits) {
+		events = append(events, newEvent(EventExit, name, hexIdent, p.last))
+	}
+	return events
+}
+
+// newEvent creates a geofence event for an aircraft position
+func newEvent(eventType, fence, hexIdent string, pos Position) *types.GeofenceEvent {
+	return &types.GeofenceEvent{
+		Time:      pos.Time,
+		Type:      eventType,
+		Fence:     fence,
+		HexIdent:  hexIdent,
+		Latitude:  pos.Latitude,
+		Longitude: pos.Longitude,
+		Altitude:  pos.Altitude,
+	}
+}
+
+// sortedNames returns the fence names of visits in order
+func sortedNames(visits map[string]*visit) []string {
+	names := make([]string, 0, len(visits))
+	for name := range visits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,198 @@
+// Package health serves the liveness and readiness endpoints of a service:
+// /healthz fails when the service stopped receiving messages, and /readyz
+// also fails when a dependency is unreachable or the service is stopping.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxMessageAge is how long a service may go without a message
+// before it is reported as stalled
+const DefaultMaxMessageAge = 5 * time.Minute
+
+// checkTimeout bounds each dependency check
+const checkTimeout = 2 * time.Second
+
+// Status of a report or of one of its checks
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check returns an error when a dependency is unavailable, e.g. Postgres
+// doesn't answer a ping
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the body of the health endpoints
+type Report struct {
+	Status          string            `json:"status"`
+	Checks          map[string]Result `json:"checks"`
+	LastMessageTime time.Time         `json:"last_message_time"`
+	LastMessageAge  float64           `json:"last_message_age_seconds"`
+}
+
+// OK reports whether every check passed
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the checks of a service. Checks are added before serving.
+type Checker struct {
+	checks      []namedCheck
+	lastMessage func() time.Time
+	maxAge      time.Duration // Zero disables the stall check
+	stopping    atomic.Bool
+}
+
+// New creates a checker reporting the service as stalled when lastMessage is
+// older than maxAge
+func New(lastMessage func() time.Time, maxAge time.Duration) *Checker {
+	return &Checker{lastMessage: lastMessage, maxAge: maxAge}
+}
+
+// Add adds the check of a dependency to the readiness report
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Stopping makes the service unready, so no new work is routed to it while
+// it shuts down
+func (c *Checker) Stopping() {
+	c.stopping.Store(true)
+}
+
+// Live reports whether the service still receives messages
+func (c *Checker) Live() Report {
+	report := c.newReport()
+	report.set("messages", c.checkMessages(report.LastMessageTime))
+	return report
+}
+
+// Ready reports whether the service receives messages and reaches every
+// dependency. The dependencies are checked concurrently.
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := c.Live()
+	if c.stopping.Load() {
+		report.set("shutdown", errors.New("shutting down"))
+	}
+
+	errs := make([]error, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			errs[i] = check.check(checkCtx)
+		}()
+	}
+	wg.Wait()
+
+	for i, check := range c.checks {
+		report.set(check.name, errs[i])
+	}
+	return report
+}
+
+// newReport returns a passing report with the age of the last message
+func (c *Checker) newReport() Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result)}
+	if c.lastMessage != nil {
+		report.LastMessageTime = c.lastMessage()
+		report.LastMessageAge = time.Since(report.LastMessageTime).Seconds()
+	}
+	return report
+}
+
+// checkMessages returns an error when the last message is too old
+func (c *Checker) checkMessages(last time.Time) error {
+	if c.lastMessage == nil || c.maxAge <= 0 {
+		return nil
+	}
+	if age := time.Since(last); age > c.maxAge {
+		return fmt.Errorf("no message for %s, more than %s", age.Round(time.Second), c.maxAge)
+	}
+	return nil
+}
+
+// set records the result of a check, failing the report on error
+func (r *Report) set(name string, err error) {
+	if err != nil {
+		r.Status = StatusFail
+		r.Checks[name] = Result{Status: StatusFail, Error: err.Error()}
+		return
+	}
+	r.Checks[name] = Result{Status: StatusOK}
+}
+
+// Handler returns the HTTP handler of the health endpoints. They answer 200
+// when the report passes and 503 otherwise.
+func (c *Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, c.Live())
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Ready(r.Context()))
+	})
+	return mux
+}
+
+// writeReport writes report as a JSON response
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Warning: Failed to write health response: %v", err)
+	}
+}
+
+// Serve starts the health endpoints on addr, if set
+func (c *Checker) Serve(addr string) (*http.Server, error) {
+	if addr == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           c.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Health endpoint stopped: %v", err)
+		}
+	}()
+	log.Printf("Health endpoint listening on %s", listener.Addr())
+	return server, nil
+}
@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChecker_Live(t *testing.T) {
+	tests := []struct {
+		name     string
+		last     time.Duration // Age of the last message
+		maxAge   time.Duration
+		expected bool
+	}{
+		{name: "recent message", last: time.Second, maxAge: time.Minute, expected: true},
+		{name: "stalled", last: 2 * time.Minute, maxAge: time.Minute, expected: false},
+		{name: "stall check disabled", last: time.Hour, maxAge: 0, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last := time.Now().Add(-tt.last)
+			report := New(func() time.Time { return last }, tt.maxAge).Live()
+			if report.OK() != tt.expected {
+				t.Errorf("Expected OK() = %v, got report %+v", tt.expected, report)
+			}
+			if report.LastMessageAge < tt.last.Seconds() {
+				t.Errorf("Expected a last message age of at least %s, got %.0fs", tt.last, report.LastMessageAge)
+			}
+		})
+	}
+}
+
+func TestChecker_Ready(t *testing.T) {
+	checker := New(time.Now, time.Minute)
+	checker.Add("nats", func(context.Context) error { return nil })
+	checker.Add("postgres", func(context.Context) error { return nil })
+
+	if report := checker.Ready(context.Background()); !report.OK() {
+		t.Errorf("Expected the service to be ready, got %+v", report)
+	}
+
+	// A failed dependency makes the service unready but still alive
+	checker.Add("redis", func(context.Context) error { return errors.New("connection refused") })
+	report := checker.Ready(context.Background())
+	if report.OK() {
+		t.Error("Expected the service to be unready")
+	}
+	if result := report.Checks["redis"]; result.Status != StatusFail || result.Error != "connection refused" {
+		t.Errorf("Expected the redis check to fail, got %+v", result)
+	}
+	if result := report.Checks["nats"]; result.Status != StatusOK {
+		t.Errorf("Expected the nats check to pass, got %+v", result)
+	}
+	if !checker.Live().OK() {
+		t.Error("Expected the service to be alive")
+	}
+
+	// Checks that don't answer time out
+	checker = New(time.Now, 0)
+	checker.Add("stuck", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if report := checker.Ready(ctx); report.OK() {
+		t.Error("Expected a check that times out to fail")
+	}
+
+	// The service is unready once it is stopping
+	checker = New(time.Now, time.Minute)
+	checker.Stopping()
+	if report := checker.Ready(context.Background()); report.OK() || report.Checks["shutdown"].Status != StatusFail {
+		t.Errorf("Expected a stopping service to be unready, got %+v", report)
+	}
+}
+
+func TestChecker_Handler(t *testing.T) {
+	last := time.Now()
+	checker := New(func() time.Time { return last }, time.Minute)
+	failing := false
+	checker.Add("postgres", func(context.Context) error {
+		if failing {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	handler := checker.Handler()
+
+	tests := []struct {
+		name     string
+		path     string
+		failing  bool
+		stalled  bool
+		expected int
+	}{
+		{name: "alive", path: "/healthz", expected: http.StatusOK},
+		{name: "ready", path: "/readyz", expected: http.StatusOK},
+		{name: "alive without a dependency", path: "/healthz", failing: true, expected: http.StatusOK},
+		{name: "unready without a dependency", path: "/readyz", failing: true, expected: http.StatusServiceUnavailable},
+		{name: "stalled", path: "/healthz", stalled: true, expected: http.StatusServiceUnavailable},
+		{name: "unready when stalled", path: "/readyz", stalled: true, expected: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing = tt.failing
+			last = time.Now()
+			if tt.stalled {
+				last = last.Add(-time.Hour)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("Failed to decode report: %v", err)
+			}
+			if report.OK() != (tt.expected == http.StatusOK) {
+				t.Errorf("Expected the report status to match the HTTP status, got %q", report.Status)
+			}
+		})
+	}
+}
+
+func TestChecker_Serve(t *testing.T) {
+	server, err := New(time.Now, 0).Serve("")
+	if err != nil || server != nil {
+		t.Errorf("Expected no server without an address, got %v, %v", server, err)
+	}
+
+	if _, err := New(time.Now, 0).Serve("invalid"); err == nil {
+		t.Error("Expected an error for an invalid address")
+	}
+
+	server, err = New(time.Now, 0).Serve("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Serve() failed: %v", err)
+	}
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() failed: %v", err)
+	}
+}
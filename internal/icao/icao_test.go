@@ -0,0 +1,65 @@
+package icao
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		hexIdent string
+		expected Allocation
+		found    bool
+	}{
+		{name: "United States civil", hexIdent: "A1B2C3", expected: Allocation{Country: "United States"}, found: true},
+		{name: "United States military", hexIdent: "AE1234", expected: Allocation{Country: "United States", Military: true}, found: true},
+		{name: "Brazil civil", hexIdent: "e48d4e", expected: Allocation{Country: "Brazil"}, found: true},
+		{name: "Brazil military", hexIdent: "E40123", expected: Allocation{Country: "Brazil", Military: true}, found: true},
+		{name: "range start", hexIdent: "3C0000", expected: Allocation{Country: "Germany"}, found: true},
+		{name: "range end", hexIdent: "3FFFFF", expected: Allocation{Country: "Germany"}, found: true},
+		{name: "small block", hexIdent: "4D2001", expected: Allocation{Country: "Malta"}, found: true},
+		{name: "unallocated", hexIdent: "000001", found: false},
+		{name: "gap between blocks", hexIdent: "4D0400", found: false},
+		{name: "invalid hex", hexIdent: "XYZ123", found: false},
+		{name: "too large", hexIdent: "1000000", found: false},
+		{name: "empty", hexIdent: "", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := Lookup(tt.hexIdent)
+			if found != tt.found {
+				t.Fatalf("Lookup(%q) found = %v, expected %v", tt.hexIdent, found, tt.found)
+			}
+			if got != tt.expected {
+				t.Errorf("Lookup(%q) = %+v, expected %+v", tt.hexIdent, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCountryAndIsMilitary(t *testing.T) {
+	if got := Country("400123"); got != "United Kingdom" {
+		t.Errorf("Country() = %q, expected United Kingdom", got)
+	}
+	if !IsMilitary("43C123") {
+		t.Error("Expected 43C123 to be military")
+	}
+	if IsMilitary("406123") {
+		t.Error("Expected 406123 not to be military")
+	}
+	if got := Country("invalid"); got != "" {
+		t.Errorf("Country() = %q, expected empty", got)
+	}
+}
+
+func TestRangesSorted(t *testing.T) {
+	for name, ranges := range map[string][]addressRange{"country": countryRanges, "military": militaryRanges} {
+		for i, r := range ranges {
+			if r.start > r.end {
+				t.Errorf("%s range %06X-%06X has start after end", name, r.start, r.end)
+			}
+			if i > 0 && ranges[i-1].end >= r.start {
+				t.Errorf("%s ranges %06X and %06X overlap or are unsorted", name, ranges[i-1].start, r.start)
+			}
+		}
+	}
+}
@@ -1,9 +1,15 @@
 package nats
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -11,18 +17,72 @@ import (
 )
 
 const (
-	SubjectSBSRaw = "sbs.raw"
+	SubjectSBSRaw   = "sbs.raw"
+	SubjectAlerts   = "sbs.alerts"
+	SubjectGeofence = "sbs.geofence"
+	SubjectSources  = "sbs.sources"
 )
 
+// SBS_RAW stream settings
+const (
+	StreamSBSRaw = "SBS_RAW"
+	StreamMaxAge = 24 * time.Hour
+
+	// DefaultDuplicateWindow is how long the stream remembers message IDs
+	// to discard duplicates
+	DefaultDuplicateWindow = 2 * time.Minute
+)
+
+// ConnStats counts the connection events of a client
+type ConnStats struct {
+	Connected     bool       `json:"connected"`
+	Server        string     `json:"server,omitempty"` // URL of the connected server, without credentials
+	Disconnects   uint64     `json:"disconnects"`
+	Reconnects    uint64     `json:"reconnects"`
+	Errors        uint64     `json:"errors"` // Asynchronous errors, e.g. slow consumers
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+// EventHandler receives the connection events of a client after they are
+// logged, e.g. to count them in service metrics
+type EventHandler interface {
+	NATSDisconnected(err error)
+	NATSReconnected(url string)
+	NATSError(err error)
+}
+
 // Client represents a NATS client
 type Client struct {
 	conn *nats.Conn
 	js   nats.JetStreamContext
+
+	publisher  *AsyncPublisher // Nil unless async publishing is enabled
+	ackTimeout time.Duration
+
+	mu      sync.Mutex
+	stats   ConnStats
+	handler EventHandler         // Optional
+	subs    []*nats.Subscription // Drained on shutdown
+
+	done      chan struct{} // Closed with the connection
+	closeOnce sync.Once
 }
 
-// New creates a new NATS client
+// New creates a new NATS client for a URL without authentication
 func New(url string) (*Client, error) {
-	nc, err := nats.Connect(url)
+	return NewWithOptions(&Options{URL: url})
+}
+
+// NewWithOptions creates a new NATS client from connection options
+func NewWithOptions(opts *Options) (*Client, error) {
+	c := &Client{done: make(chan struct{})}
+	natsOpts, err := opts.natsOptions(c)
+	if err != nil {
+		return nil, err
+	}
+
+	nc, err := nats.Connect(opts.URL, natsOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
@@ -35,30 +95,193 @@ func New(url string) (*Client, error) {
 
 	// Create stream if it doesn't exist
 	_, err = js.AddStream(&nats.StreamConfig{
-		Name:     "SBS_RAW",
-		Subjects: []string{SubjectSBSRaw},
-		Storage:  nats.FileStorage,
-		MaxAge:   24 * time.Hour,
+		Name:       StreamSBSRaw,
+		Subjects:   []string{SubjectSBSRaw},
+		Storage:    nats.FileStorage,
+		MaxAge:     StreamMaxAge,
+		Duplicates: DefaultDuplicateWindow,
 	})
 	if err != nil && !strings.Contains(err.Error(), "stream name already in use") {
 		nc.Close()
 		return nil, fmt.Errorf("failed to create stream: %w", err)
 	}
 
-	return &Client{
-		conn: nc,
-		js:   js,
-	}, nil
+	c.conn = nc
+	c.js = js
+	return c, nil
+}
+
+// disconnected logs and counts a lost connection. Closing the client isn't
+// counted.
+func (c *Client) disconnected(nc *nats.Conn, err error) {
+	if nc.IsClosed() {
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: Disconnected from NATS: %v", err)
+	} else {
+		log.Printf("Warning: Disconnected from NATS")
+	}
+
+	c.mu.Lock()
+	c.stats.Disconnects++
+	handler := c.handler
+	c.mu.Unlock()
+	if handler != nil {
+		handler.NATSDisconnected(err)
+	}
+}
+
+// reconnected logs and counts a restored connection
+func (c *Client) reconnected(nc *nats.Conn) {
+	url := nc.ConnectedUrlRedacted()
+	log.Printf("Reconnected to NATS at %s", url)
+
+	c.mu.Lock()
+	c.stats.Reconnects++
+	handler := c.handler
+	c.mu.Unlock()
+	if handler != nil {
+		handler.NATSReconnected(url)
+	}
+}
+
+// asyncError logs and counts an error reported outside of a call, such as
+// a slow consumer or a permissions violation
+func (c *Client) asyncError(err error) {
+	log.Printf("Warning: NATS error: %v", err)
+
+	now := time.Now()
+	c.mu.Lock()
+	c.stats.Errors++
+	c.stats.LastError = err.Error()
+	c.stats.LastErrorTime = &now
+	handler := c.handler
+	c.mu.Unlock()
+	if handler != nil {
+		handler.NATSError(err)
+	}
+}
+
+// closed logs the end of the connection
+func (c *Client) closed() {
+	log.Printf("NATS connection closed")
+	c.closeOnce.Do(func() {
+		if c.done != nil {
+			close(c.done)
+		}
+	})
+}
+
+// SetEventHandler sets the handler of the connection events
+func (c *Client) SetEventHandler(handler EventHandler) {
+	c.mu.Lock()
+	c.handler = handler
+	c.mu.Unlock()
+}
+
+// ConnectionStats returns the connection state and event counts
+func (c *Client) ConnectionStats() ConnStats {
+	c.mu.Lock()
+	stats := c.stats
+	c.mu.Unlock()
+
+	if c.conn != nil {
+		stats.Connected = c.conn.IsConnected()
+		if stats.Connected {
+			stats.Server = c.conn.ConnectedUrlRedacted()
+		}
+	}
+	return stats
+}
+
+// Ping checks that the client is connected by making a round trip to the
+// server
+func (c *Client) Ping(ctx context.Context) error {
+	if c.conn == nil || !c.conn.IsConnected() {
+		return fmt.Errorf("not connected to NATS")
+	}
+	if err := c.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping NATS: %w", err)
+	}
+	return nil
+}
+
+// SetDuplicateWindow sets how long the SBS_RAW stream remembers message IDs,
+// updating a stream created with another window
+func (c *Client) SetDuplicateWindow(window time.Duration) error {
+	if c.js == nil {
+		return fmt.Errorf("not connected to NATS")
+	}
+	info, err := c.js.StreamInfo(StreamSBSRaw)
+	if err != nil {
+		return fmt.Errorf("failed to get stream info: %w", err)
+	}
+	if info.Config.Duplicates == window {
+		return nil
+	}
+
+	cfg := info.Config
+	cfg.Duplicates = window
+	if _, err := c.js.UpdateStream(&cfg); err != nil {
+		return fmt.Errorf("failed to update stream: %w", err)
+	}
+	return nil
+}
+
+// MessageID returns the Nats-Msg-Id of an SBS message, made of its source,
+// timestamp and a hash of its content. A message published again, e.g. when
+// retried or replayed from the spool, keeps its ID and is discarded by the
+// stream within the duplicate window.
+func MessageID(msg *types.SBSMessage) string {
+	sum := sha256.Sum256([]byte(msg.Raw))
+	return fmt.Sprintf("%s-%d-%s", msg.Source, msg.Timestamp.UnixNano(), hex.EncodeToString(sum[:8]))
+}
+
+// EnableAsyncPublish makes PublishSBSMessage queue messages for an
+// AsyncPublisher instead of waiting for each ack. onError receives the
+// messages that couldn't be published.
+func (c *Client) EnableAsyncPublish(opts PublisherOptions, onError func(data []byte, err error)) error {
+	if c.conn == nil {
+		return fmt.Errorf("not connected to NATS")
+	}
+	opts = opts.withDefaults()
+	js, err := c.conn.JetStream(nats.PublishAsyncMaxPending(opts.MaxPending), nats.PublishAsyncTimeout(opts.AckTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to get JetStream context: %w", err)
+	}
+	c.publisher = NewAsyncPublisher(js, opts, onError)
+	c.ackTimeout = opts.AckTimeout
+	return nil
+}
+
+// PublishPending returns the number of messages waiting to be acknowledged
+// with async publishing
+func (c *Client) PublishPending() int {
+	if c.publisher == nil {
+		return 0
+	}
+	return c.publisher.Pending()
 }
 
 // PublishSBSMessage publishes an SBS message to NATS
 func (c *Client) PublishSBSMessage(msg *types.SBSMessage) error {
+	if msg == nil {
+		return fmt.Errorf("nil message")
+	}
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	_, err = c.js.Publish(SubjectSBSRaw, data)
+	if c.publisher != nil {
+		if err := c.publisher.PublishAsync(SubjectSBSRaw, data, MessageID(msg)); err != nil {
+			return fmt.Errorf("failed to publish message: %w", err)
+		}
+		return nil
+	}
+
+	_, err = c.js.Publish(SubjectSBSRaw, data, nats.MsgId(MessageID(msg)))
 	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
@@ -66,9 +289,39 @@ func (c *Client) PublishSBSMessage(msg *types.SBSMessage) error {
 	return nil
 }
 
+// PublishSBSMessageSync publishes an SBS message and waits for its ack, even
+// with async publishing enabled
+func (c *Client) PublishSBSMessageSync(msg *types.SBSMessage) error {
+	if msg == nil {
+		return fmt.Errorf("nil message")
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if c.js == nil {
+		return fmt.Errorf("not connected to NATS")
+	}
+	if _, err := c.js.Publish(SubjectSBSRaw, data, nats.MsgId(MessageID(msg))); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	return nil
+}
+
+// Publish publishes data on a core NATS subject
+func (c *Client) Publish(subject string, data []byte) error {
+	if c.conn == nil {
+		return fmt.Errorf("not connected to NATS")
+	}
+	if err := c.conn.Publish(subject, data); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", subject, err)
+	}
+	return nil
+}
+
 // SubscribeSBSRaw subscribes to raw SBS messages
 func (c *Client) SubscribeSBSRaw(handler func(*types.SBSMessage)) error {
-	_, err := c.js.Subscribe(SubjectSBSRaw, func(msg *nats.Msg) {
+	sub, err := c.js.Subscribe(SubjectSBSRaw, func(msg *nats.Msg) {
 		var sbsMsg types.SBSMessage
 		if err := json.Unmarshal(msg.Data, &sbsMsg); err != nil {
 			fmt.Printf("Error unmarshaling message: %v\n", err)
@@ -80,11 +333,85 @@ func (c *Client) SubscribeSBSRaw(handler func(*types.SBSMessage)) error {
 		return fmt.Errorf("failed to subscribe: %w", err)
 	}
 
+	c.mu.Lock()
+	c.subs = append(c.subs, sub)
+	c.mu.Unlock()
+	return nil
+}
+
+// DrainSubscriptions stops the subscriptions from receiving messages and
+// waits until the handlers are done with the messages already received, or
+// the context is done. Publishing still works afterwards.
+func (c *Client) DrainSubscriptions(ctx context.Context) error {
+	c.mu.Lock()
+	subs := c.subs
+	c.subs = nil
+	c.mu.Unlock()
+
+	for _, sub := range subs {
+		closed := sub.StatusChanged(nats.SubscriptionClosed)
+		if err := sub.Drain(); err != nil {
+			return fmt.Errorf("failed to drain subscription to %s: %w", sub.Subject, err)
+		}
+		select {
+		case <-closed:
+		case <-ctx.Done():
+			return fmt.Errorf("subscription to %s not drained: %w", sub.Subject, ctx.Err())
+		}
+	}
 	return nil
 }
 
-// Close closes the NATS connection
+// ClosePublisher stops publishing asynchronously, waiting for the acks of
+// the pending messages until the context is done. Messages still failing
+// after that are passed to the error handler as their acks time out.
+func (c *Client) ClosePublisher(ctx context.Context) error {
+	if c.publisher == nil {
+		return nil
+	}
+	if err := c.publisher.Close(ctx); err != nil {
+		return fmt.Errorf("failed to publish pending messages: %w", err)
+	}
+	return nil
+}
+
+// Drain shuts the client down without losing messages: it drains the
+// subscriptions, waits for the acks of the messages published
+// asynchronously, flushes the connection and closes it. The connection is
+// closed when the context is done first.
+func (c *Client) Drain(ctx context.Context) error {
+	var errs []error
+	if err := c.ClosePublisher(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if c.conn == nil {
+		return errors.Join(errs...)
+	}
+
+	if err := c.conn.Drain(); err != nil {
+		c.conn.Close()
+		errs = append(errs, fmt.Errorf("failed to drain NATS connection: %w", err))
+		return errors.Join(errs...)
+	}
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		c.conn.Close()
+		errs = append(errs, fmt.Errorf("NATS connection not drained: %w", ctx.Err()))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the NATS connection, first waiting up to the ack timeout for
+// the messages published asynchronously
 func (c *Client) Close() {
+	if c.publisher != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), c.ackTimeout)
+		if err := c.publisher.Close(ctx); err != nil {
+			log.Printf("Warning: Closing NATS with unpublished messages: %v", err)
+		}
+		cancel()
+	}
 	if c.conn != nil {
 		c.conn.Close()
 	}
@@ -3,6 +3,7 @@ package nats
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -585,3 +586,75 @@ func TestNATSClient_Integration_Reconnection(t *testing.T) {
 		t.Fatalf("Failed to publish message with new client: %v", err)
 	}
 }
+
+// TestNATSClient_Integration_Drain tests that draining waits for the
+// messages already received to be handled
+func TestNATSClient_Integration_Drain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	containers := setupTestContainers(t)
+	defer func() {
+		if err := containers.nats.Terminate(context.Background()); err != nil {
+			t.Logf("Failed to terminate NATS container: %v", err)
+		}
+	}()
+
+	natsURL, err := containers.nats.ConnectionString(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get NATS connection string: %v", err)
+	}
+
+	subscriber, err := New(natsURL)
+	if err != nil {
+		t.Fatalf("Failed to create subscriber: %v", err)
+	}
+	defer subscriber.Close()
+	publisher, err := New(natsURL)
+	if err != nil {
+		t.Fatalf("Failed to create publisher: %v", err)
+	}
+	defer publisher.Close()
+
+	// A slow handler, so messages are still pending when draining starts
+	var mu sync.Mutex
+	handled := 0
+	received := make(chan struct{}, 1)
+	if err := subscriber.SubscribeSBSRaw(func(*types.SBSMessage) {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		handled++
+		mu.Unlock()
+	}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	const count = 10
+	for i := range count {
+		msg := &types.SBSMessage{Raw: fmt.Sprintf("MSG,3,1,1,ABC%03d,1", i), Timestamp: time.Now().UTC(), Source: "test-source"}
+		if err := publisher.PublishSBSMessage(msg); err != nil {
+			t.Fatalf("Failed to publish message: %v", err)
+		}
+	}
+	<-received
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := subscriber.Drain(ctx); err != nil {
+		t.Fatalf("Drain() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if handled == 0 || handled > count {
+		t.Errorf("Expected the received messages to be handled, got %d", handled)
+	}
+	if subscriber.ConnectionStats().Connected {
+		t.Error("Expected the connection to be closed after draining")
+	}
+}
@@ -1,6 +1,7 @@
 package nats
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"strings"
@@ -74,6 +75,89 @@ func TestClient_PublishSBSMessage_Unit_NilMessage(t *testing.T) {
 	}
 }
 
+func TestClient_Publish_Unit_NotConnected(t *testing.T) {
+	client := &Client{conn: nil}
+	if err := client.Publish(SubjectAlerts, []byte("{}")); err == nil {
+		t.Error("Expected error when publishing without a connection")
+	}
+}
+
+func TestClient_Ping_Unit_NotConnected(t *testing.T) {
+	client := &Client{conn: nil}
+	if err := client.Ping(context.Background()); err == nil {
+		t.Error("Expected error pinging without a connection")
+	}
+}
+
+func TestClient_ClosePublisher_Unit(t *testing.T) {
+	client := &Client{conn: nil}
+	if err := client.ClosePublisher(context.Background()); err != nil {
+		t.Errorf("Expected no error without async publishing, got %v", err)
+	}
+
+	js := &mockAsyncJetStream{hold: true}
+	defer js.release()
+	client.publisher = NewAsyncPublisher(js, PublisherOptions{AckTimeout: time.Minute}, nil)
+	if err := client.publisher.PublishAsync(SubjectSBSRaw, []byte("{}"), "id"); err != nil {
+		t.Fatalf("PublishAsync() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.ClosePublisher(ctx); err == nil {
+		t.Error("Expected an error with unacknowledged messages")
+	}
+	if err := client.publisher.PublishAsync(SubjectSBSRaw, []byte("{}"), "id"); err == nil {
+		t.Error("Expected publishing to fail once the publisher is closed")
+	}
+}
+
+func TestClient_PublishSBSMessage_Unit_Nil(t *testing.T) {
+	client := &Client{}
+	if err := client.PublishSBSMessage(nil); err == nil {
+		t.Error("Expected error publishing a nil message")
+	}
+	if err := client.PublishSBSMessageSync(nil); err == nil {
+		t.Error("Expected error publishing a nil message")
+	}
+	if err := client.SetDuplicateWindow(time.Minute); err == nil {
+		t.Error("Expected error setting the duplicate window without a connection")
+	}
+}
+
+func TestMessageID_Unit(t *testing.T) {
+	timestamp := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC)
+	msg := &types.SBSMessage{Raw: "MSG,3,1,1,ABC123,1", Timestamp: timestamp, Source: "receiver:30003"}
+
+	id := MessageID(msg)
+	if !strings.HasPrefix(id, "receiver:30003-1704110400123456789-") {
+		t.Errorf("Expected the ID to start with the source and timestamp, got %s", id)
+	}
+
+	// A copy, e.g. decoded from the spool, gets the same ID
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+	var decoded types.SBSMessage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	if got := MessageID(&decoded); got != id {
+		t.Errorf("Expected the same ID after a round trip, got %s and %s", id, got)
+	}
+
+	others := []*types.SBSMessage{
+		{Raw: msg.Raw, Timestamp: timestamp, Source: "receiver:30004"},
+		{Raw: msg.Raw, Timestamp: timestamp.Add(time.Nanosecond), Source: msg.Source},
+		{Raw: "MSG,3,1,1,DEF456,1", Timestamp: timestamp, Source: msg.Source},
+	}
+	for _, other := range others {
+		if MessageID(other) == id {
+			t.Errorf("Expected a different ID for %+v", other)
+		}
+	}
+}
+
 func TestSubjectSBSRaw_Unit_Constant(t *testing.T) {
 	// Test that the constant is defined correctly
 	if SubjectSBSRaw != "sbs.raw" {
@@ -0,0 +1,90 @@
+package nats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Options configures the connection to NATS
+type Options struct {
+	URL  string // Comma separated list of server URLs
+	Name string // Connection name reported to the server, e.g. the service
+
+	CredentialsFile string // NKey/JWT credentials (.creds) file
+	NKeyFile        string // NKey seed file
+	User            string
+	Password        string
+
+	TLSCAFile   string // Optional PEM file of CAs trusted for the server certificate
+	TLSCertFile string // Client certificate, with TLSKeyFile
+	TLSKeyFile  string
+
+	ReconnectWait time.Duration // Zero uses the NATS default of 2 seconds
+	MaxReconnects int           // Zero uses the NATS default of 60, negative retries forever
+}
+
+// Validate checks that the options describe a usable connection
+func (o *Options) Validate() error {
+	if o.URL == "" {
+		return fmt.Errorf("missing NATS URL")
+	}
+	if o.CredentialsFile != "" && o.NKeyFile != "" {
+		return fmt.Errorf("NATS credentials and NKey files are exclusive")
+	}
+	if o.Password != "" && o.User == "" {
+		return fmt.Errorf("missing NATS user for the password")
+	}
+	if (o.TLSCertFile == "") != (o.TLSKeyFile == "") {
+		return fmt.Errorf("NATS TLS client certificate and key must be set together")
+	}
+	if o.ReconnectWait < 0 {
+		return fmt.Errorf("invalid NATS reconnect wait %s", o.ReconnectWait)
+	}
+	return nil
+}
+
+// natsOptions returns the nats.go options of the connection, calling the
+// client's handlers on connection events
+func (o *Options) natsOptions(c *Client) ([]nats.Option, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := []nats.Option{
+		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) { c.disconnected(nc, err) }),
+		nats.ReconnectHandler(func(nc *nats.Conn) { c.reconnected(nc) }),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) { c.asyncError(err) }),
+		nats.ClosedHandler(func(*nats.Conn) { c.closed() }),
+	}
+	if o.Name != "" {
+		opts = append(opts, nats.Name(o.Name))
+	}
+	if o.CredentialsFile != "" {
+		opts = append(opts, nats.UserCredentials(o.CredentialsFile))
+	}
+	if o.NKeyFile != "" {
+		opt, err := nats.NkeyOptionFromSeed(o.NKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read NATS NKey file: %w", err)
+		}
+		opts = append(opts, opt)
+	}
+	if o.User != "" {
+		opts = append(opts, nats.UserInfo(o.User, o.Password))
+	}
+	if o.TLSCAFile != "" {
+		opts = append(opts, nats.RootCAs(o.TLSCAFile))
+	}
+	if o.TLSCertFile != "" {
+		opts = append(opts, nats.ClientCert(o.TLSCertFile, o.TLSKeyFile))
+	}
+	if o.ReconnectWait > 0 {
+		opts = append(opts, nats.ReconnectWait(o.ReconnectWait))
+	}
+	if o.MaxReconnects != 0 {
+		opts = append(opts, nats.MaxReconnects(o.MaxReconnects))
+	}
+	return opts, nil
+}
@@ -0,0 +1,170 @@
+package nats
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "url only", opts: Options{URL: "nats://localhost:4222"}},
+		{name: "credentials and TLS", opts: Options{
+			URL:             "tls://nats:4222",
+			CredentialsFile: "/etc/nats/ingestor.creds",
+			TLSCAFile:       "/etc/nats/ca.pem",
+			TLSCertFile:     "/etc/nats/client.pem",
+			TLSKeyFile:      "/etc/nats/client-key.pem",
+			ReconnectWait:   5 * time.Second,
+			MaxReconnects:   -1,
+		}},
+		{name: "user and password", opts: Options{URL: "nats://nats:4222", User: "sbs", Password: "secret"}},
+		{name: "missing url", opts: Options{}, expectError: true},
+		{name: "credentials and nkey", opts: Options{URL: "nats://nats:4222", CredentialsFile: "a.creds", NKeyFile: "a.nk"}, expectError: true},
+		{name: "password without user", opts: Options{URL: "nats://nats:4222", Password: "secret"}, expectError: true},
+		{name: "certificate without key", opts: Options{URL: "nats://nats:4222", TLSCertFile: "client.pem"}, expectError: true},
+		{name: "negative reconnect wait", opts: Options{URL: "nats://nats:4222", ReconnectWait: -time.Second}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestOptions_NATSOptions(t *testing.T) {
+	opts := &Options{URL: "nats://nats:4222", Name: "ingestor", User: "sbs", Password: "secret", ReconnectWait: 5 * time.Second, MaxReconnects: -1}
+	natsOpts, err := opts.natsOptions(&Client{})
+	if err != nil {
+		t.Fatalf("natsOptions() failed: %v", err)
+	}
+
+	applied := nats.GetDefaultOptions()
+	for _, opt := range natsOpts {
+		if err := opt(&applied); err != nil {
+			t.Fatalf("Failed to apply option: %v", err)
+		}
+	}
+	if applied.Name != "ingestor" || applied.User != "sbs" || applied.Password != "secret" {
+		t.Errorf("Unexpected connection settings: name %q, user %q", applied.Name, applied.User)
+	}
+	if applied.ReconnectWait != 5*time.Second || applied.MaxReconnect != -1 {
+		t.Errorf("Unexpected reconnection settings: wait %s, max %d", applied.ReconnectWait, applied.MaxReconnect)
+	}
+	if applied.DisconnectedErrCB == nil || applied.ReconnectedCB == nil || applied.AsyncErrorCB == nil || applied.ClosedCB == nil {
+		t.Error("Expected connection event handlers to be set")
+	}
+
+	// Unset reconnection settings keep the NATS defaults
+	natsOpts, err = (&Options{URL: "nats://nats:4222"}).natsOptions(&Client{})
+	if err != nil {
+		t.Fatalf("natsOptions() failed: %v", err)
+	}
+	applied = nats.GetDefaultOptions()
+	for _, opt := range natsOpts {
+		if err := opt(&applied); err != nil {
+			t.Fatalf("Failed to apply option: %v", err)
+		}
+	}
+	if applied.ReconnectWait != nats.DefaultReconnectWait || applied.MaxReconnect != nats.DefaultMaxReconnect {
+		t.Errorf("Expected default reconnection settings, got wait %s, max %d", applied.ReconnectWait, applied.MaxReconnect)
+	}
+
+	// An unreadable NKey seed file is reported
+	missing := filepath.Join(t.TempDir(), "missing.nk")
+	if _, err := (&Options{URL: "nats://nats:4222", NKeyFile: missing}).natsOptions(&Client{}); err == nil {
+		t.Error("Expected error for a missing NKey file")
+	}
+	if _, err := NewWithOptions(&Options{}); err == nil {
+		t.Error("Expected error for invalid options")
+	}
+}
+
+func TestOptions_NKeyFile(t *testing.T) {
+	// A user NKey seed, as generated by nk -gen user
+	seed := "SUAMLK2ZNL35WSMW37E7UD4VZ7ELPKW7DHC3BWBSD2GCZ7IUQQXZIORRBU"
+	path := filepath.Join(t.TempDir(), "user.nk")
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("Failed to write NKey file: %v", err)
+	}
+
+	natsOpts, err := (&Options{URL: "nats://nats:4222", NKeyFile: path}).natsOptions(&Client{})
+	if err != nil {
+		t.Fatalf("natsOptions() failed: %v", err)
+	}
+	applied := nats.GetDefaultOptions()
+	for _, opt := range natsOpts {
+		if err := opt(&applied); err != nil {
+			t.Fatalf("Failed to apply option: %v", err)
+		}
+	}
+	if applied.Nkey == "" || applied.SignatureCB == nil {
+		t.Error("Expected the NKey to be used for authentication")
+	}
+}
+
+// mockEventHandler records connection events
+type mockEventHandler struct {
+	mu          sync.Mutex
+	disconnects int
+	reconnects  int
+	errors      []error
+}
+
+func (m *mockEventHandler) NATSDisconnected(error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disconnects++
+}
+
+func (m *mockEventHandler) NATSReconnected(string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects++
+}
+
+func (m *mockEventHandler) NATSError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors = append(m.errors, err)
+}
+
+func TestClient_ConnectionEvents(t *testing.T) {
+	client := &Client{}
+	handler := &mockEventHandler{}
+	client.SetEventHandler(handler)
+
+	conn := &nats.Conn{}
+	client.disconnected(conn, errors.New("connection reset"))
+	client.disconnected(conn, nil)
+	client.reconnected(conn)
+	client.asyncError(nats.ErrSlowConsumer)
+	client.closed()
+
+	stats := client.ConnectionStats()
+	if stats.Connected || stats.Disconnects != 2 || stats.Reconnects != 1 || stats.Errors != 1 {
+		t.Errorf("Unexpected connection stats: %+v", stats)
+	}
+	if stats.LastError != nats.ErrSlowConsumer.Error() || stats.LastErrorTime == nil {
+		t.Errorf("Expected the last error to be recorded, got %+v", stats)
+	}
+	if handler.disconnects != 2 || handler.reconnects != 1 || len(handler.errors) != 1 {
+		t.Errorf("Expected the events to reach the handler, got %+v", handler)
+	}
+}
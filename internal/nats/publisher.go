@@ -0,0 +1,340 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Default async publishing options
+const (
+	DefaultMaxPending    = 4096
+	DefaultBatchSize     = 256
+	DefaultFlushInterval = 50 * time.Millisecond
+	DefaultMaxRetries    = 3
+	DefaultAckTimeout    = 5 * time.Second
+)
+
+// ErrPublisherClosed is returned when publishing after Close
+var ErrPublisherClosed = errors.New("publisher is closed")
+
+// PublisherOptions configures asynchronous publishing to JetStream. Zero
+// fields use the defaults.
+type PublisherOptions struct {
+	MaxPending    int           // Unacknowledged messages before publishing blocks
+	BatchSize     int           // Messages buffered before they are sent
+	FlushInterval time.Duration // Longest a message waits in a batch
+	MaxRetries    int           // Attempts after a failed ack, negative disables
+	AckTimeout    time.Duration // Wait for the acks of a batch, and again for its retries
+}
+
+// DefaultPublisherOptions returns the default async publishing options
+func DefaultPublisherOptions() PublisherOptions {
+	return PublisherOptions{
+		MaxPending:    DefaultMaxPending,
+		BatchSize:     DefaultBatchSize,
+		FlushInterval: DefaultFlushInterval,
+		MaxRetries:    DefaultMaxRetries,
+		AckTimeout:    DefaultAckTimeout,
+	}
+}
+
+// withDefaults returns the options with their zero fields set to the defaults
+func (o PublisherOptions) withDefaults() PublisherOptions {
+	defaults := DefaultPublisherOptions()
+	if o.MaxPending == 0 {
+		o.MaxPending = defaults.MaxPending
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = defaults.BatchSize
+	}
+	if o.FlushInterval == 0 {
+		o.FlushInterval = defaults.FlushInterval
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = defaults.MaxRetries
+	}
+	if o.AckTimeout == 0 {
+		o.AckTimeout = defaults.AckTimeout
+	}
+	return o
+}
+
+// Validate checks that the options describe usable publishing settings
+func (o PublisherOptions) Validate() error {
+	if o.MaxPending < 0 {
+		return fmt.Errorf("invalid max pending %d", o.MaxPending)
+	}
+	if o.BatchSize < 0 {
+		return fmt.Errorf("invalid batch size %d", o.BatchSize)
+	}
+	if set := o.withDefaults(); set.BatchSize > set.MaxPending {
+		return fmt.Errorf("batch size %d is larger than max pending %d", set.BatchSize, set.MaxPending)
+	}
+	if o.FlushInterval < 0 {
+		return fmt.Errorf("invalid flush interval %s", o.FlushInterval)
+	}
+	if o.AckTimeout < 0 {
+		return fmt.Errorf("invalid ack timeout %s", o.AckTimeout)
+	}
+	return nil
+}
+
+// AsyncJetStream is the part of a JetStream context used for async
+// publishing, for testability
+type AsyncJetStream interface {
+	PublishMsgAsync(m *nats.Msg, opts ...nats.PubOpt) (nats.PubAckFuture, error)
+}
+
+// AsyncPublisher publishes messages to JetStream without waiting for each
+// ack. Messages are sent in batches, when a batch is full or every flush
+// interval, and their acks are awaited in the background. The failed
+// messages of a batch are retried together with the same Nats-Msg-Id, so the
+// stream drops the duplicate if the first attempt was stored. Messages that
+// still fail are passed to the error handler, in the order they were
+// published.
+type AsyncPublisher struct {
+	js      AsyncJetStream
+	opts    PublisherOptions
+	onError func(data []byte, err error)
+
+	slots chan struct{} // Holds one token per unacknowledged message
+
+	mu       sync.Mutex
+	batch    []*nats.Msg
+	reported chan struct{} // Closed once the last batch sent reported its failures
+	closed   bool
+
+	acks      sync.WaitGroup // Batches waiting for acks
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewAsyncPublisher creates a publisher sending to js. onError, which may be
+// nil, receives the data of every message that couldn't be published.
+func NewAsyncPublisher(js AsyncJetStream, opts PublisherOptions, onError func(data []byte, err error)) *AsyncPublisher {
+	opts = opts.withDefaults()
+	// A batch larger than the pending limit would never fill up
+	opts.BatchSize = min(opts.BatchSize, opts.MaxPending)
+	p := &AsyncPublisher{
+		js:       js,
+		opts:     opts,
+		onError:  onError,
+		slots:    make(chan struct{}, opts.MaxPending),
+		reported: make(chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	close(p.reported)
+	go p.run()
+	return p
+}
+
+// run flushes the pending batch every flush interval until Close
+func (p *AsyncPublisher) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.Flush()
+		}
+	}
+}
+
+// PublishAsync queues data for subject, with msgID as its Nats-Msg-Id when
+// set. It blocks while MaxPending messages are waiting for their acks.
+func (p *AsyncPublisher) PublishAsync(subject string, data []byte, msgID string) error {
+	select {
+	case <-p.stop:
+		return ErrPublisherClosed
+	default:
+	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.stop:
+		return ErrPublisherClosed
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if msgID != "" {
+		msg.Header.Set(nats.MsgIdHdr, msgID)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return ErrPublisherClosed
+	}
+	p.batch = append(p.batch, msg)
+	var full *batch
+	if len(p.batch) >= p.opts.BatchSize {
+		full = p.takeBatch()
+	}
+	p.mu.Unlock()
+
+	if full != nil {
+		p.send(full)
+	}
+	return nil
+}
+
+// Flush sends the messages waiting in the current batch
+func (p *AsyncPublisher) Flush() {
+	p.mu.Lock()
+	var pending *batch
+	if len(p.batch) > 0 {
+		pending = p.takeBatch()
+	}
+	p.mu.Unlock()
+
+	if pending != nil {
+		p.send(pending)
+	}
+}
+
+// batch is a set of messages sent together
+type batch struct {
+	msgs     []*nats.Msg
+	prev     <-chan struct{} // Closed once the previous batch reported its failures
+	reported chan struct{}   // Closed once this batch reported its failures
+}
+
+// takeBatch takes the current batch, counting it in acks so Close waits for
+// it, and chains it behind the previous one. It is called with the lock held.
+func (p *AsyncPublisher) takeBatch() *batch {
+	b := &batch{msgs: p.batch, prev: p.reported, reported: make(chan struct{})}
+	p.batch = nil
+	p.reported = b.reported
+	p.acks.Add(1)
+	return b
+}
+
+// Pending returns the number of messages queued or waiting for their acks
+func (p *AsyncPublisher) Pending() int {
+	return len(p.slots)
+}
+
+// send publishes a batch and awaits its acks in the background. The slot of
+// each message is released as soon as it is acknowledged. The failed
+// messages are retried together until they are acknowledged, MaxRetries is
+// reached or AckTimeout passes, then passed to the error handler once the
+// previous batch reported its own.
+func (p *AsyncPublisher) send(b *batch) {
+	futures := make([]nats.PubAckFuture, len(b.msgs))
+	errs := make([]error, len(b.msgs))
+	for i, msg := range b.msgs {
+		futures[i], errs[i] = p.js.PublishMsgAsync(msg)
+	}
+
+	go func() {
+		defer p.acks.Done()
+		defer close(b.reported)
+
+		ackCtx, cancelAcks := context.WithTimeout(context.Background(), p.opts.AckTimeout)
+		failed := p.awaitAcks(ackCtx, b.msgs, futures, errs)
+		cancelAcks()
+
+		// Retries share a single deadline, so a batch failing while NATS is
+		// down is handed to the error handler quickly
+		ctx, cancel := context.WithTimeout(context.Background(), p.opts.AckTimeout)
+		defer cancel()
+		for attempt := 0; len(failed) > 0 && attempt < p.opts.MaxRetries && ctx.Err() == nil; attempt++ {
+			failed = p.retry(ctx, failed)
+		}
+
+		<-b.prev
+		for _, f := range failed {
+			if p.onError != nil {
+				p.onError(f.msg.Data, fmt.Errorf("failed to publish message: %w", f.err))
+			}
+			<-p.slots
+		}
+	}()
+}
+
+// failure is a message whose publish failed
+type failure struct {
+	msg *nats.Msg
+	err error
+}
+
+// awaitAcks waits for the acks of msgs until the context is done, releasing
+// the slot of each acknowledged message, and returns the failed messages in
+// order. errs holds the errors of the publishes themselves.
+func (p *AsyncPublisher) awaitAcks(ctx context.Context, msgs []*nats.Msg, futures []nats.PubAckFuture, errs []error) []failure {
+	var failed []failure
+	for i, msg := range msgs {
+		err := errs[i]
+		if err == nil {
+			err = awaitAck(ctx, futures[i])
+		}
+		if err != nil {
+			failed = append(failed, failure{msg: msg, err: err})
+			continue
+		}
+		<-p.slots
+	}
+	return failed
+}
+
+// retry sends the failed messages again and waits for their acks until the
+// context is done, returning the ones that failed again
+func (p *AsyncPublisher) retry(ctx context.Context, failed []failure) []failure {
+	msgs := make([]*nats.Msg, len(failed))
+	futures := make([]nats.PubAckFuture, len(failed))
+	errs := make([]error, len(failed))
+	for i, f := range failed {
+		msgs[i] = f.msg
+		futures[i], errs[i] = p.js.PublishMsgAsync(f.msg)
+	}
+	return p.awaitAcks(ctx, msgs, futures, errs)
+}
+
+// awaitAck waits for the ack of an async publish until the context is done
+func awaitAck(ctx context.Context, future nats.PubAckFuture) error {
+	select {
+	case <-future.Ok():
+		return nil
+	case err := <-future.Err():
+		return err
+	case <-ctx.Done():
+		return nats.ErrAsyncPublishTimeout
+	}
+}
+
+// Close sends the pending batch and waits for the outstanding acks until
+// the context is done. Publishing after Close fails.
+func (p *AsyncPublisher) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+	})
+	<-p.done
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.Flush()
+
+	acked := make(chan struct{})
+	go func() {
+		p.acks.Wait()
+		close(acked)
+	}()
+	select {
+	case <-acked:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d messages not acknowledged: %w", p.Pending(), ctx.Err())
+	}
+}
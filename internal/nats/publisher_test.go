@@ -0,0 +1,373 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/saviobatista/sbs-logger/internal/types"
+)
+
+// mockPubAckFuture is the future of a mock async publish
+type mockPubAckFuture struct {
+	msg *nats.Msg
+	ok  chan *nats.PubAck
+	err chan error
+}
+
+func (f *mockPubAckFuture) Ok() <-chan *nats.PubAck { return f.ok }
+func (f *mockPubAckFuture) Err() <-chan error       { return f.err }
+func (f *mockPubAckFuture) Msg() *nats.Msg          { return f.msg }
+
+// mockAsyncJetStream records async publishes. Acks fail while failures is
+// positive, and are held until release while hold is set.
+type mockAsyncJetStream struct {
+	mu         sync.Mutex
+	published  []*nats.Msg
+	failures   int
+	publishErr error
+	hold       bool
+	held       []*mockPubAckFuture
+}
+
+func (m *mockAsyncJetStream) PublishMsgAsync(msg *nats.Msg, _ ...nats.PubOpt) (nats.PubAckFuture, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.publishErr != nil {
+		return nil, m.publishErr
+	}
+
+	m.published = append(m.published, msg)
+	future := &mockPubAckFuture{msg: msg, ok: make(chan *nats.PubAck, 1), err: make(chan error, 1)}
+	switch {
+	case m.failures > 0:
+		m.failures--
+		future.err <- nats.ErrAsyncPublishTimeout
+	case m.hold:
+		m.held = append(m.held, future)
+	default:
+		future.ok <- &nats.PubAck{Stream: "SBS_RAW"}
+	}
+	return future, nil
+}
+
+// release acknowledges the held messages
+func (m *mockAsyncJetStream) release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hold = false
+	for _, future := range m.held {
+		future.ok <- &nats.PubAck{Stream: "SBS_RAW"}
+	}
+	m.held = nil
+}
+
+// count returns the number of publish attempts
+func (m *mockAsyncJetStream) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.published)
+}
+
+func TestPublisherOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        PublisherOptions
+		expectError bool
+	}{
+		{name: "defaults", opts: DefaultPublisherOptions()},
+		{name: "zero", opts: PublisherOptions{}},
+		{name: "negative max pending", opts: PublisherOptions{MaxPending: -1}, expectError: true},
+		{name: "batch larger than max pending", opts: PublisherOptions{MaxPending: 10, BatchSize: 20}, expectError: true},
+		{name: "batch larger than default max pending", opts: PublisherOptions{BatchSize: DefaultMaxPending + 1}, expectError: true},
+		{name: "negative flush interval", opts: PublisherOptions{FlushInterval: -time.Second}, expectError: true},
+		{name: "negative ack timeout", opts: PublisherOptions{AckTimeout: -time.Second}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestAsyncPublisher_Batching(t *testing.T) {
+	js := &mockAsyncJetStream{}
+	p := NewAsyncPublisher(js, PublisherOptions{BatchSize: 3, FlushInterval: time.Hour}, nil)
+
+	for i := range 2 {
+		if err := p.PublishAsync(SubjectSBSRaw, []byte(fmt.Sprintf("msg %d", i)), fmt.Sprintf("id-%d", i)); err != nil {
+			t.Fatalf("PublishAsync() failed: %v", err)
+		}
+	}
+	if js.count() != 0 {
+		t.Errorf("Expected messages to wait for a full batch, got %d sent", js.count())
+	}
+
+	if err := p.PublishAsync(SubjectSBSRaw, []byte("msg 2"), "id-2"); err != nil {
+		t.Fatalf("PublishAsync() failed: %v", err)
+	}
+	if js.count() != 3 {
+		t.Errorf("Expected the full batch to be sent, got %d", js.count())
+	}
+
+	// Close sends the partial batch
+	if err := p.PublishAsync(SubjectSBSRaw, []byte("msg 3"), "id-3"); err != nil {
+		t.Fatalf("PublishAsync() failed: %v", err)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	for i, msg := range js.published {
+		if string(msg.Data) != fmt.Sprintf("msg %d", i) || msg.Subject != SubjectSBSRaw || msg.Header.Get(nats.MsgIdHdr) != fmt.Sprintf("id-%d", i) {
+			t.Errorf("Unexpected message %d: %s %s %v", i, msg.Subject, msg.Data, msg.Header)
+		}
+	}
+	if p.Pending() != 0 {
+		t.Errorf("Expected no pending messages, got %d", p.Pending())
+	}
+
+	if err := p.PublishAsync(SubjectSBSRaw, []byte("late"), ""); !errors.Is(err, ErrPublisherClosed) {
+		t.Errorf("Expected ErrPublisherClosed, got %v", err)
+	}
+}
+
+func TestAsyncPublisher_FlushInterval(t *testing.T) {
+	js := &mockAsyncJetStream{}
+	p := NewAsyncPublisher(js, PublisherOptions{BatchSize: 100, FlushInterval: 10 * time.Millisecond}, nil)
+	defer p.Close(context.Background())
+
+	if err := p.PublishAsync(SubjectSBSRaw, []byte("msg"), ""); err != nil {
+		t.Fatalf("PublishAsync() failed: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for js.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if js.count() != 1 {
+		t.Error("Expected the batch to be flushed after the flush interval")
+	}
+}
+
+func TestAsyncPublisher_Retry(t *testing.T) {
+	var mu sync.Mutex
+	var failed [][]byte
+	onError := func(data []byte, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, data)
+	}
+
+	// Two failed acks are retried with the same message ID
+	js := &mockAsyncJetStream{failures: 2}
+	p := NewAsyncPublisher(js, PublisherOptions{BatchSize: 1, MaxRetries: 2}, onError)
+	if err := p.PublishAsync(SubjectSBSRaw, []byte("msg"), "id"); err != nil {
+		t.Fatalf("PublishAsync() failed: %v", err)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if js.count() != 3 || len(failed) != 0 {
+		t.Errorf("Expected 3 attempts and no failure, got %d attempts and %d failures", js.count(), len(failed))
+	}
+	for _, msg := range js.published {
+		if msg.Header.Get(nats.MsgIdHdr) != "id" {
+			t.Errorf("Expected retries to keep the message ID, got %v", msg.Header)
+		}
+	}
+
+	// Messages failing every retry go to the error handler
+	js = &mockAsyncJetStream{failures: 10}
+	p = NewAsyncPublisher(js, PublisherOptions{BatchSize: 1, MaxRetries: 2}, onError)
+	if err := p.PublishAsync(SubjectSBSRaw, []byte("lost"), "id"); err != nil {
+		t.Fatalf("PublishAsync() failed: %v", err)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if len(failed) != 1 || string(failed[0]) != "lost" {
+		t.Errorf("Expected the message to reach the error handler, got %q", failed)
+	}
+
+	// Publish errors are retried too
+	js = &mockAsyncJetStream{publishErr: nats.ErrConnectionClosed}
+	p = NewAsyncPublisher(js, PublisherOptions{BatchSize: 1, MaxRetries: -1}, onError)
+	if err := p.PublishAsync(SubjectSBSRaw, []byte("closed"), ""); err != nil {
+		t.Fatalf("PublishAsync() failed: %v", err)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if len(failed) != 2 || string(failed[1]) != "closed" {
+		t.Errorf("Expected the message to reach the error handler, got %q", failed)
+	}
+}
+
+func TestAsyncPublisher_NoAcks(t *testing.T) {
+	var mu sync.Mutex
+	var failed []string
+	onError := func(data []byte, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, string(data))
+	}
+
+	// While NATS is down, batches fail within their ack deadlines and free
+	// their slots instead of blocking publishing for every retry of every
+	// message
+	js := &mockAsyncJetStream{hold: true}
+	defer js.release()
+	p := NewAsyncPublisher(js, PublisherOptions{MaxPending: 8, BatchSize: 4, MaxRetries: 3, AckTimeout: 20 * time.Millisecond}, onError)
+
+	start := time.Now()
+	for i := range 12 {
+		if err := p.PublishAsync(SubjectSBSRaw, []byte(fmt.Sprintf("msg %d", i)), fmt.Sprintf("id-%d", i)); err != nil {
+			t.Fatalf("PublishAsync() failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the failed batches to free their slots quickly, publishing took %s", elapsed)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// Failed messages reach the error handler in the order they were published
+	mu.Lock()
+	defer mu.Unlock()
+	if len(failed) != 12 {
+		t.Fatalf("Expected 12 messages to reach the error handler, got %d", len(failed))
+	}
+	for i, data := range failed {
+		if data != fmt.Sprintf("msg %d", i) {
+			t.Errorf("Expected msg %d at position %d, got %q", i, i, data)
+		}
+	}
+	if p.Pending() != 0 {
+		t.Errorf("Expected no pending messages, got %d", p.Pending())
+	}
+}
+
+func TestAsyncPublisher_MaxPending(t *testing.T) {
+	js := &mockAsyncJetStream{hold: true}
+	p := NewAsyncPublisher(js, PublisherOptions{MaxPending: 2, BatchSize: 1}, nil)
+
+	for range 2 {
+		if err := p.PublishAsync(SubjectSBSRaw, []byte("msg"), ""); err != nil {
+			t.Fatalf("PublishAsync() failed: %v", err)
+		}
+	}
+
+	published := make(chan error, 1)
+	go func() {
+		published <- p.PublishAsync(SubjectSBSRaw, []byte("msg"), "")
+	}()
+	select {
+	case err := <-published:
+		t.Fatalf("Expected PublishAsync() to block with 2 pending acks, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	js.release()
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatalf("PublishAsync() failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected PublishAsync() to resume once acks arrive")
+	}
+
+	// Close gives up on acks that don't arrive in time
+	js.mu.Lock()
+	js.hold = true
+	js.mu.Unlock()
+	if err := p.PublishAsync(SubjectSBSRaw, []byte("msg"), ""); err != nil {
+		t.Fatalf("PublishAsync() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Close() to time out, got %v", err)
+	}
+	js.release()
+}
+
+func TestClient_PublishSBSMessage_Async(t *testing.T) {
+	js := &mockAsyncJetStream{}
+	client := &Client{
+		publisher:  NewAsyncPublisher(js, PublisherOptions{BatchSize: 1}, nil),
+		ackTimeout: time.Second,
+	}
+
+	msg := &types.SBSMessage{Raw: "MSG,3,1,1,ABC123,1", Timestamp: time.Now().UTC(), Source: "receiver:30003"}
+	for range 2 {
+		if err := client.PublishSBSMessage(msg); err != nil {
+			t.Fatalf("PublishSBSMessage() failed: %v", err)
+		}
+	}
+	client.Close()
+
+	if js.count() != 2 {
+		t.Fatalf("Expected 2 published messages, got %d", js.count())
+	}
+	var decoded types.SBSMessage
+	if err := json.Unmarshal(js.published[0].Data, &decoded); err != nil || decoded.Raw != msg.Raw {
+		t.Errorf("Unexpected published message %s: %v", js.published[0].Data, err)
+	}
+	first, second := js.published[0].Header.Get(nats.MsgIdHdr), js.published[1].Header.Get(nats.MsgIdHdr)
+	if first != MessageID(msg) || first != second {
+		t.Errorf("Expected both publishes to use the message ID %q, got %q and %q", MessageID(msg), first, second)
+	}
+	if client.PublishPending() != 0 {
+		t.Errorf("Expected no pending messages, got %d", client.PublishPending())
+	}
+
+	if err := (&Client{}).EnableAsyncPublish(DefaultPublisherOptions(), nil); err == nil {
+		t.Error("Expected error enabling async publishing without a connection")
+	}
+}
+
+func TestClient_Drain_Unit(t *testing.T) {
+	// Drain sends the pending batch and waits for its acks
+	js := &mockAsyncJetStream{}
+	client := &Client{publisher: NewAsyncPublisher(js, PublisherOptions{BatchSize: 100, FlushInterval: time.Hour}, nil)}
+	msg := &types.SBSMessage{Raw: "MSG,3,1,1,ABC123,1", Timestamp: time.Now().UTC(), Source: "receiver:30003"}
+	if err := client.PublishSBSMessage(msg); err != nil {
+		t.Fatalf("PublishSBSMessage() failed: %v", err)
+	}
+	if err := client.Drain(context.Background()); err != nil {
+		t.Errorf("Drain() failed: %v", err)
+	}
+	if js.count() != 1 || client.PublishPending() != 0 {
+		t.Errorf("Expected the pending message to be published, got %d sent and %d pending", js.count(), client.PublishPending())
+	}
+
+	// Acks that don't arrive before the deadline make the drain fail
+	js = &mockAsyncJetStream{hold: true}
+	defer js.release()
+	client = &Client{publisher: NewAsyncPublisher(js, PublisherOptions{BatchSize: 1}, nil)}
+	if err := client.PublishSBSMessage(msg); err != nil {
+		t.Fatalf("PublishSBSMessage() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Drain() to time out, got %v", err)
+	}
+
+	if err := (&Client{}).DrainSubscriptions(context.Background()); err != nil {
+		t.Errorf("Expected nothing to drain without subscriptions, got %v", err)
+	}
+}
@@ -28,6 +28,16 @@ const (
 	MsgTypeID              MessageType = 12 // ID messages
 )
 
+// Parse parses an SBS message into an aircraft state, keeping the receiver it came from
+func Parse(msg *types.SBSMessage) (*types.AircraftState, error) {
+	state, err := ParseMessage(msg.Raw, msg.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	state.Source = msg.Source
+	return state, nil
+}
+
 // ParseMessage parses a raw SBS message into an aircraft state
 func ParseMessage(raw string, timestamp time.Time) (*types.AircraftState, error) {
 	// Split message into fields
@@ -68,7 +78,7 @@ func ParseMessage(raw string, timestamp time.Time) (*types.AircraftState, error)
 
 		// Extract callsign if available
 		if len(fields) > 9 {
-			state.Callsign = fields[9]
+			state.Callsign = strings.TrimSpace(fields[9])
 		}
 
 		return state, nil
@@ -113,7 +123,7 @@ func parseMessageFields(state *types.AircraftState, fields []string, msgTypeInde
 		// Only hex identifier is set above
 
 	case MsgTypeNewCallSign:
-		state.Callsign = fields[10+msgTypeIndex]
+		state.Callsign = strings.TrimSpace(fields[10+msgTypeIndex])
 
 	case MsgTypeNewAltitude:
 		parseAltitude(state, fields, msgTypeIndex)
@@ -134,7 +144,7 @@ func parseMessageFields(state *types.AircraftState, fields []string, msgTypeInde
 		// These are status/info messages that don't contain state information
 		// but we can extract some basic info if available
 		if len(fields) > 10+msgTypeIndex {
-			state.Callsign = fields[10+msgTypeIndex]
+			state.Callsign = strings.TrimSpace(fields[10+msgTypeIndex])
 		}
 		return nil
 
@@ -142,6 +152,10 @@ func parseMessageFields(state *types.AircraftState, fields []string, msgTypeInde
 		return fmt.Errorf("unknown message type: %d (raw message: %q)", state.MsgType, strings.Join(fields, ","))
 	}
 
+	// The squawk and the flags are not only sent with positions, e.g. MSG,6
+	// carries the squawk with the alert, emergency and SPI bits
+	parseSquawk(state, fields, msgTypeIndex)
+	parseFlags(state, fields, msgTypeIndex)
 	return nil
 }
 
@@ -181,10 +195,29 @@ func parseLatLon(state *types.AircraftState, fields []string, msgTypeIndex int)
 	if vr, err := strconv.Atoi(fields[16+msgTypeIndex]); err == nil {
 		state.VerticalRate = vr
 	}
+	parseOnGround(state, fields, msgTypeIndex)
+}
+
+// parseSquawk parses squawk field
+func parseSquawk(state *types.AircraftState, fields []string, msgTypeIndex int) {
 	if squawk, err := strconv.Atoi(fields[17+msgTypeIndex]); err == nil {
 		state.Squawk = fmt.Sprintf("%04d", squawk)
 	}
-	parseOnGround(state, fields, msgTypeIndex)
+}
+
+// parseFlags parses the alert, emergency and SPI flag fields, if set
+func parseFlags(state *types.AircraftState, fields []string, msgTypeIndex int) {
+	flag := func(index int) bool {
+		if len(fields) <= index || fields[index] == "" {
+			return false
+		}
+		state.HasFlags = true
+		value, err := strconv.Atoi(fields[index])
+		return err == nil && value != 0
+	}
+	state.Alert = flag(18 + msgTypeIndex)
+	state.Emergency = flag(19 + msgTypeIndex)
+	state.SPI = flag(20 + msgTypeIndex)
 }
 
 // parseOnGround parses on ground field
@@ -91,6 +91,97 @@ func TestParseMessage(t *testing.T) {
 	}
 }
 
+func TestParseMessage_TrimsCallsign(t *testing.T) {
+	raw := "MSG,4,111,11111,111111,ABC123,111111,111111,111111,111111,111111,TAM3456 ,,,,,,,,,,"
+	state, err := ParseMessage(raw, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("ParseMessage() unexpected error: %v", err)
+	}
+	if state.Callsign != "TAM3456" {
+		t.Errorf("ParseMessage() Callsign = %q, want %q", state.Callsign, "TAM3456")
+	}
+}
+
+func TestParseMessage_Flags(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     string
+		alert     bool
+		emergency bool
+		spi       bool
+	}{
+		{name: "no flags", flags: "0,0,0", alert: false, emergency: false, spi: false},
+		{name: "alert", flags: "-1,0,0", alert: true},
+		{name: "emergency", flags: "0,-1,0", emergency: true},
+		{name: "spi", flags: "0,0,1", spi: true},
+		{name: "empty fields", flags: ",,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := "MSG,8,111,11111,111111,ABC123,111111,111111,111111,111111,111111,111111,35000,450,180,40.7128,-74.0060,0,7700," + tt.flags + ",0"
+			state, err := ParseMessage(raw, time.Now().UTC())
+			if err != nil {
+				t.Fatalf("ParseMessage() unexpected error: %v", err)
+			}
+			if state.Alert != tt.alert || state.Emergency != tt.emergency || state.SPI != tt.spi {
+				t.Errorf("ParseMessage() flags = %v/%v/%v, want %v/%v/%v",
+					state.Alert, state.Emergency, state.SPI, tt.alert, tt.emergency, tt.spi)
+			}
+			if state.HasFlags != (tt.flags != ",,") {
+				t.Errorf("ParseMessage() HasFlags = %v for flags %q", state.HasFlags, tt.flags)
+			}
+			if state.Squawk != "7700" {
+				t.Errorf("ParseMessage() Squawk = %v, want 7700", state.Squawk)
+			}
+		})
+	}
+}
+
+func TestParseMessage_FlagsWithoutPosition(t *testing.T) {
+	// MSG,6 carries the squawk and the flags without a position
+	raw := "MSG,6,111,11111,111111,E48D4E,111111,111111,111111,111111,111111,111111,,,,,,,7700,0,-1,0,0"
+	state, err := ParseMessage(raw, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("ParseMessage() unexpected error: %v", err)
+	}
+	if state.Squawk != "7700" || !state.Emergency || state.Alert || state.SPI || !state.HasFlags {
+		t.Errorf("ParseMessage() = %+v, want squawk 7700 with the emergency flag", state)
+	}
+
+	// Messages without the flag fields leave them unset
+	raw = "MSG,6,111,11111,111111,E48D4E,111111,111111,111111,111111,111111,111111,,450,,,,,,,,,"
+	if state, err = ParseMessage(raw, time.Now().UTC()); err != nil {
+		t.Fatalf("ParseMessage() unexpected error: %v", err)
+	}
+	if state.HasFlags || state.Squawk != "" {
+		t.Errorf("ParseMessage() = %+v, want no flags and no squawk", state)
+	}
+}
+
+func TestParse(t *testing.T) {
+	msg := &types.SBSMessage{
+		Raw:       "MSG,8,111,11111,111111,ABC123,111111,111111,111111,111111,111111,35000,450,180,40.7128,-74.0060,0,1234,0,0,0,0",
+		Timestamp: time.Now().UTC(),
+		Source:    "receiver-1:30003",
+	}
+
+	state, err := Parse(msg)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if state.Source != msg.Source {
+		t.Errorf("Parse() Source = %v, want %v", state.Source, msg.Source)
+	}
+	if !state.Timestamp.Equal(msg.Timestamp) {
+		t.Errorf("Parse() Timestamp = %v, want %v", state.Timestamp, msg.Timestamp)
+	}
+
+	if _, err := Parse(&types.SBSMessage{Raw: "INVALID", Source: "receiver-1:30003"}); err == nil {
+		t.Error("Parse() expected error for invalid message")
+	}
+}
+
 func TestParseMessageWithMock(t *testing.T) {
 	mockMsg := testutils.MockSBSMessage(8, "ABC123")
 	state, err := ParseMessage(mockMsg.Raw, mockMsg.Timestamp)
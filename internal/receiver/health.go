@@ -0,0 +1,243 @@
+package receiver
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultSampleInterval is how often Monitor.Run updates the source rates
+const DefaultSampleInterval = 10 * time.Second
+
+// State is the connection state of a source
+type State string
+
+// Source connection states
+const (
+	StateConnecting State = "connecting" // Dialing the source
+	StateConnected  State = "connected"  // Receiving data
+	StateIdle       State = "idle"       // Connected, but no data since the last sample
+	StateBackoff    State = "backoff"    // Waiting before reconnecting
+)
+
+// Status is a snapshot of the health of a source
+type Status struct {
+	Source            string     `json:"source"`
+	State             State      `json:"state"`
+	Since             time.Time  `json:"since"` // When the source entered its state
+	LastConnected     *time.Time `json:"last_connected,omitempty"`
+	Reconnects        uint64     `json:"reconnects"` // Connections after the first one
+	Bytes             uint64     `json:"bytes"`
+	Messages          uint64     `json:"messages"`
+	LastMessage       *time.Time `json:"last_message,omitempty"`
+	BytesPerSecond    float64    `json:"bytes_per_second"`
+	MessagesPerSecond float64    `json:"messages_per_second"`
+	LastError         string     `json:"last_error,omitempty"`
+	LastErrorTime     *time.Time `json:"last_error_time,omitempty"`
+	RetryAt           *time.Time `json:"retry_at,omitempty"` // Next connection attempt while in backoff
+}
+
+// Health tracks the connection state and throughput of one source. It is
+// safe for concurrent use.
+type Health struct {
+	mu          sync.Mutex
+	status      Status
+	connections uint64
+	sampledAt   time.Time
+	sampled     Status // Counters at the last sample
+}
+
+// NewHealth creates the health of a source that is about to connect
+func NewHealth(source string) *Health {
+	now := time.Now()
+	return &Health{
+		status:    Status{Source: source, State: StateConnecting, Since: now},
+		sampledAt: now,
+	}
+}
+
+// setState changes the state, keeping the time it was entered
+func (h *Health) setState(state State, now time.Time) {
+	if h.status.State != state {
+		h.status.State = state
+		h.status.Since = now
+	}
+	if state != StateBackoff {
+		h.status.RetryAt = nil
+	}
+}
+
+// Connecting records a connection attempt
+func (h *Health) Connecting() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.setState(StateConnecting, time.Now())
+}
+
+// Connected records a successful connection
+func (h *Health) Connected() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.setState(StateConnected, now)
+	h.status.LastConnected = &now
+	if h.connections > 0 {
+		h.status.Reconnects++
+	}
+	h.connections++
+}
+
+// Backoff records the error that ended a connection or connection attempt,
+// and the delay before the next attempt
+func (h *Health) Backoff(err error, delay time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.setState(StateBackoff, now)
+	retryAt := now.Add(delay)
+	h.status.RetryAt = &retryAt
+	if err != nil {
+		h.status.LastError = err.Error()
+		h.status.LastErrorTime = &now
+	}
+}
+
+// Received records data read from the source, with the number of complete
+// messages it contained
+func (h *Health) Received(bytes, messages int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.status.Bytes += uint64(bytes)
+	h.status.Messages += uint64(messages)
+	if messages > 0 {
+		h.status.LastMessage = &now
+	}
+	if h.status.State == StateIdle {
+		h.setState(StateConnected, now)
+	}
+}
+
+// sample updates the rates from the counters received since the last sample.
+// A connected source that received nothing becomes idle.
+func (h *Health) sample(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	elapsed := now.Sub(h.sampledAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	bytes := h.status.Bytes - h.sampled.Bytes
+	messages := h.status.Messages - h.sampled.Messages
+	h.status.BytesPerSecond = float64(bytes) / elapsed
+	h.status.MessagesPerSecond = float64(messages) / elapsed
+	if h.status.State == StateConnected && bytes == 0 {
+		h.setState(StateIdle, now)
+	}
+
+	h.sampled = h.status
+	h.sampledAt = now
+}
+
+// Status returns a snapshot of the health of the source
+func (h *Health) Status() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// Monitor holds the health of every source
+type Monitor struct {
+	mu      sync.RWMutex
+	sources map[string]*Health
+	started time.Time
+}
+
+// NewMonitor creates an empty monitor
+func NewMonitor() *Monitor {
+	return &Monitor{sources: make(map[string]*Health), started: time.Now()}
+}
+
+// Add starts tracking source, replacing any previous health
+func (m *Monitor) Add(source string) *Health {
+	h := NewHealth(source)
+	m.mu.Lock()
+	m.sources[source] = h
+	m.mu.Unlock()
+	return h
+}
+
+// Remove stops tracking source
+func (m *Monitor) Remove(source string) {
+	m.mu.Lock()
+	delete(m.sources, source)
+	m.mu.Unlock()
+}
+
+// Status returns the health of source
+func (m *Monitor) Status(source string) (Status, bool) {
+	m.mu.RLock()
+	h, ok := m.sources[source]
+	m.mu.RUnlock()
+	if !ok {
+		return Status{}, false
+	}
+	return h.Status(), true
+}
+
+// Statuses returns the health of every source, sorted by source
+func (m *Monitor) Statuses() []Status {
+	m.mu.RLock()
+	statuses := make([]Status, 0, len(m.sources))
+	for _, h := range m.sources {
+		statuses = append(statuses, h.Status())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Source < statuses[j].Source })
+	return statuses
+}
+
+// LastMessage returns when the last message was received from any source,
+// or when the monitor was created if none was
+func (m *Monitor) LastMessage() time.Time {
+	last := m.started
+	for _, status := range m.Statuses() {
+		if status.LastMessage != nil && status.LastMessage.After(last) {
+			last = *status.LastMessage
+		}
+	}
+	return last
+}
+
+// Sample updates the rates of every source
+func (m *Monitor) Sample(now time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, h := range m.sources {
+		h.sample(now)
+	}
+}
+
+// Run samples the rates every interval until the context is cancelled
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSampleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.Sample(now)
+		}
+	}
+}
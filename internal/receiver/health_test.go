@@ -0,0 +1,107 @@
+package receiver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	h := NewHealth("receiver:30003")
+	if status := h.Status(); status.State != StateConnecting || status.Source != "receiver:30003" {
+		t.Errorf("Expected new source to be connecting, got %+v", status)
+	}
+
+	h.Backoff(errors.New("connection refused"), time.Second)
+	status := h.Status()
+	if status.State != StateBackoff || status.LastError != "connection refused" || status.RetryAt == nil {
+		t.Errorf("Expected backoff with the last error, got %+v", status)
+	}
+
+	h.Connecting()
+	h.Connected()
+	status = h.Status()
+	if status.State != StateConnected || status.LastConnected == nil || status.RetryAt != nil || status.Reconnects != 0 {
+		t.Errorf("Expected first connection, got %+v", status)
+	}
+
+	h.Backoff(errors.New("EOF"), 0)
+	h.Connected()
+	if status := h.Status(); status.Reconnects != 1 || status.LastError != "EOF" {
+		t.Errorf("Expected 1 reconnect keeping the last error, got %+v", status)
+	}
+}
+
+func TestHealth_Sample(t *testing.T) {
+	h := NewHealth("receiver:30003")
+	h.Connected()
+	start := h.sampledAt
+
+	h.Received(1000, 10)
+	h.Received(1000, 10)
+	h.sample(start.Add(2 * time.Second))
+	status := h.Status()
+	if status.Bytes != 2000 || status.Messages != 20 || status.BytesPerSecond != 1000 || status.MessagesPerSecond != 10 {
+		t.Errorf("Unexpected counters and rates: %+v", status)
+	}
+	if status.State != StateConnected {
+		t.Errorf("Expected connected source, got %s", status.State)
+	}
+
+	// Nothing received since the last sample
+	h.sample(start.Add(4 * time.Second))
+	status = h.Status()
+	if status.State != StateIdle || status.BytesPerSecond != 0 {
+		t.Errorf("Expected idle source, got %+v", status)
+	}
+
+	h.Received(100, 1)
+	if status := h.Status(); status.State != StateConnected {
+		t.Errorf("Expected data to make the source connected again, got %s", status.State)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	m := NewMonitor()
+	m.Add("b:30003").Connected()
+	m.Add("a:30003")
+
+	statuses := m.Statuses()
+	if len(statuses) != 2 || statuses[0].Source != "a:30003" || statuses[1].State != StateConnected {
+		t.Errorf("Unexpected statuses: %+v", statuses)
+	}
+	if _, ok := m.Status("a:30003"); !ok {
+		t.Error("Expected status of a:30003")
+	}
+
+	m.Remove("a:30003")
+	if _, ok := m.Status("a:30003"); ok {
+		t.Error("Expected removed source to have no status")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m.Run(ctx, 10*time.Millisecond)
+	if status, _ := m.Status("b:30003"); status.State != StateIdle {
+		t.Errorf("Expected Run to sample the source idle, got %s", status.State)
+	}
+}
+
+func TestMonitor_LastMessage(t *testing.T) {
+	m := NewMonitor()
+	started := m.LastMessage()
+	h := m.Add("a:30003")
+
+	// Data without a complete message doesn't count
+	h.Received(10, 0)
+	if last := m.LastMessage(); !last.Equal(started) {
+		t.Errorf("Expected the creation time without messages, got %s", last)
+	}
+
+	h.Received(120, 2)
+	last := m.LastMessage()
+	if status := h.Status(); status.LastMessage == nil || !last.Equal(*status.LastMessage) || last.Before(started) {
+		t.Errorf("Expected the time of the last message, got %s and status %+v", last, status)
+	}
+}
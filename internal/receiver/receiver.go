@@ -0,0 +1,138 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// Default connection options of an SBS source
+const (
+	DefaultDialTimeout     = 10 * time.Second
+	DefaultReadIdleTimeout = 30 * time.Second
+	DefaultKeepAlive       = 15 * time.Second
+	DefaultBufferSize      = 1024
+	DefaultBackoffInitial  = time.Second
+	DefaultBackoffMax      = time.Minute
+	DefaultBackoffJitter   = 0.2
+)
+
+// Options configures the TCP connection to an SBS source (a receiver). Zero
+// fields are taken from other options with Merge.
+type Options struct {
+	DialTimeout     time.Duration
+	ReadIdleTimeout time.Duration // Reconnect after this long without data
+	KeepAlive       time.Duration // TCP keepalive period, negative disables
+	BufferSize      int           // Read buffer size in bytes
+	Backoff         Backoff
+}
+
+// Backoff configures the delay between reconnection attempts, doubling from
+// Initial up to Max
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Jitter  float64 // Fraction of each delay randomized, up to 1, negative disables
+}
+
+// DefaultOptions returns the default connection options
+func DefaultOptions() Options {
+	return Options{
+		DialTimeout:     DefaultDialTimeout,
+		ReadIdleTimeout: DefaultReadIdleTimeout,
+		KeepAlive:       DefaultKeepAlive,
+		BufferSize:      DefaultBufferSize,
+		Backoff: Backoff{
+			Initial: DefaultBackoffInitial,
+			Max:     DefaultBackoffMax,
+			Jitter:  DefaultBackoffJitter,
+		},
+	}
+}
+
+// Merge returns the options with their zero fields taken from defaults
+func (o Options) Merge(defaults Options) Options {
+	if o.DialTimeout == 0 {
+		o.DialTimeout = defaults.DialTimeout
+	}
+	if o.ReadIdleTimeout == 0 {
+		o.ReadIdleTimeout = defaults.ReadIdleTimeout
+	}
+	if o.KeepAlive == 0 {
+		o.KeepAlive = defaults.KeepAlive
+	}
+	if o.BufferSize == 0 {
+		o.BufferSize = defaults.BufferSize
+	}
+	if o.Backoff.Initial == 0 {
+		o.Backoff.Initial = defaults.Backoff.Initial
+	}
+	if o.Backoff.Max == 0 {
+		o.Backoff.Max = defaults.Backoff.Max
+	}
+	if o.Backoff.Jitter == 0 {
+		o.Backoff.Jitter = defaults.Backoff.Jitter
+	}
+	return o
+}
+
+// Validate checks that the options describe usable connection settings
+func (o Options) Validate() error {
+	if o.DialTimeout < 0 {
+		return fmt.Errorf("invalid dial timeout %s", o.DialTimeout)
+	}
+	if o.ReadIdleTimeout < 0 {
+		return fmt.Errorf("invalid read idle timeout %s", o.ReadIdleTimeout)
+	}
+	if o.BufferSize < 0 {
+		return fmt.Errorf("invalid buffer size %d", o.BufferSize)
+	}
+	if o.Backoff.Initial < 0 || o.Backoff.Max < 0 {
+		return fmt.Errorf("invalid backoff delay")
+	}
+	if o.Backoff.Max > 0 && o.Backoff.Max < o.Backoff.Initial {
+		return fmt.Errorf("backoff max %s is lower than initial %s", o.Backoff.Max, o.Backoff.Initial)
+	}
+	if o.Backoff.Jitter > 1 {
+		return fmt.Errorf("invalid backoff jitter %g, expected up to 1", o.Backoff.Jitter)
+	}
+	return nil
+}
+
+// Dial connects to the source at address, giving up after the dial timeout
+// or when the context is cancelled, and enables TCP keepalive
+func (o Options) Dial(ctx context.Context, address string) (*net.TCPConn, error) {
+	dialer := net.Dialer{Timeout: o.DialTimeout, KeepAlive: o.KeepAlive}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unexpected connection type %T", conn)
+	}
+	return tcpConn, nil
+}
+
+// Delay returns the wait before reconnection attempt n, counting from 0.
+// Without a maximum, DefaultBackoffMax caps the delay.
+func (b Backoff) Delay(attempt int) time.Duration {
+	limit := b.Max
+	if limit <= 0 {
+		limit = DefaultBackoffMax
+	}
+
+	delay := b.Initial
+	for i := 0; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(delay, limit)
+
+	if b.Jitter > 0 {
+		delay -= time.Duration(b.Jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
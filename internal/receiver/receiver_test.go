@@ -0,0 +1,115 @@
+package receiver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestOptions_Merge(t *testing.T) {
+	opts := Options{ReadIdleTimeout: time.Minute, Backoff: Backoff{Max: 10 * time.Second}}.Merge(DefaultOptions())
+
+	expected := DefaultOptions()
+	expected.ReadIdleTimeout = time.Minute
+	expected.Backoff.Max = 10 * time.Second
+	if opts != expected {
+		t.Errorf("Merge() = %+v, expected %+v", opts, expected)
+	}
+
+	// A negative jitter is kept, disabling it
+	if opts := (Options{Backoff: Backoff{Jitter: -1}}).Merge(DefaultOptions()); opts.Backoff.Jitter != -1 {
+		t.Errorf("Expected disabled jitter to be kept, got %g", opts.Backoff.Jitter)
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectError bool
+	}{
+		{name: "defaults", opts: DefaultOptions()},
+		{name: "unset", opts: Options{}},
+		{name: "keepalive disabled", opts: Options{KeepAlive: -1}},
+		{name: "negative dial timeout", opts: Options{DialTimeout: -time.Second}, expectError: true},
+		{name: "negative read idle timeout", opts: Options{ReadIdleTimeout: -time.Second}, expectError: true},
+		{name: "negative buffer size", opts: Options{BufferSize: -1}, expectError: true},
+		{name: "negative backoff", opts: Options{Backoff: Backoff{Initial: -time.Second}}, expectError: true},
+		{name: "max below initial", opts: Options{Backoff: Backoff{Initial: time.Minute, Max: time.Second}}, expectError: true},
+		{name: "jitter disabled", opts: Options{Backoff: Backoff{Jitter: -1}}},
+		{name: "jitter above 1", opts: Options{Backoff: Backoff{Jitter: 1.5}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Validate() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 10 * time.Second}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 0, expected: time.Second},
+		{attempt: 1, expected: 2 * time.Second},
+		{attempt: 3, expected: 8 * time.Second},
+		{attempt: 4, expected: 10 * time.Second},
+		{attempt: 100, expected: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := b.Delay(tt.attempt); got != tt.expected {
+			t.Errorf("Delay(%d) = %v, expected %v", tt.attempt, got, tt.expected)
+		}
+	}
+
+	if got := (Backoff{Initial: time.Second}).Delay(100); got != DefaultBackoffMax {
+		t.Errorf("Expected delay capped at %v without a maximum, got %v", DefaultBackoffMax, got)
+	}
+
+	b.Jitter = 0.5
+	for range 100 {
+		if got := b.Delay(4); got < 5*time.Second || got > 10*time.Second {
+			t.Fatalf("Expected jittered delay between 5s and 10s, got %v", got)
+		}
+	}
+
+	b.Jitter = -1
+	if got := b.Delay(4); got != 10*time.Second {
+		t.Errorf("Expected no jitter when disabled, got %v", got)
+	}
+}
+
+func TestOptions_Dial(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	defer func() {
+		if err := listener.Close(); err != nil {
+			t.Errorf("Failed to close listener: %v", err)
+		}
+	}()
+
+	conn, err := DefaultOptions().Dial(context.Background(), listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Failed to close connection: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DefaultOptions().Dial(ctx, listener.Addr().String()); err == nil {
+		t.Error("Expected error with a cancelled context")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -16,36 +19,191 @@ type RedisClientInterface interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 	Get(ctx context.Context, key string) *redis.StringCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	GeoAdd(ctx context.Context, key string, geoLocation ...*redis.GeoLocation) *redis.IntCmd
+	GeoSearchLocation(ctx context.Context, key string, q *redis.GeoSearchLocationQuery) *redis.GeoSearchLocationCmd
+	ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd
+	ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	MGet(ctx context.Context, keys ...string) *redis.SliceCmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+	RenameNX(ctx context.Context, key, newkey string) *redis.BoolCmd
+	TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
 	Close() error
 }
 
+// TTLs are the expiry times of the cached keys. Zero durations use the defaults.
+type TTLs struct {
+	Flight        time.Duration
+	AircraftState time.Duration
+	Validation    time.Duration
+}
+
+// DefaultTTLs are the expiry times used when none are configured
+var DefaultTTLs = TTLs{
+	Flight:        24 * time.Hour,
+	AircraftState: time.Hour,
+	Validation:    24 * time.Hour,
+}
+
+// withDefaults replaces the zero durations with the defaults
+func (t TTLs) withDefaults() TTLs {
+	if t.Flight == 0 {
+		t.Flight = DefaultTTLs.Flight
+	}
+	if t.AircraftState == 0 {
+		t.AircraftState = DefaultTTLs.AircraftState
+	}
+	if t.Validation == 0 {
+		t.Validation = DefaultTTLs.Validation
+	}
+	return t
+}
+
+// keyFamilies are the patterns of all the keys written by the client, before the key prefix
+var keyFamilies = []string{"flight:*", "aircraft:*", "validation:*", "geo:*", "index:*"}
+
+// Keys of the sets indexing the stored flights and aircraft states, kept apart
+// from the flight:* and aircraft:* keys so clients scanning those only find
+// JSON values
+const (
+	flightIndexKey   = "index:flights"
+	aircraftIndexKey = "index:aircraft"
+)
+
+// listBatchSize is the number of keys fetched by each MGET when listing
+const listBatchSize = 500
+
+// LiveChannel is the pub/sub channel every stored aircraft state is published
+// to, before the key prefix
+const LiveChannel = "sbs:live"
+
+// Keys of the live position index, kept apart from the aircraft:* state keys
+// so clients scanning those only find JSON values
+const (
+	positionsKey        = "geo:aircraft"         // GEO set of the latest position of each aircraft
+	positionsUpdatedKey = "geo:aircraft:updated" // Sorted set of the last position update time of each aircraft
+)
+
+// DefaultPositionExpiryInterval is how often stale positions are removed from the index
+const DefaultPositionExpiryInterval = time.Minute
+
+// NearbyAircraft is an aircraft found by a spatial query
+type NearbyAircraft struct {
+	HexIdent   string  `json:"hex_ident"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	DistanceKm float64 `json:"distance_km"`
+}
+
 // Client manages Redis connections and operations
 type Client struct {
 	client RedisClientInterface
+	prefix string // Namespace prepended to every key and channel
+	ttls   TTLs
 }
 
-// New creates a new Redis client
+// New creates a new Redis client for an address without authentication
 func New(addr string) (*Client, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	})
+	return NewWithOptions(&Options{Addr: addr})
+}
+
+// NewWithOptions creates a new Redis client from connection options
+func NewWithOptions(opts *Options) (*Client, error) {
+	client, err := opts.newClient()
+	if err != nil {
+		return nil, err
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		if closeErr := client.Close(); closeErr != nil {
+			log.Printf("Warning: Failed to close Redis client: %v", closeErr)
+		}
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &Client{client: client}, nil
+	c := NewWithClient(client)
+	c.prefix = normalizePrefix(opts.KeyPrefix)
+	c.ttls = opts.TTLs.withDefaults()
+	return c, nil
 }
 
 // NewWithClient creates a new Redis client with a custom RedisClientInterface (useful for testing)
 func NewWithClient(client RedisClientInterface) *Client {
-	return &Client{client: client}
+	return &Client{client: client, ttls: DefaultTTLs}
+}
+
+// normalizePrefix ends a non-empty key prefix with a colon
+func normalizePrefix(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, ":") {
+		prefix += ":"
+	}
+	return prefix
+}
+
+// key returns a key in the namespace of the client
+func (c *Client) key(name string) string {
+	return c.prefix + name
+}
+
+// TTLs returns the expiry times of the cached keys
+func (c *Client) TTLs() TTLs {
+	return c.ttls
+}
+
+// MigrateKeys renames the keys written under fromPrefix to the prefix of the
+// client, e.g. after setting a prefix on a deployment that had none. Keys that
+// already exist under the new prefix are left in place. With dryRun the keys
+// are only counted. It returns the number of keys renamed.
+func (c *Client) MigrateKeys(ctx context.Context, fromPrefix string, dryRun bool) (int, error) {
+	fromPrefix = normalizePrefix(fromPrefix)
+	if fromPrefix == c.prefix {
+		return 0, fmt.Errorf("keys are already under prefix %q", c.prefix)
+	}
+
+	renamed := 0
+	for _, family := range keyFamilies {
+		var cursor uint64
+		for {
+			keys, next, err := c.client.Scan(ctx, cursor, fromPrefix+family, listBatchSize).Result()
+			if err != nil {
+				return renamed, fmt.Errorf("failed to scan %s keys: %w", fromPrefix+family, err)
+			}
+
+			for _, key := range keys {
+				newKey := c.prefix + strings.TrimPrefix(key, fromPrefix)
+				if dryRun {
+					renamed++
+					continue
+				}
+
+				ok, err := c.client.RenameNX(ctx, key, newKey).Result()
+				switch {
+				case err != nil && strings.Contains(err.Error(), "no such key"):
+					// Expired or already renamed while scanning
+				case err != nil:
+					return renamed, fmt.Errorf("failed to rename %s: %w", key, err)
+				case !ok:
+					log.Printf("Warning: Not renaming %s, %s already exists", key, newKey)
+				default:
+					renamed++
+				}
+			}
+
+			cursor = next
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+	return renamed, nil
 }
 
 // Close closes the Redis connection
@@ -53,6 +211,11 @@ func (c *Client) Close() error {
 	return c.client.Close()
 }
 
+// Ping checks that Redis is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 // StoreFlight stores flight data in Redis
 func (c *Client) StoreFlight(ctx context.Context, flight *types.Flight) error {
 	data, err := json.Marshal(flight)
@@ -60,32 +223,42 @@ func (c *Client) StoreFlight(ctx context.Context, flight *types.Flight) error {
 		return fmt.Errorf("failed to marshal flight data: %w", err)
 	}
 
-	key := fmt.Sprintf("flight:%s", flight.HexIdent)
-	return c.client.Set(ctx, key, data, 24*time.Hour).Err()
+	// Store and index the flight in a single transaction
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, c.key("flight:"+flight.HexIdent), data, c.ttls.Flight)
+		pipe.SAdd(ctx, c.key(flightIndexKey), flight.HexIdent)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store flight: %w", err)
+	}
+	return nil
 }
 
-// getData retrieves data from Redis and unmarshals it into the target
-func (c *Client) getData(ctx context.Context, key string, target interface{}, dataType string) error {
+// getData retrieves data from Redis and unmarshals it into the target. It
+// reports false when the key doesn't exist.
+func (c *Client) getData(ctx context.Context, key string, target interface{}, dataType string) (bool, error) {
 	data, err := c.client.Get(ctx, key).Bytes()
 	if err == redis.Nil {
-		return nil // Data not found
+		return false, nil // Data not found
 	}
 	if err != nil {
-		return fmt.Errorf("failed to get %s data: %w", dataType, err)
+		return false, fmt.Errorf("failed to get %s data: %w", dataType, err)
 	}
 
 	if err := json.Unmarshal(data, target); err != nil {
-		return fmt.Errorf("failed to unmarshal %s data: %w", dataType, err)
+		return false, fmt.Errorf("failed to unmarshal %s data: %w", dataType, err)
 	}
 
-	return nil
+	return true, nil
 }
 
-// GetFlight retrieves flight data from Redis
+// GetFlight retrieves flight data from Redis, or nil if the flight isn't stored
 func (c *Client) GetFlight(ctx context.Context, hexIdent string) (*types.Flight, error) {
-	key := fmt.Sprintf("flight:%s", hexIdent)
+	key := c.key("flight:" + hexIdent)
 	var flight types.Flight
-	if err := c.getData(ctx, key, &flight, "flight"); err != nil {
+	found, err := c.getData(ctx, key, &flight, "flight")
+	if err != nil || !found {
 		return nil, err
 	}
 	return &flight, nil
@@ -93,50 +266,271 @@ func (c *Client) GetFlight(ctx context.Context, hexIdent string) (*types.Flight,
 
 // DeleteFlight removes flight data from Redis
 func (c *Client) DeleteFlight(ctx context.Context, hexIdent string) error {
-	key := fmt.Sprintf("flight:%s", hexIdent)
-	return c.client.Del(ctx, key).Err()
+	key := c.key("flight:" + hexIdent)
+	if err := c.client.Del(ctx, key).Err(); err != nil {
+		return err
+	}
+	return c.unindex(ctx, flightIndexKey, hexIdent)
+}
+
+// ListActiveFlights returns all the flights stored in Redis, sorted by hex ident
+func (c *Client) ListActiveFlights(ctx context.Context) ([]*types.Flight, error) {
+	values, err := c.listData(ctx, flightIndexKey, "flight:")
+	if err != nil {
+		return nil, err
+	}
+
+	flights := make([]*types.Flight, 0, len(values))
+	for _, value := range values {
+		var flight types.Flight
+		if err := json.Unmarshal([]byte(value), &flight); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal flight data: %w", err)
+		}
+		flights = append(flights, &flight)
+	}
+	return flights, nil
+}
+
+// ListAircraftStates returns the latest state of all the aircraft stored in
+// Redis, sorted by hex ident
+func (c *Client) ListAircraftStates(ctx context.Context) ([]*types.AircraftState, error) {
+	values, err := c.listData(ctx, aircraftIndexKey, "aircraft:")
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*types.AircraftState, 0, len(values))
+	for _, value := range values {
+		var state types.AircraftState
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal aircraft state data: %w", err)
+		}
+		states = append(states, &state)
+	}
+	return states, nil
 }
 
-// StoreAircraftState stores the latest aircraft state in Redis
+// listData fetches the values of the keys in an index set in batches of MGET,
+// sorted by member. Members whose key has expired are removed from the index.
+func (c *Client) listData(ctx context.Context, indexKey, keyPrefix string) ([]string, error) {
+	members, err := c.client.SMembers(ctx, c.key(indexKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", indexKey, err)
+	}
+	sort.Strings(members)
+
+	values := make([]string, 0, len(members))
+	var expired []string
+	for start := 0; start < len(members); start += listBatchSize {
+		batch := members[start:min(start+listBatchSize, len(members))]
+		keys := make([]string, len(batch))
+		for i, member := range batch {
+			keys[i] = c.key(keyPrefix + member)
+		}
+
+		results, err := c.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s data: %w", indexKey, err)
+		}
+		for i, result := range results {
+			value, ok := result.(string)
+			if !ok {
+				expired = append(expired, batch[i])
+				continue
+			}
+			values = append(values, value)
+		}
+	}
+
+	if len(expired) > 0 {
+		if err := c.unindex(ctx, indexKey, expired...); err != nil {
+			log.Printf("Warning: Failed to clean up %s: %v", indexKey, err)
+		}
+	}
+	return values, nil
+}
+
+// unindex removes hex idents from an index set
+func (c *Client) unindex(ctx context.Context, indexKey string, hexIdents ...string) error {
+	if err := c.client.SRem(ctx, c.key(indexKey), toMembers(hexIdents)...).Err(); err != nil {
+		return fmt.Errorf("failed to remove from %s: %w", indexKey, err)
+	}
+	return nil
+}
+
+// toMembers converts hex idents to set members
+func toMembers(hexIdents []string) []interface{} {
+	members := make([]interface{}, len(hexIdents))
+	for i, hexIdent := range hexIdents {
+		members[i] = hexIdent
+	}
+	return members
+}
+
+// StoreAircraftState stores the latest aircraft state in Redis and publishes
+// it on LiveChannel for subscribers that want push updates. The state is
+// stored, indexed and published in a single transaction, so it is never
+// stored without being indexed or published.
 func (c *Client) StoreAircraftState(ctx context.Context, state *types.AircraftState) error {
 	data, err := json.Marshal(state)
 	if err != nil {
 		return fmt.Errorf("failed to marshal aircraft state: %w", err)
 	}
 
-	key := fmt.Sprintf("aircraft:%s", state.HexIdent)
-	return c.client.Set(ctx, key, data, 1*time.Hour).Err()
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, c.key("aircraft:"+state.HexIdent), data, c.ttls.AircraftState)
+		pipe.SAdd(ctx, c.key(aircraftIndexKey), state.HexIdent)
+
+		// Index the position for spatial queries
+		if state.Latitude != 0 || state.Longitude != 0 {
+			c.indexPosition(ctx, pipe, state)
+		}
+
+		pipe.Publish(ctx, c.key(LiveChannel), data)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store aircraft state: %w", err)
+	}
+	return nil
 }
 
-// GetAircraftState retrieves the latest aircraft state from Redis
+// indexPosition queues adding the position of an aircraft to the GEO index
+// and recording when it was updated for expiry
+func (c *Client) indexPosition(ctx context.Context, pipe redis.Pipeliner, state *types.AircraftState) {
+	pipe.GeoAdd(ctx, c.key(positionsKey), &redis.GeoLocation{
+		Name:      state.HexIdent,
+		Latitude:  state.Latitude,
+		Longitude: state.Longitude,
+	})
+
+	updated := state.Timestamp
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	pipe.ZAdd(ctx, c.key(positionsUpdatedKey), redis.Z{
+		Score:  float64(updated.Unix()),
+		Member: state.HexIdent,
+	})
+}
+
+// AircraftWithin returns the aircraft whose latest position is within radiusKm
+// of a point, nearest first
+func (c *Client) AircraftWithin(ctx context.Context, lat, lon, radiusKm float64) ([]NearbyAircraft, error) {
+	locations, err := c.client.GeoSearchLocation(ctx, c.key(positionsKey), &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{
+			Latitude:   lat,
+			Longitude:  lon,
+			Radius:     radiusKm,
+			RadiusUnit: "km",
+			Sort:       "ASC",
+		},
+		WithCoord: true,
+		WithDist:  true,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search aircraft positions: %w", err)
+	}
+
+	aircraft := make([]NearbyAircraft, 0, len(locations))
+	for _, location := range locations {
+		aircraft = append(aircraft, NearbyAircraft{
+			HexIdent:   location.Name,
+			Latitude:   location.Latitude,
+			Longitude:  location.Longitude,
+			DistanceKm: location.Dist,
+		})
+	}
+	return aircraft, nil
+}
+
+// ExpirePositions removes the aircraft whose position was last updated before
+// cutoff from the position index, returning how many were removed
+func (c *Client) ExpirePositions(ctx context.Context, cutoff time.Time) (int, error) {
+	stale, err := c.client.ZRangeByScore(ctx, c.key(positionsUpdatedKey), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprintf("(%d", cutoff.Unix()),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find stale aircraft positions: %w", err)
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	if err := c.removePositions(ctx, stale...); err != nil {
+		return 0, err
+	}
+	return len(stale), nil
+}
+
+// RunPositionExpiry periodically removes positions older than maxAge from the
+// index until the context is cancelled
+func (c *Client) RunPositionExpiry(ctx context.Context, interval, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.ExpirePositions(ctx, time.Now().Add(-maxAge)); err != nil {
+				log.Printf("Warning: Failed to expire aircraft positions: %v", err)
+			}
+		}
+	}
+}
+
+// removePositions removes aircraft from the position index
+func (c *Client) removePositions(ctx context.Context, hexIdents ...string) error {
+	members := toMembers(hexIdents)
+	if err := c.client.ZRem(ctx, c.key(positionsKey), members...).Err(); err != nil {
+		return fmt.Errorf("failed to remove aircraft positions: %w", err)
+	}
+	if err := c.client.ZRem(ctx, c.key(positionsUpdatedKey), members...).Err(); err != nil {
+		return fmt.Errorf("failed to remove aircraft position updates: %w", err)
+	}
+	return nil
+}
+
+// GetAircraftState retrieves the latest aircraft state from Redis, or nil if
+// the aircraft isn't stored
 func (c *Client) GetAircraftState(ctx context.Context, hexIdent string) (*types.AircraftState, error) {
-	key := fmt.Sprintf("aircraft:%s", hexIdent)
+	key := c.key("aircraft:" + hexIdent)
 	var state types.AircraftState
-	if err := c.getData(ctx, key, &state, "aircraft state"); err != nil {
+	found, err := c.getData(ctx, key, &state, "aircraft state")
+	if err != nil || !found {
 		return nil, err
 	}
 	return &state, nil
 }
 
-// DeleteAircraftState removes aircraft state from Redis
+// DeleteAircraftState removes aircraft state and position from Redis
 func (c *Client) DeleteAircraftState(ctx context.Context, hexIdent string) error {
-	key := fmt.Sprintf("aircraft:%s", hexIdent)
-	return c.client.Del(ctx, key).Err()
+	key := c.key("aircraft:" + hexIdent)
+	if err := c.client.Del(ctx, key).Err(); err != nil {
+		return err
+	}
+	if err := c.unindex(ctx, aircraftIndexKey, hexIdent); err != nil {
+		return err
+	}
+	return c.removePositions(ctx, hexIdent)
 }
 
 // SetFlightValidation sets flight validation data
 func (c *Client) SetFlightValidation(ctx context.Context, hexIdent string, valid bool) error {
-	key := fmt.Sprintf("validation:%s", hexIdent)
+	key := c.key("validation:" + hexIdent)
 	value := "1"
 	if !valid {
 		value = "0"
 	}
-	return c.client.Set(ctx, key, value, 24*time.Hour).Err()
+	return c.client.Set(ctx, key, value, c.ttls.Validation).Err()
 }
 
 // GetFlightValidation gets flight validation status
 func (c *Client) GetFlightValidation(ctx context.Context, hexIdent string) (bool, error) {
-	key := fmt.Sprintf("validation:%s", hexIdent)
+	key := c.key("validation:" + hexIdent)
 	val, err := c.client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		return false, nil // No validation data
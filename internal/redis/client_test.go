@@ -4,10 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/saviobatista/sbs-logger/internal/geo"
 	"github.com/saviobatista/sbs-logger/internal/types"
 )
 
@@ -18,7 +24,18 @@ type mockRedisClient struct {
 	getError   error
 	setError   error
 	delError   error
+	geoError   error
+	pubError   error
+	indexError error
 	closeError error
+
+	positions map[string]redis.GeoLocation // GEO sets by key
+	scores    map[string]float64           // Sorted set of position updates
+	published map[string][]string          // Published messages by channel
+	sets      map[string]map[string]bool   // Index sets by key
+	scanned   []string                     // Keys of the current scan
+
+	transactions [][]string // Commands of each transaction, by name
 }
 
 func (m *mockRedisClient) Ping(ctx context.Context) *redis.StatusCmd {
@@ -47,7 +64,7 @@ func (m *mockRedisClient) Get(ctx context.Context, key string) *redis.StringCmd
 }
 
 func (m *mockRedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
-	cmd := redis.NewStatusCmd(ctx)
+	cmd := redis.NewStatusCmd(ctx, "set", key)
 	if m.setError != nil {
 		cmd.SetErr(m.setError)
 		return cmd
@@ -96,10 +113,339 @@ func (m *mockRedisClient) Del(ctx context.Context, keys ...string) *redis.IntCmd
 	return cmd
 }
 
+func (m *mockRedisClient) GeoAdd(ctx context.Context, key string, geoLocation ...*redis.GeoLocation) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "geoadd")
+	if m.geoError != nil {
+		cmd.SetErr(m.geoError)
+		return cmd
+	}
+
+	if m.positions == nil {
+		m.positions = make(map[string]redis.GeoLocation)
+	}
+	for _, location := range geoLocation {
+		m.positions[location.Name] = *location
+	}
+	cmd.SetVal(int64(len(geoLocation)))
+	return cmd
+}
+
+func (m *mockRedisClient) GeoSearchLocation(ctx context.Context, key string, q *redis.GeoSearchLocationQuery) *redis.GeoSearchLocationCmd {
+	cmd := redis.NewGeoSearchLocationCmd(ctx, q, "geosearch", key)
+	if m.geoError != nil {
+		cmd.SetErr(m.geoError)
+		return cmd
+	}
+
+	var locations []redis.GeoLocation
+	for _, location := range m.positions {
+		location.Dist = geo.Distance(q.Latitude, q.Longitude, location.Latitude, location.Longitude)
+		if location.Dist <= q.Radius {
+			locations = append(locations, location)
+		}
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i].Dist < locations[j].Dist })
+	cmd.SetVal(locations)
+	return cmd
+}
+
+func (m *mockRedisClient) ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "zadd")
+	if m.geoError != nil {
+		cmd.SetErr(m.geoError)
+		return cmd
+	}
+
+	if m.scores == nil {
+		m.scores = make(map[string]float64)
+	}
+	for _, member := range members {
+		m.scores[member.Member.(string)] = member.Score
+	}
+	cmd.SetVal(int64(len(members)))
+	return cmd
+}
+
+func (m *mockRedisClient) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "zrem")
+	if m.delError != nil {
+		cmd.SetErr(m.delError)
+		return cmd
+	}
+
+	for _, member := range members {
+		if strings.HasSuffix(key, positionsKey) {
+			delete(m.positions, member.(string))
+		} else {
+			delete(m.scores, member.(string))
+		}
+	}
+	return cmd
+}
+
+func (m *mockRedisClient) ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd {
+	cmd := redis.NewStringSliceCmd(ctx, "zrangebyscore", key)
+	if m.geoError != nil {
+		cmd.SetErr(m.geoError)
+		return cmd
+	}
+
+	// Only the exclusive maximum used for expiry is supported
+	max, err := strconv.ParseFloat(strings.TrimPrefix(opt.Max, "("), 64)
+	if err != nil {
+		cmd.SetErr(err)
+		return cmd
+	}
+	var members []string
+	for member, score := range m.scores {
+		if score < max {
+			members = append(members, member)
+		}
+	}
+	sort.Strings(members)
+	cmd.SetVal(members)
+	return cmd
+}
+
+func (m *mockRedisClient) Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "publish", channel)
+	if m.pubError != nil {
+		cmd.SetErr(m.pubError)
+		return cmd
+	}
+
+	if m.published == nil {
+		m.published = make(map[string][]string)
+	}
+	data, _ := message.([]byte)
+	m.published[channel] = append(m.published[channel], string(data))
+	cmd.SetVal(1)
+	return cmd
+}
+
+func (m *mockRedisClient) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "sadd", key)
+	if m.indexError != nil {
+		cmd.SetErr(m.indexError)
+		return cmd
+	}
+
+	if m.sets == nil {
+		m.sets = make(map[string]map[string]bool)
+	}
+	if m.sets[key] == nil {
+		m.sets[key] = make(map[string]bool)
+	}
+	for _, member := range members {
+		m.sets[key][member.(string)] = true
+	}
+	return cmd
+}
+
+func (m *mockRedisClient) SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	cmd := redis.NewIntCmd(ctx, "srem", key)
+	if m.indexError != nil {
+		cmd.SetErr(m.indexError)
+		return cmd
+	}
+
+	for _, member := range members {
+		delete(m.sets[key], member.(string))
+	}
+	return cmd
+}
+
+func (m *mockRedisClient) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
+	cmd := redis.NewStringSliceCmd(ctx, "smembers", key)
+	if m.indexError != nil {
+		cmd.SetErr(m.indexError)
+		return cmd
+	}
+
+	members := []string{}
+	for member := range m.sets[key] {
+		members = append(members, member)
+	}
+	cmd.SetVal(members)
+	return cmd
+}
+
+func (m *mockRedisClient) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	cmd := redis.NewSliceCmd(ctx, "mget")
+	if m.getError != nil {
+		cmd.SetErr(m.getError)
+		return cmd
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if value, exists := m.data[key]; exists {
+			values[i] = value
+		}
+	}
+	cmd.SetVal(values)
+	return cmd
+}
+
+func (m *mockRedisClient) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	if m.getError != nil {
+		return redis.NewScanCmdResult(nil, 0, m.getError)
+	}
+
+	// Return one key of the keys matching when the scan started per call to
+	// exercise the cursor
+	if cursor == 0 {
+		m.scanned = nil
+		for key := range m.data {
+			if ok, _ := path.Match(match, key); ok {
+				m.scanned = append(m.scanned, key)
+			}
+		}
+		sort.Strings(m.scanned)
+	}
+	if int(cursor) >= len(m.scanned) {
+		return redis.NewScanCmdResult(nil, 0, nil)
+	}
+	next := cursor + 1
+	if int(next) == len(m.scanned) {
+		next = 0
+	}
+	return redis.NewScanCmdResult(m.scanned[cursor:cursor+1], next, nil)
+}
+
+func (m *mockRedisClient) RenameNX(ctx context.Context, key, newkey string) *redis.BoolCmd {
+	cmd := redis.NewBoolCmd(ctx, "renamenx", key, newkey)
+	if m.setError != nil {
+		cmd.SetErr(m.setError)
+		return cmd
+	}
+
+	value, exists := m.data[key]
+	if !exists {
+		cmd.SetErr(errors.New("ERR no such key"))
+		return cmd
+	}
+	if _, exists := m.data[newkey]; exists {
+		cmd.SetVal(false)
+		return cmd
+	}
+	delete(m.data, key)
+	m.data[newkey] = value
+	cmd.SetVal(true)
+	return cmd
+}
+
 func (m *mockRedisClient) Close() error {
 	return m.closeError
 }
 
+// TxPipelined runs the queued commands as a unit: when one of them fails,
+// the writes of the others are undone
+func (m *mockRedisClient) TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	saved := m.snapshot()
+	pipe := &mockPipeline{client: m}
+	if err := fn(pipe); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(pipe.cmds))
+	for i, cmd := range pipe.cmds {
+		names[i] = cmd.Name()
+	}
+	m.transactions = append(m.transactions, names)
+
+	for _, cmd := range pipe.cmds {
+		if err := cmd.Err(); err != nil {
+			m.restore(saved)
+			return pipe.cmds, err
+		}
+	}
+	return pipe.cmds, nil
+}
+
+// mockState is a copy of the data of the mock client
+type mockState struct {
+	data      map[string]string
+	positions map[string]redis.GeoLocation
+	scores    map[string]float64
+	published map[string][]string
+	sets      map[string]map[string]bool
+}
+
+func (m *mockRedisClient) snapshot() mockState {
+	state := mockState{
+		data:      make(map[string]string),
+		positions: make(map[string]redis.GeoLocation),
+		scores:    make(map[string]float64),
+		published: make(map[string][]string),
+		sets:      make(map[string]map[string]bool),
+	}
+	for key, value := range m.data {
+		state.data[key] = value
+	}
+	for name, location := range m.positions {
+		state.positions[name] = location
+	}
+	for member, score := range m.scores {
+		state.scores[member] = score
+	}
+	for channel, messages := range m.published {
+		state.published[channel] = append([]string(nil), messages...)
+	}
+	for key, members := range m.sets {
+		state.sets[key] = make(map[string]bool)
+		for member := range members {
+			state.sets[key][member] = true
+		}
+	}
+	return state
+}
+
+func (m *mockRedisClient) restore(state mockState) {
+	m.data = state.data
+	m.positions = state.positions
+	m.scores = state.scores
+	m.published = state.published
+	m.sets = state.sets
+}
+
+// mockPipeline queues the commands used in transactions on the mock client
+type mockPipeline struct {
+	redis.Pipeliner
+	client *mockRedisClient
+	cmds   []redis.Cmder
+}
+
+func (p *mockPipeline) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	cmd := p.client.Set(ctx, key, value, expiration)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+func (p *mockPipeline) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	cmd := p.client.SAdd(ctx, key, members...)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+func (p *mockPipeline) GeoAdd(ctx context.Context, key string, geoLocation ...*redis.GeoLocation) *redis.IntCmd {
+	cmd := p.client.GeoAdd(ctx, key, geoLocation...)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+func (p *mockPipeline) ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd {
+	cmd := p.client.ZAdd(ctx, key, members...)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+func (p *mockPipeline) Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd {
+	cmd := p.client.Publish(ctx, channel, message)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
 // UNIT TESTS WITH PROPER MOCKING
 
 func TestNewWithClient_Unit(t *testing.T) {
@@ -149,6 +495,18 @@ func TestClient_Close_Unit(t *testing.T) {
 	}
 }
 
+func TestClient_Ping_Unit(t *testing.T) {
+	client := NewWithClient(&mockRedisClient{})
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	client = NewWithClient(&mockRedisClient{pingError: errors.New("connection refused")})
+	if err := client.Ping(context.Background()); err == nil {
+		t.Error("Expected error, got none")
+	}
+}
+
 func TestClient_StoreFlight_Unit(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -252,7 +610,7 @@ func TestClient_GetFlight_Unit(t *testing.T) {
 			storedData:  map[string]string{},
 			getError:    nil,
 			expectError: false,
-			expectNil:   false, // Changed from true - getData returns nil but GetFlight returns empty struct
+			expectNil:   true,
 		},
 		{
 			name:        "redis get error",
@@ -299,15 +657,8 @@ func TestClient_GetFlight_Unit(t *testing.T) {
 			}
 
 			// Verify flight data for successful retrieval
-			if !tt.expectError && !tt.expectNil && flight != nil {
-				if tt.hexIdent == "NOTFOUND" {
-					// For not found case, should return empty struct
-					if flight.HexIdent != "" {
-						t.Error("Expected empty HexIdent for not found flight")
-					}
-				} else if flight.HexIdent != testFlight.HexIdent {
-					t.Errorf("Expected HexIdent %s, got %s", testFlight.HexIdent, flight.HexIdent)
-				}
+			if !tt.expectError && !tt.expectNil && flight != nil && flight.HexIdent != testFlight.HexIdent {
+				t.Errorf("Expected HexIdent %s, got %s", testFlight.HexIdent, flight.HexIdent)
 			}
 		})
 	}
@@ -473,7 +824,7 @@ func TestClient_GetAircraftState_Unit(t *testing.T) {
 			storedData:  map[string]string{},
 			getError:    nil,
 			expectError: false,
-			expectNil:   false, // Changed from true - getData returns nil but GetAircraftState returns empty struct
+			expectNil:   true,
 		},
 		{
 			name:        "redis get error",
@@ -520,15 +871,8 @@ func TestClient_GetAircraftState_Unit(t *testing.T) {
 			}
 
 			// Verify state data for successful retrieval
-			if !tt.expectError && !tt.expectNil && state != nil {
-				if tt.hexIdent == "NOTFOUND" {
-					// For not found case, should return empty struct
-					if state.HexIdent != "" {
-						t.Error("Expected empty HexIdent for not found aircraft state")
-					}
-				} else if state.HexIdent != testState.HexIdent {
-					t.Errorf("Expected HexIdent %s, got %s", testState.HexIdent, state.HexIdent)
-				}
+			if !tt.expectError && !tt.expectNil && state != nil && state.HexIdent != testState.HexIdent {
+				t.Errorf("Expected HexIdent %s, got %s", testState.HexIdent, state.HexIdent)
 			}
 		})
 	}
@@ -588,6 +932,323 @@ func TestClient_DeleteAircraftState_Unit(t *testing.T) {
 	}
 }
 
+func TestClient_StoreAircraftState_Publishes_Unit(t *testing.T) {
+	mockClient := &mockRedisClient{}
+	client := NewWithClient(mockClient)
+	ctx := context.Background()
+
+	state := &types.AircraftState{HexIdent: "ABC123", Callsign: "TEST123", Altitude: 35000}
+	if err := client.StoreAircraftState(ctx, state); err != nil {
+		t.Fatalf("StoreAircraftState() unexpected error: %v", err)
+	}
+
+	messages := mockClient.published[LiveChannel]
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message on %s, got %d", LiveChannel, len(messages))
+	}
+	var published types.AircraftState
+	if err := json.Unmarshal([]byte(messages[0]), &published); err != nil {
+		t.Fatalf("Failed to unmarshal published state: %v", err)
+	}
+	if published.HexIdent != state.HexIdent || published.Callsign != state.Callsign || published.Altitude != state.Altitude {
+		t.Errorf("Expected published state %+v, got %+v", state, published)
+	}
+	if messages[0] != mockClient.data["aircraft:ABC123"] {
+		t.Error("Expected the published state to match the stored state")
+	}
+
+	mockClient.pubError = errors.New("publish failed")
+	moved := &types.AircraftState{HexIdent: "ABC123", Altitude: 1000, Latitude: -23.5, Longitude: -46.6}
+	if err := client.StoreAircraftState(ctx, moved); err == nil {
+		t.Error("Expected publish error, got none")
+	}
+	if messages[0] != mockClient.data["aircraft:ABC123"] || len(mockClient.positions) != 0 {
+		t.Error("Expected a state that failed to publish not to be stored or indexed")
+	}
+}
+
+func TestClient_StoreAircraftState_Transaction_Unit(t *testing.T) {
+	mockClient := &mockRedisClient{}
+	client := NewWithClient(mockClient)
+	ctx := context.Background()
+
+	if err := client.StoreAircraftState(ctx, &types.AircraftState{HexIdent: "ABC123"}); err != nil {
+		t.Fatalf("StoreAircraftState() unexpected error: %v", err)
+	}
+	if err := client.StoreAircraftState(ctx, &types.AircraftState{HexIdent: "ABC123", Latitude: -23.5, Longitude: -46.6}); err != nil {
+		t.Fatalf("StoreAircraftState() unexpected error: %v", err)
+	}
+	if err := client.StoreFlight(ctx, &types.Flight{HexIdent: "ABC123"}); err != nil {
+		t.Fatalf("StoreFlight() unexpected error: %v", err)
+	}
+
+	expected := [][]string{
+		{"set", "sadd", "publish"},
+		{"set", "sadd", "geoadd", "zadd", "publish"},
+		{"set", "sadd"},
+	}
+	if fmt.Sprint(mockClient.transactions) != fmt.Sprint(expected) {
+		t.Errorf("Expected transactions %v, got %v", expected, mockClient.transactions)
+	}
+
+	mockClient.indexError = errors.New("sadd failed")
+	if err := client.StoreFlight(ctx, &types.Flight{HexIdent: "DEF456"}); err == nil {
+		t.Error("Expected index error, got none")
+	}
+	if _, exists := mockClient.data["flight:DEF456"]; exists {
+		t.Error("Expected a flight that failed to be indexed not to be stored")
+	}
+}
+
+func TestClient_ListAircraftStates_Unit(t *testing.T) {
+	mockClient := &mockRedisClient{}
+	client := NewWithClient(mockClient)
+	ctx := context.Background()
+
+	// More aircraft than fit in one MGET batch
+	for i := listBatchSize + 9; i >= 0; i-- {
+		state := &types.AircraftState{HexIdent: fmt.Sprintf("%06X", i), Altitude: i}
+		if err := client.StoreAircraftState(ctx, state); err != nil {
+			t.Fatalf("StoreAircraftState() unexpected error: %v", err)
+		}
+	}
+	if err := client.DeleteAircraftState(ctx, "000001"); err != nil {
+		t.Fatalf("DeleteAircraftState() unexpected error: %v", err)
+	}
+	// An expired state is skipped and dropped from the index
+	delete(mockClient.data, "aircraft:000002")
+
+	states, err := client.ListAircraftStates(ctx)
+	if err != nil {
+		t.Fatalf("ListAircraftStates() unexpected error: %v", err)
+	}
+	if len(states) != listBatchSize+8 {
+		t.Fatalf("Expected %d states, got %d", listBatchSize+8, len(states))
+	}
+	if states[0].HexIdent != "000000" || states[1].HexIdent != "000003" || states[len(states)-1].Altitude != listBatchSize+9 {
+		t.Errorf("Expected states sorted by hex ident, got %s, %s ... %d", states[0].HexIdent, states[1].HexIdent, states[len(states)-1].Altitude)
+	}
+	if mockClient.sets[aircraftIndexKey]["000002"] {
+		t.Error("Expected the expired state to be removed from the index")
+	}
+
+	mockClient.data["aircraft:000000"] = "{invalid"
+	if _, err := client.ListAircraftStates(ctx); err == nil {
+		t.Error("Expected unmarshal error, got none")
+	}
+
+	mockClient.indexError = errors.New("smembers failed")
+	if _, err := client.ListAircraftStates(ctx); err == nil {
+		t.Error("Expected error, got none")
+	}
+	if err := client.StoreAircraftState(ctx, &types.AircraftState{HexIdent: "ABC123"}); err == nil {
+		t.Error("Expected index error, got none")
+	}
+}
+
+func TestClient_ListActiveFlights_Unit(t *testing.T) {
+	mockClient := &mockRedisClient{}
+	client := NewWithClient(mockClient)
+	ctx := context.Background()
+
+	for _, hexIdent := range []string{"DEF456", "ABC123", "789ABC"} {
+		if err := client.StoreFlight(ctx, &types.Flight{HexIdent: hexIdent, SessionID: "session-" + hexIdent}); err != nil {
+			t.Fatalf("StoreFlight() unexpected error: %v", err)
+		}
+	}
+	if err := client.DeleteFlight(ctx, "DEF456"); err != nil {
+		t.Fatalf("DeleteFlight() unexpected error: %v", err)
+	}
+
+	flights, err := client.ListActiveFlights(ctx)
+	if err != nil {
+		t.Fatalf("ListActiveFlights() unexpected error: %v", err)
+	}
+	if len(flights) != 2 || flights[0].HexIdent != "789ABC" || flights[1].SessionID != "session-ABC123" {
+		t.Errorf("Unexpected flights: %+v", flights)
+	}
+
+	mockClient.getError = errors.New("mget failed")
+	if _, err := client.ListActiveFlights(ctx); err == nil {
+		t.Error("Expected error, got none")
+	}
+}
+
+func TestClient_KeyPrefixAndTTLs_Unit(t *testing.T) {
+	mockClient := &mockRedisClient{}
+	client := NewWithClient(mockClient)
+	client.prefix = normalizePrefix("prod")
+	client.ttls = TTLs{Flight: 2 * time.Hour}.withDefaults()
+	ctx := context.Background()
+
+	if client.TTLs().Flight != 2*time.Hour || client.TTLs().AircraftState != DefaultTTLs.AircraftState {
+		t.Errorf("Unexpected TTLs: %+v", client.TTLs())
+	}
+
+	if err := client.StoreFlight(ctx, &types.Flight{HexIdent: "ABC123"}); err != nil {
+		t.Fatalf("StoreFlight() unexpected error: %v", err)
+	}
+	if err := client.StoreAircraftState(ctx, &types.AircraftState{HexIdent: "ABC123", Latitude: -23.5, Longitude: -46.6}); err != nil {
+		t.Fatalf("StoreAircraftState() unexpected error: %v", err)
+	}
+
+	for _, key := range []string{"prod:flight:ABC123", "prod:aircraft:ABC123"} {
+		if _, exists := mockClient.data[key]; !exists {
+			t.Errorf("Expected key %s, got %v", key, mockClient.data)
+		}
+	}
+	if !mockClient.sets["prod:"+flightIndexKey]["ABC123"] || !mockClient.sets["prod:"+aircraftIndexKey]["ABC123"] {
+		t.Errorf("Expected prefixed index sets, got %v", mockClient.sets)
+	}
+	if len(mockClient.published["prod:"+LiveChannel]) != 1 {
+		t.Errorf("Expected state published on the prefixed channel, got %v", mockClient.published)
+	}
+
+	flights, err := client.ListActiveFlights(ctx)
+	if err != nil || len(flights) != 1 {
+		t.Errorf("Expected 1 flight under the prefix, got %d, %v", len(flights), err)
+	}
+	if err := client.DeleteAircraftState(ctx, "ABC123"); err != nil || len(mockClient.positions) != 0 {
+		t.Errorf("Expected position removed under the prefix, got %v, %v", mockClient.positions, err)
+	}
+}
+
+func TestClient_MigrateKeys_Unit(t *testing.T) {
+	mockClient := &mockRedisClient{data: map[string]string{
+		"flight:ABC123":        "{}",
+		"index:flights":        "set",
+		"geo:aircraft":         "geo",
+		"aircraft:ABC123":      "{}",
+		"validation:ABC123":    "1",
+		"aircraft:DEF456":      "{}",
+		"prod:aircraft:DEF456": "{\"newer\":true}",
+		"other:key":            "untouched",
+	}}
+	client := NewWithClient(mockClient)
+	client.prefix = normalizePrefix("prod")
+	ctx := context.Background()
+
+	if _, err := client.MigrateKeys(ctx, "prod:", false); err == nil {
+		t.Error("Expected error migrating to the same prefix")
+	}
+
+	count, err := client.MigrateKeys(ctx, "", true)
+	if err != nil || count != 6 {
+		t.Fatalf("Expected 6 keys to migrate in dry run, got %d, %v", count, err)
+	}
+	if _, exists := mockClient.data["flight:ABC123"]; !exists {
+		t.Fatal("Expected dry run to leave keys in place")
+	}
+
+	count, err = client.MigrateKeys(ctx, "", false)
+	if err != nil {
+		t.Fatalf("MigrateKeys() unexpected error: %v", err)
+	}
+	// aircraft:DEF456 already exists under the prefix and is left in place
+	if count != 5 {
+		t.Errorf("Expected 5 renamed keys, got %d", count)
+	}
+	for _, key := range []string{"prod:flight:ABC123", "prod:index:flights", "prod:geo:aircraft", "prod:aircraft:ABC123", "prod:validation:ABC123", "aircraft:DEF456", "other:key"} {
+		if _, exists := mockClient.data[key]; !exists {
+			t.Errorf("Expected key %s after migration", key)
+		}
+	}
+	if mockClient.data["prod:aircraft:DEF456"] != "{\"newer\":true}" {
+		t.Error("Expected existing key under the new prefix to be kept")
+	}
+
+	mockClient.getError = errors.New("scan failed")
+	if _, err := client.MigrateKeys(ctx, "", false); err == nil {
+		t.Error("Expected scan error, got none")
+	}
+}
+
+func TestClient_AircraftWithin_Unit(t *testing.T) {
+	mockClient := &mockRedisClient{}
+	client := NewWithClient(mockClient)
+	ctx := context.Background()
+
+	states := []*types.AircraftState{
+		{HexIdent: "FAR001", Latitude: -22.9, Longitude: -43.2},   // Rio de Janeiro, ~360 km away
+		{HexIdent: "NEAR01", Latitude: -23.55, Longitude: -46.65}, // ~5 km away
+		{HexIdent: "NEAR02", Latitude: -23.6, Longitude: -46.6},
+		{HexIdent: "NOPOS1"}, // Not indexed without a position
+	}
+	for _, state := range states {
+		if err := client.StoreAircraftState(ctx, state); err != nil {
+			t.Fatalf("StoreAircraftState() unexpected error: %v", err)
+		}
+	}
+	if len(mockClient.positions) != 3 || len(mockClient.scores) != 3 {
+		t.Fatalf("Expected 3 indexed positions, got %d and %d updates", len(mockClient.positions), len(mockClient.scores))
+	}
+
+	aircraft, err := client.AircraftWithin(ctx, -23.5, -46.6, 50)
+	if err != nil {
+		t.Fatalf("AircraftWithin() unexpected error: %v", err)
+	}
+	if len(aircraft) != 2 || aircraft[0].HexIdent != "NEAR01" || aircraft[1].HexIdent != "NEAR02" {
+		t.Fatalf("Expected NEAR01 and NEAR02 nearest first, got %+v", aircraft)
+	}
+	if aircraft[0].DistanceKm <= 0 || aircraft[0].DistanceKm > aircraft[1].DistanceKm || aircraft[0].Latitude != -23.55 {
+		t.Errorf("Unexpected nearby aircraft: %+v", aircraft[0])
+	}
+
+	// Deleting the state removes the aircraft from the index
+	if err := client.DeleteAircraftState(ctx, "NEAR01"); err != nil {
+		t.Fatalf("DeleteAircraftState() unexpected error: %v", err)
+	}
+	if aircraft, _ := client.AircraftWithin(ctx, -23.5, -46.6, 50); len(aircraft) != 1 {
+		t.Errorf("Expected 1 aircraft after deletion, got %d", len(aircraft))
+	}
+
+	mockClient.geoError = errors.New("geo failed")
+	if _, err := client.AircraftWithin(ctx, -23.5, -46.6, 50); err == nil {
+		t.Error("Expected error, got none")
+	}
+	if err := client.StoreAircraftState(ctx, states[0]); err == nil {
+		t.Error("Expected indexing error, got none")
+	}
+}
+
+func TestClient_ExpirePositions_Unit(t *testing.T) {
+	mockClient := &mockRedisClient{}
+	client := NewWithClient(mockClient)
+	ctx := context.Background()
+
+	now := time.Now()
+	states := []*types.AircraftState{
+		{HexIdent: "OLD001", Latitude: -23.5, Longitude: -46.6, Timestamp: now.Add(-2 * time.Hour)},
+		{HexIdent: "OLD002", Latitude: -23.5, Longitude: -46.6, Timestamp: now.Add(-90 * time.Minute)},
+		{HexIdent: "NEW001", Latitude: -23.5, Longitude: -46.6, Timestamp: now},
+	}
+	for _, state := range states {
+		if err := client.StoreAircraftState(ctx, state); err != nil {
+			t.Fatalf("StoreAircraftState() unexpected error: %v", err)
+		}
+	}
+
+	removed, err := client.ExpirePositions(ctx, now.Add(-DefaultTTLs.AircraftState))
+	if err != nil {
+		t.Fatalf("ExpirePositions() unexpected error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 expired positions, got %d", removed)
+	}
+	if _, ok := mockClient.positions["NEW001"]; !ok || len(mockClient.positions) != 1 || len(mockClient.scores) != 1 {
+		t.Errorf("Expected only NEW001 to remain, got %v", mockClient.positions)
+	}
+
+	if removed, err := client.ExpirePositions(ctx, now.Add(-DefaultTTLs.AircraftState)); err != nil || removed != 0 {
+		t.Errorf("Expected nothing to expire, got %d, %v", removed, err)
+	}
+
+	mockClient.geoError = errors.New("zrange failed")
+	if _, err := client.ExpirePositions(ctx, now); err == nil {
+		t.Error("Expected error, got none")
+	}
+}
+
 func TestClient_SetFlightValidation_Unit(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -743,6 +1404,7 @@ func TestClient_GetData_Unit(t *testing.T) {
 		key         string
 		storedData  map[string]string
 		getError    error
+		expectFound bool
 		expectError bool
 	}{
 		{
@@ -752,6 +1414,7 @@ func TestClient_GetData_Unit(t *testing.T) {
 				"test:key": string(jsonData),
 			},
 			getError:    nil,
+			expectFound: true,
 			expectError: false,
 		},
 		{
@@ -759,7 +1422,8 @@ func TestClient_GetData_Unit(t *testing.T) {
 			key:         "missing:key",
 			storedData:  map[string]string{},
 			getError:    nil,
-			expectError: false, // getData returns nil for missing data
+			expectFound: false,
+			expectError: false,
 		},
 		{
 			name:        "redis get error",
@@ -789,7 +1453,7 @@ func TestClient_GetData_Unit(t *testing.T) {
 			ctx := context.Background()
 
 			var target map[string]interface{}
-			err := client.getData(ctx, tt.key, &target, "test")
+			found, err := client.getData(ctx, tt.key, &target, "test")
 
 			if tt.expectError && err == nil {
 				t.Error("Expected error, got none")
@@ -797,6 +1461,9 @@ func TestClient_GetData_Unit(t *testing.T) {
 			if !tt.expectError && err != nil {
 				t.Errorf("Expected no error, got: %v", err)
 			}
+			if found != tt.expectFound {
+				t.Errorf("Expected found = %v, got %v", tt.expectFound, found)
+			}
 		})
 	}
 }
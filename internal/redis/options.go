@@ -0,0 +1,197 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Options configures the connection to Redis. When SentinelMaster is set the
+// client connects to the master through the Sentinel addresses instead of Addr.
+type Options struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+
+	TLS       bool   // Connect over TLS
+	TLSCAFile string // Optional PEM file of CAs trusted for the server certificate
+
+	PoolSize     int // Maximum connections, zero uses the go-redis default
+	MinIdleConns int
+	DialTimeout  time.Duration // Zero uses the go-redis defaults
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	SentinelMaster   string
+	SentinelAddrs    []string
+	SentinelUsername string
+	SentinelPassword string
+
+	KeyPrefix string // Optional namespace of the keys, so deployments can share a Redis
+	TTLs      TTLs
+}
+
+// ParseURL parses a redis:// or rediss:// (TLS) URL of the form
+// redis://[user:password@]host:port[/db][?option=value...]. Supported
+// options are pool_size, min_idle_conns, dial_timeout, read_timeout,
+// write_timeout, tls_ca_file, sentinel_master, sentinel_addrs (comma
+// separated), sentinel_username and sentinel_password.
+func ParseURL(rawURL string) (*Options, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+
+	opts := &Options{Addr: u.Host}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		opts.TLS = true
+	default:
+		return nil, fmt.Errorf("invalid Redis URL scheme %q, expected redis or rediss", u.Scheme)
+	}
+
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if opts.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+
+	query := u.Query()
+	ints := map[string]*int{"pool_size": &opts.PoolSize, "min_idle_conns": &opts.MinIdleConns}
+	for name, target := range ints {
+		if value := query.Get(name); value != "" {
+			if *target, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("invalid Redis URL option %s %q", name, value)
+			}
+		}
+	}
+	durations := map[string]*time.Duration{
+		"dial_timeout":  &opts.DialTimeout,
+		"read_timeout":  &opts.ReadTimeout,
+		"write_timeout": &opts.WriteTimeout,
+	}
+	for name, target := range durations {
+		if value := query.Get(name); value != "" {
+			if *target, err = time.ParseDuration(value); err != nil {
+				return nil, fmt.Errorf("invalid Redis URL option %s %q", name, value)
+			}
+		}
+	}
+
+	opts.TLSCAFile = query.Get("tls_ca_file")
+	opts.SentinelMaster = query.Get("sentinel_master")
+	opts.SentinelUsername = query.Get("sentinel_username")
+	opts.SentinelPassword = query.Get("sentinel_password")
+	for _, addr := range strings.Split(query.Get("sentinel_addrs"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			opts.SentinelAddrs = append(opts.SentinelAddrs, addr)
+		}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// Validate checks that the options describe a usable connection
+func (o *Options) Validate() error {
+	if o.SentinelMaster != "" {
+		if len(o.SentinelAddrs) == 0 {
+			return fmt.Errorf("missing Sentinel addresses for Redis master %q", o.SentinelMaster)
+		}
+	} else if o.Addr == "" {
+		return fmt.Errorf("missing Redis address")
+	}
+	if o.DB < 0 {
+		return fmt.Errorf("invalid Redis database %d", o.DB)
+	}
+	if o.PoolSize < 0 || o.MinIdleConns < 0 {
+		return fmt.Errorf("invalid Redis pool size")
+	}
+	if o.DialTimeout < 0 || o.ReadTimeout < 0 || o.WriteTimeout < 0 {
+		return fmt.Errorf("invalid Redis timeout")
+	}
+	if o.TTLs.Flight < 0 || o.TTLs.AircraftState < 0 || o.TTLs.Validation < 0 {
+		return fmt.Errorf("invalid Redis TTL")
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of the options, or nil without TLS
+func (o *Options) tlsConfig() (*tls.Config, error) {
+	if !o.TLS && o.TLSCAFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.TLSCAFile != "" {
+		pem, err := os.ReadFile(o.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in Redis CA file %s", o.TLSCAFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// newClient creates a go-redis client for the options, going
+// through Sentinel when a master name is set
+func (o *Options) newClient() (*redis.Client, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if o.SentinelMaster != "" {
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       o.SentinelMaster,
+			SentinelAddrs:    o.SentinelAddrs,
+			SentinelUsername: o.SentinelUsername,
+			SentinelPassword: o.SentinelPassword,
+			Username:         o.Username,
+			Password:         o.Password,
+			DB:               o.DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         o.PoolSize,
+			MinIdleConns:     o.MinIdleConns,
+			DialTimeout:      o.DialTimeout,
+			ReadTimeout:      o.ReadTimeout,
+			WriteTimeout:     o.WriteTimeout,
+		}), nil
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr:         o.Addr,
+		Username:     o.Username,
+		Password:     o.Password,
+		DB:           o.DB,
+		TLSConfig:    tlsConfig,
+		PoolSize:     o.PoolSize,
+		MinIdleConns: o.MinIdleConns,
+		DialTimeout:  o.DialTimeout,
+		ReadTimeout:  o.ReadTimeout,
+		WriteTimeout: o.WriteTimeout,
+	}), nil
+}
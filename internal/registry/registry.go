@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/csvfile"
+	"github.com/saviobatista/sbs-logger/internal/filewatch"
+)
+
+// Aircraft holds the registry attributes of an aircraft
+type Aircraft struct {
+	HexIdent     string `json:"hex_ident"`
+	Registration string `json:"registration"`
+	TypeCode     string `json:"type_code"`
+	Operator     string `json:"operator"`
+	Country      string `json:"country"`
+}
+
+// Positional columns of the header-less tar1090-db export
+// (icao;registration;type;flags;description;year;ownop)
+const (
+	tarColHex          = 0
+	tarColRegistration = 1
+	tarColType         = 2
+	tarColOperator     = 6
+)
+
+// Registry is an in-memory aircraft database loaded from a local file
+type Registry struct {
+	path       string
+	aircraft   map[string]*Aircraft
+	generation uint64 // Incremented by every successful load
+	mu         sync.RWMutex
+}
+
+// Open loads the aircraft database at path
+func Open(path string) (*Registry, error) {
+	r := &Registry{path: path}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the database file again and replaces the loaded entries
+func (r *Registry) Reload() error {
+	records, err := csvfile.ReadAll(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to load aircraft database: %w", err)
+	}
+
+	aircraft := parseRecords(records)
+
+	r.mu.Lock()
+	r.aircraft = aircraft
+	r.generation++
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the database whenever the file changes until the context is cancelled
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	filewatch.Watch(ctx, r.path, interval, r.Reload)
+}
+
+// Lookup returns the registry attributes of an aircraft
+func (r *Registry) Lookup(hexIdent string) (*Aircraft, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	aircraft, exists := r.aircraft[strings.ToUpper(hexIdent)]
+	return aircraft, exists
+}
+
+// Generation returns the number of times the database was loaded, so callers
+// can tell whether entries they looked up may have changed
+func (r *Registry) Generation() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.generation
+}
+
+// Len returns the number of loaded aircraft
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.aircraft)
+}
+
+// parseRecords builds the aircraft index from CSV records. Files with a header
+// row (e.g. a BaseStation.sqlite export) are matched by column name, header-less
+// files are read with the tar1090-db column layout.
+func parseRecords(records [][]string) map[string]*Aircraft {
+	aircraft := make(map[string]*Aircraft, len(records))
+	if len(records) == 0 {
+		return aircraft
+	}
+
+	hexCol, regCol, typeCol, opCol, countryCol := tarColHex, tarColRegistration, tarColType, tarColOperator, -1
+	if !isHexIdent(csvfile.Field(records[0], 0)) {
+		columns := csvfile.NewColumns(records[0])
+		hexCol = columns.Index("icao", "icao24", "hex", "hexident", "modes")
+		regCol = columns.Index("registration", "reg", "r")
+		typeCol = columns.Index("icaotypecode", "typecode", "type", "t")
+		opCol = columns.Index("registeredowners", "operator", "owner", "ownop")
+		countryCol = columns.Index("modescountry", "country")
+		records = records[1:]
+	}
+	if hexCol < 0 {
+		return aircraft
+	}
+
+	for _, record := range records {
+		hexIdent := strings.ToUpper(csvfile.Field(record, hexCol))
+		if !isHexIdent(hexIdent) {
+			continue
+		}
+		aircraft[hexIdent] = &Aircraft{
+			HexIdent:     hexIdent,
+			Registration: csvfile.Field(record, regCol),
+			TypeCode:     csvfile.Field(record, typeCol),
+			Operator:     csvfile.Field(record, opCol),
+			Country:      csvfile.Field(record, countryCol),
+		}
+	}
+	return aircraft
+}
+
+// isHexIdent reports whether s is a 24-bit ICAO address in hex
+func isHexIdent(s string) bool {
+	if len(s) != 6 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen_Tar1090Format(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aircraft.csv")
+	data := "a1b2c3;N123AB;B738;00;BOEING 737-800;2015;Example Airlines;\n" +
+		"e48d4e;PR-ABC;A320;00;AIRBUS A-320;;;\n" +
+		"invalid;X;Y;;;;;\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if r.Len() != 2 {
+		t.Errorf("Expected 2 aircraft, got %d", r.Len())
+	}
+
+	aircraft, ok := r.Lookup("A1B2C3")
+	if !ok {
+		t.Fatal("Expected A1B2C3 to be found")
+	}
+	if aircraft.Registration != "N123AB" || aircraft.TypeCode != "B738" || aircraft.Operator != "Example Airlines" {
+		t.Errorf("Unexpected aircraft: %+v", aircraft)
+	}
+
+	if _, ok := r.Lookup("e48d4e"); !ok {
+		t.Error("Expected lookup to be case insensitive")
+	}
+}
+
+func TestOpen_HeaderFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "basestation.csv")
+	data := "ModeS,Registration,ICAOTypeCode,RegisteredOwners,ModeSCountry\n" +
+		"4CA123,EI-ABC,B38M,Ryanair,Ireland\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	aircraft, ok := r.Lookup("4ca123")
+	if !ok {
+		t.Fatal("Expected 4CA123 to be found")
+	}
+	expected := Aircraft{HexIdent: "4CA123", Registration: "EI-ABC", TypeCode: "B38M", Operator: "Ryanair", Country: "Ireland"}
+	if *aircraft != expected {
+		t.Errorf("Lookup() = %+v, expected %+v", *aircraft, expected)
+	}
+}
+
+func TestOpen_Errors(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	path := filepath.Join(t.TempDir(), "nohex.csv")
+	if err := os.WriteFile(path, []byte("name,value\nfoo,bar\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if r.Len() != 0 {
+		t.Errorf("Expected no aircraft without a hex column, got %d", r.Len())
+	}
+}
+
+func TestRegistry_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aircraft.csv")
+	if err := os.WriteFile(path, []byte("a1b2c3;N123AB;B738;;;;;\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("a1b2c3;N999ZZ;B39M;;;;;\nabcdef;G-ABCD;A20N;;;;;\n"), 0o600); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload() unexpected error: %v", err)
+	}
+
+	if r.Len() != 2 {
+		t.Errorf("Expected 2 aircraft after reload, got %d", r.Len())
+	}
+	if r.Generation() != 2 {
+		t.Errorf("Expected generation 2 after a reload, got %d", r.Generation())
+	}
+	if aircraft, _ := r.Lookup("A1B2C3"); aircraft.Registration != "N999ZZ" {
+		t.Errorf("Expected updated registration, got %q", aircraft.Registration)
+	}
+
+	// A failed reload keeps the previous entries
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Error("Expected error reloading a missing file")
+	}
+	if r.Len() != 2 || r.Generation() != 2 {
+		t.Errorf("Expected entries to be kept after failed reload, got %d (generation %d)", r.Len(), r.Generation())
+	}
+}
@@ -0,0 +1,124 @@
+// Package shutdown stops a service in order within a deadline: it waits for
+// a termination signal, runs the shutdown steps and maps the outcome to the
+// exit code of the process.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout is how long a service may take to shut down
+const DefaultTimeout = 30 * time.Second
+
+// Exit codes of the services
+const (
+	ExitOK      = 0
+	ExitFailure = 1 // The service failed to start or stopped on an error
+	ExitUnclean = 2 // Shutdown missed its deadline or a step failed
+)
+
+// ErrUnclean is returned when a shutdown step failed or the deadline passed
+var ErrUnclean = errors.New("unclean shutdown")
+
+// Step is one stage of a shutdown, e.g. draining a subscription. Run should
+// return when its context is done.
+type Step struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Func returns a step running fn, for steps that don't take a context, such
+// as closing a client
+func Func(name string, fn func() error) Step {
+	return Step{Name: name, Run: func(context.Context) error { return fn() }}
+}
+
+// Run runs the steps in order, sharing a deadline of timeout. A failed step
+// doesn't stop the following ones, which usually release resources. Once the
+// deadline passes, the step running is abandoned and the remaining ones are
+// skipped. The returned error wraps ErrUnclean and every step error.
+func Run(timeout time.Duration, steps ...Step) error {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			log.Printf("Shutdown: skipping %s, deadline of %s exceeded", step.Name, timeout)
+			errs = append(errs, fmt.Errorf("%s: skipped: %w", step.Name, ctx.Err()))
+			continue
+		}
+		if err := runStep(ctx, step); err != nil {
+			log.Printf("Shutdown: %s failed: %v", step.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrUnclean, errors.Join(errs...))
+	}
+	return nil
+}
+
+// runStep runs a step, returning when it is done or when the context is,
+// whichever comes first
+func runStep(ctx context.Context, step Step) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- step.Run(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait blocks until the process receives SIGINT or SIGTERM and returns the
+// signal. A second signal exits immediately with ExitUnclean.
+func Wait() os.Signal {
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigChan
+
+	go func() {
+		<-sigChan
+		log.Printf("Received a second signal, exiting without finishing the shutdown")
+		os.Exit(ExitUnclean)
+	}()
+	return sig
+}
+
+// ExitCode returns the exit code reporting err: ExitOK for nil, ExitUnclean
+// for an unclean shutdown and ExitFailure otherwise
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrUnclean):
+		return ExitUnclean
+	default:
+		return ExitFailure
+	}
+}
+
+// Exit logs err, if any, and exits the process with the code reporting it
+func Exit(err error) {
+	code := ExitCode(err)
+	if err != nil {
+		log.Printf("Exiting with code %d: %v", code, err)
+	} else {
+		log.Printf("Shutdown complete")
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,89 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var order []string
+	step := func(name string, err error) Step {
+		return Func(name, func() error {
+			order = append(order, name)
+			return err
+		})
+	}
+
+	if err := Run(time.Second, step("sources", nil), step("nats", nil)); err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+	if !reflect.DeepEqual(order, []string{"sources", "nats"}) {
+		t.Errorf("Expected the steps to run in order, got %v", order)
+	}
+
+	// A failed step doesn't stop the following ones
+	order = nil
+	failure := errors.New("flush failed")
+	err := Run(time.Second, step("logs", failure), step("nats", nil))
+	if !errors.Is(err, ErrUnclean) || !errors.Is(err, failure) {
+		t.Errorf("Expected an unclean shutdown wrapping the step error, got %v", err)
+	}
+	if !reflect.DeepEqual(order, []string{"logs", "nats"}) {
+		t.Errorf("Expected every step to run, got %v", order)
+	}
+}
+
+func TestRun_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	ran := false
+	err := Run(20*time.Millisecond,
+		Step{Name: "stuck", Run: func(context.Context) error {
+			<-release
+			return nil
+		}},
+		Func("close", func() error {
+			ran = true
+			return nil
+		}),
+	)
+	if !errors.Is(err, ErrUnclean) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if ran {
+		t.Error("Expected the steps after the deadline to be skipped")
+	}
+
+	// Steps see the deadline through their context
+	err = Run(20*time.Millisecond, Step{Name: "drain", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the step context to expire, got %v", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "clean", expected: ExitOK},
+		{name: "unclean", err: Run(time.Second, Func("close", func() error { return errors.New("failed") })), expected: ExitUnclean},
+		{name: "failure", err: errors.New("failed to subscribe"), expected: ExitFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.expected {
+				t.Errorf("ExitCode(%v) = %d, expected %d", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default spool limits
+const (
+	DefaultMaxSize     = 1 << 30  // 1 GiB
+	DefaultSegmentSize = 16 << 20 // 16 MiB
+)
+
+// segmentExt is the file extension of the segment files
+const segmentExt = ".seg"
+
+// headerSize is the size of the length prefix of each record
+const headerSize = 4
+
+// offsetName is the file holding the replay position in the oldest segment:
+// its sequence number and the offset of its first record not replayed
+const offsetName = "offset"
+
+// offsetSize is the size of the replay position in the offset file
+const offsetSize = 16
+
+// ErrFull is returned when a record doesn't fit in the spool
+var ErrFull = errors.New("spool is full")
+
+// ErrClosed is returned when appending to a closed spool
+var ErrClosed = errors.New("spool is closed")
+
+// segment is a spool file holding records in append order
+type segment struct {
+	seq     uint64
+	path    string
+	records int
+	size    int64
+}
+
+// Depth is the amount of data waiting in the spool
+type Depth struct {
+	Records  int   `json:"records"`
+	Bytes    int64 `json:"bytes"`
+	Segments int   `json:"segments"`
+}
+
+// Spool is a bounded first-in first-out queue of records stored in segment
+// files, so they survive restarts. Records are appended to the newest
+// segment and replayed from the oldest one, which is deleted once replayed.
+type Spool struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	mu       sync.Mutex
+	segments []*segment
+	nextSeq  uint64   // Sequence number of the next segment, never reused
+	writer   *os.File // Open on the newest segment, nil until the next append
+	offset   int64    // Read offset in the oldest segment
+	position *os.File // Offset file, persisting offset across restarts
+	depth    Depth
+	closed   bool
+
+	replayMu sync.Mutex // Serializes replays
+}
+
+// Open opens the spool in dir, creating the directory if needed and picking
+// up the segments left by a previous run. Zero sizes use the defaults.
+func Open(dir string, maxSize, segmentSize int64) (*Spool, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	s := &Spool{dir: dir, maxSize: maxSize, segmentSize: segmentSize}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seg := &segment{seq: seq, path: filepath.Join(dir, name)}
+		if seg.records, seg.size, err = countRecords(seg.path); err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, seg)
+		s.depth.Records += seg.records
+		s.depth.Bytes += seg.size
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+	s.depth.Segments = len(s.segments)
+
+	if err := s.openOffset(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// openOffset opens the offset file and resumes replaying the oldest segment
+// where the previous run stopped. A position that doesn't match the oldest
+// segment is ignored, replaying it from the start. New segments are numbered
+// after both the existing segments and the position, so a segment created
+// once the spool was emptied is never mistaken for the one last replayed.
+func (s *Spool) openOffset() error {
+	f, err := os.OpenFile(filepath.Join(s.dir, offsetName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open spool offset: %w", err)
+	}
+	s.position = f
+
+	s.nextSeq = 1
+	if last := s.last(); last != nil {
+		s.nextSeq = last.seq + 1
+	}
+	position := make([]byte, offsetSize)
+	if _, err := f.ReadAt(position, 0); err != nil {
+		return nil
+	}
+	seq, offset := binary.BigEndian.Uint64(position), int64(binary.BigEndian.Uint64(position[8:]))
+	s.nextSeq = max(s.nextSeq, seq+1)
+	if len(s.segments) == 0 {
+		return nil
+	}
+	seg := s.segments[0]
+	if seq != seg.seq || offset <= 0 {
+		return nil
+	}
+	records, err := countReplayed(seg.path, offset)
+	if err != nil || records > seg.records {
+		return nil
+	}
+
+	s.offset = offset
+	seg.records -= records
+	s.depth.Records -= records
+	s.depth.Bytes -= offset
+	return nil
+}
+
+// countReplayed returns the number of records of a segment file before
+// offset, or an error when offset doesn't start a record
+func countReplayed(path string, offset int64) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var records int
+	var size int64
+	header := make([]byte, headerSize)
+	for size < offset {
+		if _, err := f.ReadAt(header, size); err != nil {
+			return 0, fmt.Errorf("failed to read spool segment: %w", err)
+		}
+		size += headerSize + int64(binary.BigEndian.Uint32(header))
+		records++
+	}
+	if size != offset {
+		return 0, fmt.Errorf("offset %d of spool segment %s doesn't start a record", offset, path)
+	}
+	return records, nil
+}
+
+// countRecords returns the number of complete records of a segment file and
+// their size. A record cut short by a crash is ignored.
+func countRecords(path string) (int, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to stat spool segment: %w", err)
+	}
+
+	var records int
+	var size int64
+	header := make([]byte, headerSize)
+	for {
+		if _, err := f.ReadAt(header, size); err != nil {
+			break
+		}
+		next := size + headerSize + int64(binary.BigEndian.Uint32(header))
+		if next > info.Size() {
+			break
+		}
+		records++
+		size = next
+	}
+	return records, size, nil
+}
+
+// Append adds a record at the end of the spool. It returns ErrFull when the
+// record would exceed the maximum size of the spool, and ErrClosed once the
+// spool is closed.
+func (s *Spool) Append(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	size := int64(headerSize + len(data))
+	if s.depth.Bytes+size > s.maxSize {
+		return ErrFull
+	}
+
+	last := s.last()
+	if s.writer == nil || (last.size > 0 && last.size+size > s.segmentSize) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+		last = s.last()
+	}
+
+	record := make([]byte, size)
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[headerSize:], data)
+	if _, err := s.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+
+	last.records++
+	last.size += size
+	s.depth.Records++
+	s.depth.Bytes += size
+	return nil
+}
+
+// last returns the newest segment, or nil without segments
+func (s *Spool) last() *segment {
+	if len(s.segments) == 0 {
+		return nil
+	}
+	return s.segments[len(s.segments)-1]
+}
+
+// rotate closes the current segment and starts a new one
+func (s *Spool) rotate() error {
+	if err := s.seal(); err != nil {
+		return err
+	}
+
+	seq := s.nextSeq
+	s.nextSeq++
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+
+	s.writer = f
+	s.segments = append(s.segments, &segment{seq: seq, path: path})
+	s.depth.Segments = len(s.segments)
+	return nil
+}
+
+// seal syncs and closes the writer, if open, so the newest segment can be
+// replayed
+func (s *Spool) seal() error {
+	if s.writer == nil {
+		return nil
+	}
+	syncErr := s.writer.Sync()
+	err := s.writer.Close()
+	s.writer = nil
+	if syncErr != nil {
+		return fmt.Errorf("failed to sync spool segment: %w", syncErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close spool segment: %w", err)
+	}
+	return nil
+}
+
+// saveOffset records the replay position in the offset file
+func (s *Spool) saveOffset(seq uint64, offset int64) error {
+	position := make([]byte, offsetSize)
+	binary.BigEndian.PutUint64(position, seq)
+	binary.BigEndian.PutUint64(position[8:], uint64(offset))
+	if _, err := s.position.WriteAt(position, 0); err != nil {
+		return fmt.Errorf("failed to write spool offset: %w", err)
+	}
+	return nil
+}
+
+// Replay passes the records to fn in append order, removing each one that fn
+// accepts. It stops at the first error of fn, keeping that record for the
+// next replay. Records appended during the replay are replayed too. The
+// position reached is persisted, so records aren't replayed again after a
+// restart.
+func (s *Spool) Replay(fn func(data []byte) error) (err error) {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+	defer func() {
+		if syncErr := s.position.Sync(); syncErr != nil && err == nil {
+			err = fmt.Errorf("failed to sync spool offset: %w", syncErr)
+		}
+	}()
+
+	for {
+		s.mu.Lock()
+		if len(s.segments) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		seg := s.segments[0]
+		if len(s.segments) == 1 {
+			// Stop appending to the segment being replayed
+			if err := s.seal(); err != nil {
+				s.mu.Unlock()
+				return err
+			}
+		}
+		offset := s.offset
+		s.mu.Unlock()
+
+		if err := s.replaySegment(seg, offset, fn); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.segments = s.segments[1:]
+		s.offset = 0
+		s.depth.Segments = len(s.segments)
+		s.mu.Unlock()
+		if err := os.Remove(seg.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove spool segment: %w", err)
+		}
+	}
+}
+
+// replaySegment passes the records of a sealed segment from offset to fn
+func (s *Spool) replaySegment(seg *segment, offset int64, fn func(data []byte) error) error {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek spool segment: %w", err)
+	}
+	reader := bufio.NewReader(f)
+	header := make([]byte, headerSize)
+
+	for offset < seg.size {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return fmt.Errorf("failed to read spool segment: %w", err)
+		}
+		data := make([]byte, binary.BigEndian.Uint32(header))
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return fmt.Errorf("failed to read spool segment: %w", err)
+		}
+
+		if err := fn(data); err != nil {
+			return err
+		}
+
+		size := int64(headerSize + len(data))
+		offset += size
+		s.mu.Lock()
+		s.offset = offset
+		s.depth.Records--
+		s.depth.Bytes -= size
+		s.mu.Unlock()
+		if err := s.saveOffset(seg.seq, offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Depth returns the amount of data waiting in the spool
+func (s *Spool) Depth() Depth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.depth
+}
+
+// Close syncs and closes the newest segment and the offset file. Spooled
+// records stay on disk for the next Open, and appending fails afterwards.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	err := s.seal()
+	if syncErr := s.position.Sync(); syncErr != nil && err == nil {
+		err = fmt.Errorf("failed to sync spool offset: %w", syncErr)
+	}
+	if closeErr := s.position.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close spool offset: %w", closeErr)
+	}
+	return err
+}
@@ -0,0 +1,304 @@
+package spool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// replayAll replays the spool, returning the records as strings
+func replayAll(t *testing.T, s *Spool) []string {
+	t.Helper()
+	var records []string
+	if err := s.Replay(func(data []byte) error {
+		records = append(records, string(data))
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay() failed: %v", err)
+	}
+	return records
+}
+
+func TestSpool_AppendReplay(t *testing.T) {
+	dir := t.TempDir()
+	// Small segments so the records span several files
+	s, err := Open(dir, 0, 32)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+
+	var expected []string
+	for i := range 10 {
+		record := fmt.Sprintf("message %d", i)
+		expected = append(expected, record)
+		if err := s.Append([]byte(record)); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	depth := s.Depth()
+	if depth.Records != 10 || depth.Bytes != 10*(headerSize+9) || depth.Segments < 2 {
+		t.Errorf("Unexpected depth: %+v", depth)
+	}
+
+	if got := replayAll(t, s); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Replay() = %v, expected %v", got, expected)
+	}
+	if depth := s.Depth(); depth != (Depth{}) {
+		t.Errorf("Expected empty spool after replay, got %+v", depth)
+	}
+	if segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt)); len(segments) != 0 {
+		t.Errorf("Expected replayed segments to be removed, got %d files", len(segments))
+	}
+
+	// Appending after a replay starts a new segment
+	if err := s.Append([]byte("after")); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if got := replayAll(t, s); !reflect.DeepEqual(got, []string{"after"}) {
+		t.Errorf("Replay() = %v, expected [after]", got)
+	}
+}
+
+func TestSpool_ReplayError(t *testing.T) {
+	s, err := Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	for _, record := range []string{"a", "b", "c"} {
+		if err := s.Append([]byte(record)); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	// The record rejected by fn is kept, with the ones after it
+	errUnavailable := errors.New("unavailable")
+	var replayed []string
+	err = s.Replay(func(data []byte) error {
+		if string(data) == "b" {
+			return errUnavailable
+		}
+		replayed = append(replayed, string(data))
+		return nil
+	})
+	if !errors.Is(err, errUnavailable) {
+		t.Fatalf("Expected replay error, got %v", err)
+	}
+	if !reflect.DeepEqual(replayed, []string{"a"}) || s.Depth().Records != 2 {
+		t.Errorf("Expected a replayed and 2 records left, got %v and %+v", replayed, s.Depth())
+	}
+
+	if err := s.Append([]byte("d")); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if got := replayAll(t, s); !reflect.DeepEqual(got, []string{"b", "c", "d"}) {
+		t.Errorf("Replay() = %v, expected [b c d]", got)
+	}
+}
+
+func TestSpool_Full(t *testing.T) {
+	s, err := Open(t.TempDir(), 2*(headerSize+4), 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	for range 2 {
+		if err := s.Append([]byte("abcd")); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+	if err := s.Append([]byte("abcd")); !errors.Is(err, ErrFull) {
+		t.Errorf("Expected ErrFull, got %v", err)
+	}
+
+	// Replaying frees space
+	replayAll(t, s)
+	if err := s.Append([]byte("abcd")); err != nil {
+		t.Errorf("Expected room after replay, got %v", err)
+	}
+}
+
+func TestSpool_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0, 16)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	for _, record := range []string{"first", "second", "third"} {
+		if err := s.Append([]byte(record)); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if err := s.Append([]byte("late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+
+	// A record cut short by a crash is ignored
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil || len(segments) == 0 {
+		t.Fatalf("Expected segment files, got %v", err)
+	}
+	last := segments[len(segments)-1]
+	f, err := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open segment: %v", err)
+	}
+	if _, err := f.Write([]byte{0, 0, 0, 10, 'x'}); err != nil {
+		t.Fatalf("Failed to write segment: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Failed to close segment: %v", err)
+	}
+
+	s, err = Open(dir, 0, 16)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if depth := s.Depth(); depth.Records != 3 {
+		t.Errorf("Expected 3 records after reopening, got %+v", depth)
+	}
+	if err := s.Append([]byte("fourth")); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if got := replayAll(t, s); !reflect.DeepEqual(got, []string{"first", "second", "third", "fourth"}) {
+		t.Errorf("Replay() = %v", got)
+	}
+
+}
+
+func TestSpool_ReopenOffset(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	for _, record := range []string{"first", "second", "third"} {
+		if err := s.Append([]byte(record)); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	// Replay stops after the first record
+	errUnavailable := errors.New("unavailable")
+	if err := s.Replay(func(data []byte) error {
+		if string(data) != "first" {
+			return errUnavailable
+		}
+		return nil
+	}); !errors.Is(err, errUnavailable) {
+		t.Fatalf("Expected replay to stop, got %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// The replayed record isn't replayed again after reopening
+	s, err = Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if depth := s.Depth(); depth.Records != 2 || depth.Bytes != 2*headerSize+int64(len("second")+len("third")) {
+		t.Errorf("Expected 2 records after reopening, got %+v", depth)
+	}
+	if got := replayAll(t, s); !reflect.DeepEqual(got, []string{"second", "third"}) {
+		t.Errorf("Replay() = %v", got)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// A position not starting a record of the oldest segment is ignored
+	s, err = Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if err := s.Append([]byte("fourth")); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	if err := s.saveOffset(s.segments[0].seq, 2); err != nil {
+		t.Fatalf("saveOffset() failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	s, err = Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if got := replayAll(t, s); !reflect.DeepEqual(got, []string{"fourth"}) {
+		t.Errorf("Replay() = %v", got)
+	}
+}
+
+func TestSpool_ReopenEmptied(t *testing.T) {
+	dir := t.TempDir()
+	appendAll := func(s *Spool, records []string) {
+		t.Helper()
+		for _, record := range records {
+			if err := s.Append([]byte(record)); err != nil {
+				t.Fatalf("Append() failed: %v", err)
+			}
+		}
+	}
+	refill := []string{"4", "5", "6", "7", "8"}
+
+	// Emptying the spool and refilling it in the same run
+	s, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	appendAll(s, []string{"1", "2", "3"})
+	replayAll(t, s)
+	appendAll(s, refill)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	s, err = Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if depth := s.Depth(); depth.Records != len(refill) {
+		t.Errorf("Expected %d records after reopening, got %+v", len(refill), depth)
+	}
+	if got := replayAll(t, s); !reflect.DeepEqual(got, refill) {
+		t.Errorf("Replay() = %v, expected %v", got, refill)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// Refilling the emptied spool after a restart
+	s, err = Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	appendAll(s, refill)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	s, err = Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if got := replayAll(t, s); !reflect.DeepEqual(got, refill) {
+		t.Errorf("Replay() after a restart = %v, expected %v", got, refill)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+}
+
+func TestOpen_InvalidDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := Open(filepath.Join(file, "spool"), 0, 0); err == nil {
+		t.Error("Expected error for a spool directory inside a file")
+	}
+}
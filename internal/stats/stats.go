@@ -13,13 +13,14 @@ import (
 // Stats tracks message processing statistics
 type Stats struct {
 	// Message counts
-	TotalMessages  uint64
-	ParsedMessages uint64
-	FailedMessages uint64
-	StoredStates   uint64
-	CreatedFlights uint64
-	UpdatedFlights uint64
-	EndedFlights   uint64
+	TotalMessages     uint64
+	ParsedMessages    uint64
+	FailedMessages    uint64
+	DuplicateMessages uint64
+	StoredStates      uint64
+	CreatedFlights    uint64
+	UpdatedFlights    uint64
+	EndedFlights      uint64
 
 	// Message type counts
 	MessageTypeCounts [10]uint64 // Index corresponds to message type
@@ -32,6 +33,15 @@ type Stats struct {
 	ActiveAircraft uint64
 	ActiveFlights  uint64
 
+	// NATS connection events
+	NATSDisconnects uint64
+	NATSReconnects  uint64
+	NATSErrors      uint64
+
+	// Flights created per country of registration for the current UTC day
+	CountryFlights map[string]uint64
+	countryDay     time.Time
+
 	// Database client for persistence
 	db *db.Client
 
@@ -42,6 +52,7 @@ type Stats struct {
 func New() *Stats {
 	return &Stats{
 		LastMessageTime: time.Now(),
+		CountryFlights:  make(map[string]uint64),
 	}
 }
 
@@ -80,6 +91,11 @@ func (s *Stats) IncrementFailedMessages() {
 	atomic.AddUint64(&s.FailedMessages, 1)
 }
 
+// IncrementDuplicateMessages increments the duplicate messages counter
+func (s *Stats) IncrementDuplicateMessages() {
+	atomic.AddUint64(&s.DuplicateMessages, 1)
+}
+
 // IncrementStoredStates increments the stored states counter
 func (s *Stats) IncrementStoredStates() {
 	atomic.AddUint64(&s.StoredStates, 1)
@@ -107,6 +123,23 @@ func (s *Stats) IncrementEndedFlights() {
 	atomic.AddUint64(&s.EndedFlights, 1)
 }
 
+// IncrementCountryFlights increments the flights counter of a country for the
+// current UTC day, resetting all countries when the day changes
+func (s *Stats) IncrementCountryFlights(country string) {
+	if country == "" {
+		country = "Unknown"
+	}
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !day.Equal(s.countryDay) || s.CountryFlights == nil {
+		s.CountryFlights = make(map[string]uint64)
+		s.countryDay = day
+	}
+	s.CountryFlights[country]++
+}
+
 // SetActiveAircraft sets the number of active aircraft
 func (s *Stats) SetActiveAircraft(count uint64) {
 	atomic.StoreUint64(&s.ActiveAircraft, count)
@@ -117,6 +150,21 @@ func (s *Stats) SetActiveFlights(count uint64) {
 	atomic.StoreUint64(&s.ActiveFlights, count)
 }
 
+// NATSDisconnected counts a lost NATS connection
+func (s *Stats) NATSDisconnected(error) {
+	atomic.AddUint64(&s.NATSDisconnects, 1)
+}
+
+// NATSReconnected counts a restored NATS connection
+func (s *Stats) NATSReconnected(string) {
+	atomic.AddUint64(&s.NATSReconnects, 1)
+}
+
+// NATSError counts an asynchronous NATS error
+func (s *Stats) NATSError(error) {
+	atomic.AddUint64(&s.NATSErrors, 1)
+}
+
 // UpdateLastMessageTime updates the last message time
 func (s *Stats) UpdateLastMessageTime() {
 	s.mu.Lock()
@@ -124,6 +172,14 @@ func (s *Stats) UpdateLastMessageTime() {
 	s.mu.Unlock()
 }
 
+// LastMessage returns when the last message was processed, or when the
+// statistics were created if none was
+func (s *Stats) LastMessage() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.LastMessageTime
+}
+
 // AddProcessingTime adds to the total processing time
 func (s *Stats) AddProcessingTime(duration time.Duration) {
 	s.mu.Lock()
@@ -136,20 +192,30 @@ func (s *Stats) GetStats() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	countryFlights := make(map[string]uint64, len(s.CountryFlights))
+	for country, count := range s.CountryFlights {
+		countryFlights[country] = count
+	}
+
 	return map[string]interface{}{
-		"total_messages":    atomic.LoadUint64(&s.TotalMessages),
-		"parsed_messages":   atomic.LoadUint64(&s.ParsedMessages),
-		"failed_messages":   atomic.LoadUint64(&s.FailedMessages),
-		"stored_states":     atomic.LoadUint64(&s.StoredStates),
-		"created_flights":   atomic.LoadUint64(&s.CreatedFlights),
-		"updated_flights":   atomic.LoadUint64(&s.UpdatedFlights),
-		"ended_flights":     atomic.LoadUint64(&s.EndedFlights),
-		"active_aircraft":   atomic.LoadUint64(&s.ActiveAircraft),
-		"active_flights":    atomic.LoadUint64(&s.ActiveFlights),
-		"message_types":     s.MessageTypeCounts,
-		"last_message_time": s.LastMessageTime,
-		"processing_time":   s.ProcessingTime,
-		"uptime":            time.Since(s.LastMessageTime),
+		"total_messages":     atomic.LoadUint64(&s.TotalMessages),
+		"parsed_messages":    atomic.LoadUint64(&s.ParsedMessages),
+		"failed_messages":    atomic.LoadUint64(&s.FailedMessages),
+		"duplicate_messages": atomic.LoadUint64(&s.DuplicateMessages),
+		"stored_states":      atomic.LoadUint64(&s.StoredStates),
+		"created_flights":    atomic.LoadUint64(&s.CreatedFlights),
+		"updated_flights":    atomic.LoadUint64(&s.UpdatedFlights),
+		"ended_flights":      atomic.LoadUint64(&s.EndedFlights),
+		"active_aircraft":    atomic.LoadUint64(&s.ActiveAircraft),
+		"active_flights":     atomic.LoadUint64(&s.ActiveFlights),
+		"nats_disconnects":   atomic.LoadUint64(&s.NATSDisconnects),
+		"nats_reconnects":    atomic.LoadUint64(&s.NATSReconnects),
+		"nats_errors":        atomic.LoadUint64(&s.NATSErrors),
+		"message_types":      s.MessageTypeCounts,
+		"country_flights":    countryFlights,
+		"last_message_time":  s.LastMessageTime,
+		"processing_time":    s.ProcessingTime,
+		"uptime":             time.Since(s.LastMessageTime),
 	}
 }
 
@@ -160,24 +226,32 @@ func (s *Stats) String() string {
 		"Total Messages: %d\n"+
 			"Parsed Messages: %d\n"+
 			"Failed Messages: %d\n"+
+			"Duplicate Messages: %d\n"+
 			"Stored States: %d\n"+
 			"Created Flights: %d\n"+
 			"Updated Flights: %d\n"+
 			"Ended Flights: %d\n"+
 			"Active Aircraft: %d\n"+
 			"Active Flights: %d\n"+
+			"NATS Disconnects: %d\n"+
+			"NATS Reconnects: %d\n"+
+			"NATS Errors: %d\n"+
 			"Last Message Time: %s\n"+
 			"Processing Time: %s\n"+
 			"Uptime: %s",
 		stats["total_messages"],
 		stats["parsed_messages"],
 		stats["failed_messages"],
+		stats["duplicate_messages"],
 		stats["stored_states"],
 		stats["created_flights"],
 		stats["updated_flights"],
 		stats["ended_flights"],
 		stats["active_aircraft"],
 		stats["active_flights"],
+		stats["nats_disconnects"],
+		stats["nats_reconnects"],
+		stats["nats_errors"],
 		stats["last_message_time"],
 		stats["processing_time"],
 		stats["uptime"],
@@ -2,10 +2,12 @@ package stats
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/saviobatista/sbs-logger/internal/db"
+	"github.com/saviobatista/sbs-logger/internal/nats"
 )
 
 func TestNew(t *testing.T) {
@@ -73,6 +75,40 @@ func TestIncrementFailedMessages(t *testing.T) {
 	}
 }
 
+func TestIncrementDuplicateMessages(t *testing.T) {
+	stats := New()
+
+	initial := stats.DuplicateMessages
+	stats.IncrementDuplicateMessages()
+
+	if stats.DuplicateMessages != initial+1 {
+		t.Errorf("Expected DuplicateMessages to be %d, got %d", initial+1, stats.DuplicateMessages)
+	}
+}
+
+func TestIncrementCountryFlights(t *testing.T) {
+	stats := New()
+
+	stats.IncrementCountryFlights("Brazil")
+	stats.IncrementCountryFlights("Brazil")
+	stats.IncrementCountryFlights("")
+
+	countryFlights := stats.GetStats()["country_flights"].(map[string]uint64)
+	if countryFlights["Brazil"] != 2 {
+		t.Errorf("Expected 2 flights for Brazil, got %d", countryFlights["Brazil"])
+	}
+	if countryFlights["Unknown"] != 1 {
+		t.Errorf("Expected 1 flight for Unknown, got %d", countryFlights["Unknown"])
+	}
+
+	// A new day resets the counters
+	stats.countryDay = stats.countryDay.Add(-24 * time.Hour)
+	stats.IncrementCountryFlights("Chile")
+	if len(stats.CountryFlights) != 1 || stats.CountryFlights["Chile"] != 1 {
+		t.Errorf("Expected counters to reset on a new day, got %v", stats.CountryFlights)
+	}
+}
+
 func TestIncrementStoredStates(t *testing.T) {
 	stats := New()
 
@@ -186,6 +222,9 @@ func TestUpdateLastMessageTime(t *testing.T) {
 	if !stats.LastMessageTime.After(oldTime) {
 		t.Error("LastMessageTime should be updated to a later time")
 	}
+	if !stats.LastMessage().Equal(stats.LastMessageTime) {
+		t.Error("LastMessage() should return LastMessageTime")
+	}
 }
 
 func TestAddProcessingTime(t *testing.T) {
@@ -207,6 +246,35 @@ func TestAddProcessingTime(t *testing.T) {
 	}
 }
 
+func TestNATSEvents(t *testing.T) {
+	stats := New()
+
+	// Stats receives the events of the NATS client
+	var handler nats.EventHandler = stats
+	handler.NATSDisconnected(errors.New("connection reset"))
+	handler.NATSDisconnected(nil)
+	handler.NATSReconnected("nats://localhost:4222")
+	handler.NATSError(errors.New("slow consumer"))
+
+	if stats.NATSDisconnects != 2 {
+		t.Errorf("Expected NATSDisconnects to be 2, got %d", stats.NATSDisconnects)
+	}
+	if stats.NATSReconnects != 1 {
+		t.Errorf("Expected NATSReconnects to be 1, got %d", stats.NATSReconnects)
+	}
+	if stats.NATSErrors != 1 {
+		t.Errorf("Expected NATSErrors to be 1, got %d", stats.NATSErrors)
+	}
+
+	statsMap := stats.GetStats()
+	if statsMap["nats_disconnects"] != uint64(2) || statsMap["nats_reconnects"] != uint64(1) || statsMap["nats_errors"] != uint64(1) {
+		t.Errorf("Unexpected NATS stats: %v %v %v", statsMap["nats_disconnects"], statsMap["nats_reconnects"], statsMap["nats_errors"])
+	}
+	if !contains(stats.String(), "NATS Disconnects: 2") {
+		t.Error("String should contain 'NATS Disconnects: 2'")
+	}
+}
+
 func TestGetStats(t *testing.T) {
 	stats := New()
 
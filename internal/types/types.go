@@ -23,9 +23,14 @@ type AircraftState struct {
 	VerticalRate int       `json:"vertical_rate"`
 	Squawk       string    `json:"squawk"`
 	OnGround     bool      `json:"on_ground"`
+	Alert        bool      `json:"alert"`     // Squawk has changed
+	Emergency    bool      `json:"emergency"` // Emergency code set
+	SPI          bool      `json:"spi"`       // Special position identification (ident)
+	HasFlags     bool      `json:"-"`         // Whether the message carried the flags above
 	MsgType      int       `json:"msg_type"`
 	Timestamp    time.Time `json:"timestamp"`
 	SessionID    string    `json:"session_id"`
+	Source       string    `json:"source"`
 }
 
 // Flight represents a complete flight session
@@ -41,4 +46,53 @@ type Flight struct {
 	LastLongitude  float64   `json:"last_longitude"`
 	MaxAltitude    int       `json:"max_altitude"`
 	MaxGroundSpeed float64   `json:"max_ground_speed"`
+	Registration   string    `json:"registration"`
+	AircraftType   string    `json:"aircraft_type"`
+	Operator       string    `json:"operator"`
+	Country        string    `json:"country"`
+	Military       bool      `json:"military"`
+	AirlineICAO    string    `json:"airline_icao"`
+	AirlineIATA    string    `json:"airline_iata"`
+	Origin         string    `json:"origin"`
+	Destination    string    `json:"destination"`
+
+	// Airport events detected from on-ground transitions
+	DepartureAirport string    `json:"departure_airport"`
+	DepartureRunway  string    `json:"departure_runway"`
+	TakeoffAt        time.Time `json:"takeoff_at"`
+	ArrivalAirport   string    `json:"arrival_airport"`
+	ArrivalRunway    string    `json:"arrival_runway"`
+	LandedAt         time.Time `json:"landed_at"`
+
+	// Generation of the aircraft database the flight was last enriched from,
+	// so it is enriched again only after a reload
+	RegistryGeneration uint64 `json:"registry_generation,omitempty"`
+}
+
+// Alert represents a notable event detected for an aircraft
+type Alert struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Severity  string    `json:"severity"`
+	HexIdent  string    `json:"hex_ident"`
+	Callsign  string    `json:"callsign"`
+	SessionID string    `json:"session_id"`
+	Squawk    string    `json:"squawk"`
+	Message   string    `json:"message"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Altitude  int       `json:"altitude"`
+}
+
+// GeofenceEvent represents an aircraft entering, leaving or dwelling in a geofence
+type GeofenceEvent struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Fence     string    `json:"fence"`
+	HexIdent  string    `json:"hex_ident"`
+	Callsign  string    `json:"callsign"`
+	SessionID string    `json:"session_id"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Altitude  int       `json:"altitude"`
 }
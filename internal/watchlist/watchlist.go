@@ -0,0 +1,270 @@
+package watchlist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saviobatista/sbs-logger/internal/filewatch"
+)
+
+// Watchlist event types, raised as alerts for the flights of watched aircraft
+const (
+	EventAppeared      = "watchlist_appeared"
+	EventGeofenceEntry = "watchlist_geofence_entry"
+	EventFlightEnded   = "watchlist_flight_ended"
+)
+
+// ErrNotFound is returned when a watchlist does not exist
+var ErrNotFound = errors.New("watchlist not found")
+
+// Aircraft holds the attributes a watchlist is matched against
+type Aircraft struct {
+	HexIdent     string
+	Callsign     string
+	Registration string
+	TypeCode     string
+}
+
+// Watchlist is a named set of aircraft criteria. An aircraft is watched when
+// any of its attributes matches an entry. Entries are case insensitive and may
+// use the * and ? wildcards, e.g. "BAW*" or "PR-A??".
+type Watchlist struct {
+	Name          string   `json:"name"`
+	HexIdents     []string `json:"hex_idents,omitempty"`
+	Callsigns     []string `json:"callsigns,omitempty"`
+	Registrations []string `json:"registrations,omitempty"`
+	Types         []string `json:"types,omitempty"`
+}
+
+// Validate checks that a watchlist has a name, at least one entry and valid patterns
+func (w *Watchlist) Validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return fmt.Errorf("watchlist name is required")
+	}
+
+	entries := 0
+	for _, patterns := range [][]string{w.HexIdents, w.Callsigns, w.Registrations, w.Types} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q in watchlist %q: %w", pattern, w.Name, err)
+			}
+			entries++
+		}
+	}
+	if entries == 0 {
+		return fmt.Errorf("watchlist %q has no entries", w.Name)
+	}
+	return nil
+}
+
+// Matches reports whether an aircraft is on the watchlist
+func (w *Watchlist) Matches(a Aircraft) bool {
+	return matchAny(w.HexIdents, a.HexIdent) ||
+		matchAny(w.Callsigns, a.Callsign) ||
+		matchAny(w.Registrations, a.Registration) ||
+		matchAny(w.Types, a.TypeCode)
+}
+
+// normalize upper-cases and trims the entries of a watchlist
+func (w *Watchlist) normalize() {
+	w.Name = strings.TrimSpace(w.Name)
+	for _, patterns := range [][]string{w.HexIdents, w.Callsigns, w.Registrations, w.Types} {
+		for i, pattern := range patterns {
+			patterns[i] = strings.ToUpper(strings.TrimSpace(pattern))
+		}
+	}
+}
+
+// matchAny reports whether value matches any of the patterns
+func matchAny(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	value = strings.ToUpper(strings.TrimSpace(value))
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// List holds the configured watchlists. When backed by a file, changes made
+// through Put and Delete are written back to it.
+type List struct {
+	path       string
+	watchlists map[string]*Watchlist
+	mu         sync.RWMutex
+}
+
+// New creates an empty List that is not backed by a file
+func New() *List {
+	return &List{watchlists: make(map[string]*Watchlist)}
+}
+
+// Open loads the watchlists from a JSON file holding an array of watchlists.
+// A missing file gives an empty List that is created on the first change.
+func Open(path string) (*List, error) {
+	l := &List{path: path, watchlists: make(map[string]*Watchlist)}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload reads the watchlist file again and replaces the loaded watchlists
+func (l *List) Reload() error {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to read watchlists: %w", err)
+	}
+
+	var entries []*Watchlist
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse watchlists: %w", err)
+	}
+
+	watchlists := make(map[string]*Watchlist, len(entries))
+	for _, w := range entries {
+		w.normalize()
+		if err := w.Validate(); err != nil {
+			return err
+		}
+		watchlists[w.Name] = w
+	}
+
+	l.mu.Lock()
+	l.watchlists = watchlists
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the watchlists whenever the file changes until the context is cancelled
+func (l *List) Watch(ctx context.Context, interval time.Duration) {
+	if l.path == "" {
+		return
+	}
+	filewatch.Watch(ctx, l.path, interval, l.Reload)
+}
+
+// All returns the watchlists sorted by name
+func (l *List) All() []*Watchlist {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.sorted()
+}
+
+// Get returns a watchlist by name
+func (l *List) Get(name string) (*Watchlist, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	w, exists := l.watchlists[name]
+	return w, exists
+}
+
+// Put adds or replaces a watchlist
+func (l *List) Put(w *Watchlist) error {
+	w.normalize()
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous, existed := l.watchlists[w.Name]
+	l.watchlists[w.Name] = w
+	if err := l.save(); err != nil {
+		if existed {
+			l.watchlists[w.Name] = previous
+		} else {
+			delete(l.watchlists, w.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a watchlist, returning ErrNotFound if it does not exist
+func (l *List) Delete(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous, exists := l.watchlists[name]
+	if !exists {
+		return ErrNotFound
+	}
+	delete(l.watchlists, name)
+	if err := l.save(); err != nil {
+		l.watchlists[name] = previous
+		return err
+	}
+	return nil
+}
+
+// Match returns the names of the watchlists an aircraft is on
+func (l *List) Match(a Aircraft) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var names []string
+	for name, w := range l.watchlists {
+		if w.Matches(a) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Len returns the number of watchlists
+func (l *List) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.watchlists)
+}
+
+// save writes the watchlists to the backing file, if any. The caller must hold the lock.
+func (l *List) save() error {
+	if l.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(l.sorted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal watchlists: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial file
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write watchlists: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("failed to write watchlists: %w", err)
+	}
+	return nil
+}
+
+// sorted returns the watchlists sorted by name. The caller must hold the lock.
+func (l *List) sorted() []*Watchlist {
+	watchlists := make([]*Watchlist, 0, len(l.watchlists))
+	for _, w := range l.watchlists {
+		watchlists = append(watchlists, w)
+	}
+	sort.Slice(watchlists, func(i, j int) bool { return watchlists[i].Name < watchlists[j].Name })
+	return watchlists
+}
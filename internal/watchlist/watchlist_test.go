@@ -0,0 +1,170 @@
+package watchlist
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWatchlist_Matches(t *testing.T) {
+	w := &Watchlist{
+		Name:          "interesting",
+		HexIdents:     []string{"ae1234"},
+		Callsigns:     []string{"baw*"},
+		Registrations: []string{"PR-A??"},
+		Types:         []string{"A388"},
+	}
+	w.normalize()
+
+	tests := []struct {
+		name     string
+		aircraft Aircraft
+		expected bool
+	}{
+		{name: "hex ident", aircraft: Aircraft{HexIdent: "AE1234"}, expected: true},
+		{name: "callsign pattern", aircraft: Aircraft{HexIdent: "400000", Callsign: "BAW123 "}, expected: true},
+		{name: "registration pattern", aircraft: Aircraft{Registration: "pr-abc"}, expected: true},
+		{name: "registration too long", aircraft: Aircraft{Registration: "PR-ABCD"}},
+		{name: "type code", aircraft: Aircraft{TypeCode: "A388"}, expected: true},
+		{name: "no match", aircraft: Aircraft{HexIdent: "E48D4E", Callsign: "TAM3054", TypeCode: "A320"}},
+		{name: "empty aircraft", aircraft: Aircraft{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.Matches(tt.aircraft); got != tt.expected {
+				t.Errorf("Matches(%+v) = %v, expected %v", tt.aircraft, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWatchlist_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		watchlist   Watchlist
+		expectError bool
+	}{
+		{name: "valid", watchlist: Watchlist{Name: "vip", HexIdents: []string{"AE1234"}}},
+		{name: "missing name", watchlist: Watchlist{HexIdents: []string{"AE1234"}}, expectError: true},
+		{name: "no entries", watchlist: Watchlist{Name: "empty"}, expectError: true},
+		{name: "bad pattern", watchlist: Watchlist{Name: "bad", Callsigns: []string{"BAW["}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.watchlist.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlists.json")
+	data := `[
+		{"name": "military", "types": ["c17", "KC35"]},
+		{"name": "vip", "hex_idents": ["ae1234"], "callsigns": ["SAM*"]}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if l.Len() != 2 {
+		t.Errorf("Expected 2 watchlists, got %d", l.Len())
+	}
+
+	got := l.Match(Aircraft{HexIdent: "AE1234", TypeCode: "C17"})
+	if !reflect.DeepEqual(got, []string{"military", "vip"}) {
+		t.Errorf("Match() = %v", got)
+	}
+	if got := l.Match(Aircraft{HexIdent: "E48D4E"}); len(got) != 0 {
+		t.Errorf("Expected no match, got %v", got)
+	}
+}
+
+func TestOpen_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("Expected missing file to give an empty list, got %v", err)
+	}
+	if l.Len() != 0 {
+		t.Errorf("Expected empty list, got %d", l.Len())
+	}
+
+	for name, data := range map[string]string{
+		"invalid.json": "{not json",
+		"empty.json":   `[{"name": "empty"}]`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := Open(path); err == nil {
+			t.Errorf("Expected error opening %s", name)
+		}
+	}
+}
+
+func TestList_PutDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlists.json")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	if err := l.Put(&Watchlist{Name: " vip ", Callsigns: []string{"sam*"}}); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+	if err := l.Put(&Watchlist{Name: "invalid"}); err == nil {
+		t.Error("Expected invalid watchlist to be rejected")
+	}
+	w, ok := l.Get("vip")
+	if !ok || w.Callsigns[0] != "SAM*" {
+		t.Fatalf("Get() = %+v, %v", w, ok)
+	}
+
+	// Changes are written back to the file
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if all := reopened.All(); len(all) != 1 || all[0].Name != "vip" {
+		t.Errorf("Expected saved watchlist, got %+v", all)
+	}
+
+	if err := l.Delete("vip"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	if err := l.Delete("vip"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := reopened.Reload(); err != nil {
+		t.Fatalf("Reload() unexpected error: %v", err)
+	}
+	if reopened.Len() != 0 {
+		t.Errorf("Expected deletion to be saved, got %d watchlists", reopened.Len())
+	}
+}
+
+func TestList_PutWithoutFile(t *testing.T) {
+	l := New()
+	if err := l.Put(&Watchlist{Name: "vip", HexIdents: []string{"AE1234"}}); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+	if got := l.Match(Aircraft{HexIdent: "ae1234"}); len(got) != 1 {
+		t.Errorf("Expected match, got %v", got)
+	}
+}